@@ -6,6 +6,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"wiki-go/internal/crypto"
 	"wiki-go/internal/roles"
@@ -75,6 +77,24 @@ type Config struct {
 		MaxUploadSize               int    `yaml:"max_upload_size"` // Maximum upload file size in MB
 		Language                    string `yaml:"language"`        // Default language for the wiki
 	} `yaml:"wiki"`
+	LinkChecker struct {
+		Enabled       bool `yaml:"enabled"`        // Periodically check external links for dead links
+		IntervalHours int  `yaml:"interval_hours"` // Hours between link checks
+		CheckPages    bool `yaml:"check_pages"`    // Also check external links in regular pages
+	} `yaml:"link_checker"`
+	RenderCache struct {
+		Enabled    bool `yaml:"enabled"`     // Cache rendered document HTML
+		Disk       bool `yaml:"disk"`        // Also keep rendered documents on disk across restarts
+		MaxEntries int  `yaml:"max_entries"` // Number of documents kept in memory
+	} `yaml:"render_cache"`
+	Citations struct {
+		Bibliography string `yaml:"bibliography"` // .bib attachment used by every page, e.g. /research/library.bib
+		Style        string `yaml:"style"`        // "numeric" or "author-year"
+	} `yaml:"citations"`
+	// Variables are wiki-wide values for :::var name:::. The /_variables
+	// page and page frontmatter can override them.
+	Variables map[string]string `yaml:"variables,omitempty"`
+
 	Users       []User       `yaml:"users"`
 	AccessRules []AccessRule `yaml:"access_rules,omitempty"`
 	Security    struct {
@@ -117,6 +137,20 @@ func LoadConfig(path string) (*Config, error) {
 	config.Wiki.Language = "en"    // Default to English
 	config.Users = []User{}        // Initialize empty users array
 
+	// Link checker defaults
+	config.LinkChecker.Enabled = false
+	config.LinkChecker.IntervalHours = 24
+	config.LinkChecker.CheckPages = false
+
+	// Render cache defaults
+	config.RenderCache.Enabled = true
+	config.RenderCache.Disk = false
+	config.RenderCache.MaxEntries = 500
+
+	// Citation defaults
+	config.Citations.Bibliography = ""
+	config.Citations.Style = "numeric"
+
 	// Security defaults
 	config.Security.PasswordStrength = 14
 	config.Security.LoginBan.Enabled = true
@@ -191,6 +225,15 @@ func LoadConfig(path string) (*Config, error) {
 				config.Wiki.MaxVersions,
 				config.Wiki.MaxUploadSize,
 				config.Wiki.Language,
+				config.LinkChecker.Enabled,
+				config.LinkChecker.IntervalHours,
+				config.LinkChecker.CheckPages,
+				config.RenderCache.Enabled,
+				config.RenderCache.Disk,
+				config.RenderCache.MaxEntries,
+				config.Citations.Bibliography,
+				config.Citations.Style,
+				FormatVariableEntries(config.Variables),
 				config.Security.PasswordStrength,
 				config.Security.LoginBan.Enabled,
 				config.Security.LoginBan.MaxFailures,
@@ -265,6 +308,28 @@ wiki:
     max_upload_size: %d
     # Default language for the wiki interface (en, es, etc.)
     language: "%s"
+link_checker:
+    # Periodically check external links in links-layout pages for dead links
+    enabled: %t
+    # Hours between link checks
+    interval_hours: %d
+    # Also check external links in regular pages
+    check_pages: %t
+render_cache:
+    # Cache the HTML of rendered documents until they or their dependencies change
+    enabled: %t
+    # Also keep rendered documents on disk so the cache survives restarts
+    disk: %t
+    # Maximum number of documents kept in memory
+    max_entries: %d
+citations:
+    # BibTeX file attached to a page, such as /research/library.bib, that
+    # [@key] citations on every page are resolved against
+    bibliography: "%s"
+    # Citation style: numeric ([1]) or author-year ((Knuth, 1984))
+    style: "%s"
+# Values for :::var name::: in pages, e.g. release_version: "2.4.1"
+variables:%s
 security:
     # cost factor for bcrypt password hashing
     passwordstrength: %d
@@ -311,6 +376,22 @@ func FormatAccessRuleEntry(rule AccessRule) string {
 	return entry
 }
 
+// FormatVariableEntries formats the variables for the config file, sorted
+// by name, each on a line of its own after the section heading
+func FormatVariableEntries(vars map[string]string) string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var entries strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&entries, "\n    %s: %s", name, strconv.Quote(vars[name]))
+	}
+	return entries.String()
+}
+
 // SaveConfig saves the configuration to a writer
 func SaveConfig(cfg *Config, w io.Writer) error {
 	// Format all users
@@ -356,6 +437,15 @@ func SaveConfig(cfg *Config, w io.Writer) error {
 		cfg.Wiki.MaxVersions,
 		cfg.Wiki.MaxUploadSize,
 		cfg.Wiki.Language,
+		cfg.LinkChecker.Enabled,
+		cfg.LinkChecker.IntervalHours,
+		cfg.LinkChecker.CheckPages,
+		cfg.RenderCache.Enabled,
+		cfg.RenderCache.Disk,
+		cfg.RenderCache.MaxEntries,
+		cfg.Citations.Bibliography,
+		cfg.Citations.Style,
+		FormatVariableEntries(cfg.Variables),
 		cfg.Security.PasswordStrength,
 		cfg.Security.LoginBan.Enabled,
 		cfg.Security.LoginBan.MaxFailures,
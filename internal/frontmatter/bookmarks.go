@@ -0,0 +1,152 @@
+package frontmatter
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bookmarkTokenRegex matches the parts of a Netscape bookmark file we care
+// about: folder headings, list open/close tags, links and descriptions
+var bookmarkTokenRegex = regexp.MustCompile(`(?is)<h3[^>]*>(.*?)</h3>|<dl[^>]*>|</dl>|<a\s([^>]*)>(.*?)</a>|<dd>([^<]*)`)
+
+// bookmarkAttrRegex matches name="value" attribute pairs inside a tag
+var bookmarkAttrRegex = regexp.MustCompile(`(?i)([a-z_]+)\s*=\s*"([^"]*)"`)
+
+// Replacers for characters that would break the markdown link syntax
+var (
+	titleReplacer = strings.NewReplacer("[", "(", "]", ")")
+	urlReplacer   = strings.NewReplacer("(", "%28", ")", "%29", " ", "%20")
+)
+
+// ParseNetscapeBookmarks parses a Netscape bookmark HTML file, as exported by
+// all major browsers, into links. The innermost folder of each bookmark is
+// used as its category. Bookmarks that are not valid http(s) links, such as
+// javascript: bookmarklets, are skipped and counted.
+func ParseNetscapeBookmarks(content string) ([]Link, int) {
+	var links []Link
+	skipped := 0
+
+	// Folder names for each open <DL>; the pending folder is the last <H3>
+	// seen, which applies to the next <DL>
+	var folders []string
+	pendingFolder := ""
+	var lastLink *Link
+
+	for _, m := range bookmarkTokenRegex.FindAllStringSubmatch(content, -1) {
+		token := strings.ToLower(m[0])
+
+		switch {
+		case strings.HasPrefix(token, "<h3"):
+			pendingFolder = strings.TrimSpace(html.UnescapeString(m[1]))
+			lastLink = nil
+
+		case strings.HasPrefix(token, "<dl"):
+			folders = append(folders, pendingFolder)
+			pendingFolder = ""
+
+		case token == "</dl>":
+			if len(folders) > 0 {
+				folders = folders[:len(folders)-1]
+			}
+			lastLink = nil
+
+		case strings.HasPrefix(token, "<a"):
+			attrs := make(map[string]string)
+			for _, attr := range bookmarkAttrRegex.FindAllStringSubmatch(m[2], -1) {
+				attrs[strings.ToLower(attr[1])] = html.UnescapeString(attr[2])
+			}
+
+			// Links are stored as "- [Title](URL)", so keep brackets out of
+			// titles and parentheses out of URLs
+			link := Link{
+				Title:    titleReplacer.Replace(strings.Join(strings.Fields(html.UnescapeString(stripTags(m[3]))), " ")),
+				URL:      urlReplacer.Replace(strings.TrimSpace(attrs["href"])),
+				Category: SanitizeCategory(innermostFolder(folders)),
+			}
+			if link.Title == "" {
+				link.Title = link.URL
+			}
+			if seconds, err := strconv.ParseInt(attrs["add_date"], 10, 64); err == nil && seconds > 0 {
+				link.AddedAt = time.Unix(seconds, 0).UTC()
+			}
+
+			if len(ValidateLink(link)) > 0 {
+				skipped++
+				lastLink = nil
+				continue
+			}
+
+			links = append(links, link)
+			lastLink = &links[len(links)-1]
+
+		case strings.HasPrefix(token, "<dd"):
+			if lastLink != nil {
+				lastLink.Description = strings.Join(strings.Fields(html.UnescapeString(m[4])), " ")
+			}
+		}
+	}
+
+	return links, skipped
+}
+
+// RenderNetscapeBookmarks renders links data as a Netscape bookmark HTML
+// file with one folder per category
+func RenderNetscapeBookmarks(data *LinksData) string {
+	title := data.Title
+	if title == "" {
+		title = "Bookmarks"
+	}
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE NETSCAPE-Bookmark-file-1>\n")
+	b.WriteString("<!-- This is an automatically generated file.\n     It will be read and overwritten.\n     DO NOT EDIT! -->\n")
+	b.WriteString("<META HTTP-EQUIV=\"Content-Type\" CONTENT=\"text/html; charset=UTF-8\">\n")
+	fmt.Fprintf(&b, "<TITLE>%s</TITLE>\n", html.EscapeString(title))
+	fmt.Fprintf(&b, "<H1>%s</H1>\n", html.EscapeString(title))
+	b.WriteString("<DL><p>\n")
+
+	for _, category := range data.OrderedCategories() {
+		links := data.Categories[category]
+		if len(links) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "    <DT><H3>%s</H3>\n", html.EscapeString(category))
+		b.WriteString("    <DL><p>\n")
+		for _, link := range links {
+			b.WriteString("        <DT><A HREF=\"")
+			b.WriteString(html.EscapeString(link.URL))
+			b.WriteString("\"")
+			if !link.AddedAt.IsZero() {
+				fmt.Fprintf(&b, " ADD_DATE=\"%d\"", link.AddedAt.Unix())
+			}
+			fmt.Fprintf(&b, ">%s</A>\n", html.EscapeString(link.Title))
+			if link.Description != "" {
+				fmt.Fprintf(&b, "        <DD>%s\n", html.EscapeString(link.Description))
+			}
+		}
+		b.WriteString("    </DL><p>\n")
+	}
+
+	b.WriteString("</DL><p>\n")
+	return b.String()
+}
+
+// innermostFolder returns the name of the closest enclosing named folder
+func innermostFolder(folders []string) string {
+	for i := len(folders) - 1; i >= 0; i-- {
+		if folders[i] != "" {
+			return folders[i]
+		}
+	}
+	return ""
+}
+
+// stripTags removes any HTML tags from s
+func stripTags(s string) string {
+	return regexp.MustCompile(`<[^>]*>`).ReplaceAllString(s, "")
+}
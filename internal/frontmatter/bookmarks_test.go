@@ -0,0 +1,57 @@
+package frontmatter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNetscapeBookmarks(t *testing.T) {
+	input := `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<TITLE>Bookmarks</TITLE>
+<DL><p>
+    <DT><H3 ADD_DATE="1700000000">Dev &amp; Ops</H3>
+    <DL><p>
+        <DT><A HREF="https://go.dev/" ADD_DATE="1700000000">The Go [Programming] Language</A>
+        <DD>Go home page
+        <DT><A HREF="javascript:alert(1)">Bookmarklet</A>
+    </DL><p>
+    <DT><A HREF="https://example.com/">Example</A>
+</DL><p>`
+
+	links, skipped := ParseNetscapeBookmarks(input)
+
+	if skipped != 1 {
+		t.Errorf("expected 1 skipped bookmark, got %d", skipped)
+	}
+	if len(links) != 2 {
+		t.Fatalf("expected 2 links, got %d: %+v", len(links), links)
+	}
+	if links[0].Category != "Dev & Ops" || links[0].Title != "The Go (Programming) Language" || links[0].Description != "Go home page" {
+		t.Errorf("unexpected first link: %+v", links[0])
+	}
+	if links[0].AddedAt.Unix() != 1700000000 {
+		t.Errorf("unexpected add date: %v", links[0].AddedAt)
+	}
+	if links[1].Category != "General" || links[1].Description != "" {
+		t.Errorf("unexpected second link: %+v", links[1])
+	}
+}
+
+func TestNetscapeBookmarksRoundTrip(t *testing.T) {
+	data := NewLinksData()
+	data.AddLink(Link{Title: "Go", URL: "https://go.dev/", Description: "Go & more", Category: "Languages"})
+	data.AddLink(Link{Title: "Example", URL: "https://example.com/?a=1&b=2", Category: "Misc"})
+
+	exported := RenderNetscapeBookmarks(data)
+	if !strings.HasPrefix(exported, "<!DOCTYPE NETSCAPE-Bookmark-file-1>") {
+		t.Fatalf("missing bookmark file header: %q", exported)
+	}
+
+	links, skipped := ParseNetscapeBookmarks(exported)
+	if skipped != 0 || len(links) != 2 {
+		t.Fatalf("expected 2 links and none skipped, got %d and %d", len(links), skipped)
+	}
+	if links[0] != data.Categories["Languages"][0] || links[1] != data.Categories["Misc"][0] {
+		t.Errorf("round trip mismatch: %+v", links)
+	}
+}
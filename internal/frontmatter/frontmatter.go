@@ -10,7 +10,30 @@ import (
 // Metadata represents the frontmatter data structure
 // This can be expanded with additional fields in the future
 type Metadata struct {
-	Layout string `yaml:"layout,omitempty"`
+	Layout string   `yaml:"layout,omitempty"`
+	Tags   []string `yaml:"tags,omitempty"`
+
+	// Title and Description describe the page in page listings
+	Title       string `yaml:"title,omitempty"`
+	Description string `yaml:"description,omitempty"`
+
+	// Glossary set to false stops glossary terms being linked in the page
+	Glossary *bool `yaml:"glossary,omitempty"`
+
+	// Bibliography names the .bib attachment citations are resolved
+	// against, and CitationStyle is "numeric" or "author-year"
+	Bibliography  string `yaml:"bibliography,omitempty"`
+	CitationStyle string `yaml:"citation_style,omitempty"`
+
+	// Variables set values for :::var name::: on this page. On the
+	// /_variables page they define the wiki-wide variables.
+	Variables map[string]string `yaml:"variables,omitempty"`
+
+	// NumberHeadings numbers the page's headings 1, 1.1, 1.1.1
+	NumberHeadings bool `yaml:"number_headings,omitempty"`
+
+	// KanbanWIP set to "strict" refuses task moves over column WIP limits
+	KanbanWIP string `yaml:"kanban_wip,omitempty"`
 	// Add additional fields here as needed
 }
 
@@ -4,9 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"regexp"
-	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"wiki-go/internal/i18n"
 
@@ -20,12 +20,18 @@ import (
 type KanbanBoard struct {
 	Title   string
 	Columns []KanbanColumn
+	Lanes   []string // Swimlanes in display order, empty if the board has none
 }
 
 // KanbanColumn represents a column in the kanban board
 type KanbanColumn struct {
-	Title string
+	Title string // Heading text, including any policies
 	Tasks []KanbanTask
+
+	// Column policies parsed from the title, see newKanbanColumn
+	Name     string // Title without policies
+	WIPLimit int    // Maximum number of cards, 0 for no limit
+	Done     bool   // Tasks moved into the column are stamped as completed
 }
 
 // KanbanTask represents a task in the kanban board
@@ -34,6 +40,15 @@ type KanbanTask struct {
 	Checked     bool
 	HTMLText    string // Rendered HTML text of the task
 	IndentLevel int    // Indentation level for nested tasks
+	Lane        string // Swimlane of the task, empty for the unnamed lane
+
+	// Inline metadata parsed from Text, see ParseTaskMetadata
+	Assignees   []string
+	Labels      []string
+	Due         time.Time
+	Completed   time.Time
+	Priority    string
+	DisplayText string // Text without the metadata tokens
 }
 
 // Store kanban boards until after goldext processing
@@ -63,8 +78,8 @@ func RenderKanban(content string) string {
 }
 
 // RenderKanbanWithProcessors converts markdown content to a kanban board HTML with full goldext support
-// This function accepts preprocessor and postprocessor functions to avoid circular dependencies
-func RenderKanbanWithProcessors(content string, preprocessors []PreprocessorFunc, postProcessors []PostProcessorFunc) string {
+// This function accepts preprocessor and postprocessor functions and goldmark extensions to avoid circular dependencies
+func RenderKanbanWithProcessors(content string, preprocessors []PreprocessorFunc, postProcessors []PostProcessorFunc, extensions ...goldmark.Extender) string {
 	// Apply kanban-aware preprocessing to protect kanban structure while allowing goldext processing
 	processedContent := kanbanAwarePreprocess(content)
 
@@ -76,7 +91,7 @@ func RenderKanbanWithProcessors(content string, preprocessors []PreprocessorFunc
 	}
 
 	// Render the processed content with goldmark
-	renderedHTML := renderWithGoldmark(processedContent)
+	renderedHTML := renderWithGoldmark(processedContent, extensions)
 
 	// Apply post-processors
 	for _, postProcessor := range postProcessors {
@@ -86,7 +101,7 @@ func RenderKanbanWithProcessors(content string, preprocessors []PreprocessorFunc
 	}
 
 	// Restore kanban boards and build final kanban HTML
-	return restoreKanbanBoards(renderedHTML, preprocessors)
+	return restoreKanbanBoards(renderedHTML, preprocessors, extensions)
 }
 
 // RenderKanbanBasic provides basic kanban rendering without full goldext support (fallback)
@@ -96,6 +111,7 @@ func RenderKanbanBasic(content string) string {
 
 	// Generate HTML for all kanban boards
 	var html strings.Builder
+	now := time.Now()
 
 	// Add header content if it exists
 	if headerContent != "" {
@@ -118,46 +134,28 @@ func RenderKanbanBasic(content string) string {
 			html.WriteString(fmt.Sprintf(`<h4 class="kanban-board-title">%s</h4>`, board.Title))
 		}
 
-		html.WriteString(`<div class="kanban-board">`)
+		// Add assignee and label filters if tasks have metadata
+		html.WriteString(renderKanbanFilterBar(board))
+
+		html.WriteString(fmt.Sprintf(`<div %s>`, kanbanBoardAttrs(board)))
 
 		for _, column := range board.Columns {
-			html.WriteString(fmt.Sprintf(`<div class="kanban-column">
+			html.WriteString(fmt.Sprintf(`<div %s>
 				<div class="kanban-column-header">
-					<span class="column-title">%s</span>
+					<span class="column-title">%s</span>%s
 					<span class="kanban-status"></span>
 					<button class="rename-column-btn editor-admin-only" title="%s"><i class="fa fa-pencil"></i></button>
 					<button class="add-task-btn editor-admin-only" title="%s"><i class="fa fa-plus"></i></button>
 					<button class="delete-column-btn editor-admin-only" title="%s"><i class="fa fa-trash"></i></button>
 				</div>
-				<div class="kanban-column-content">`, column.Title, i18n.Translate("kanban.rename_column"), i18n.Translate("kanban.add_task"), i18n.Translate("kanban.delete_column")))
-
-			// Add a task list for each column
-			html.WriteString(`<ul class="task-list">`)
-
-			for _, task := range column.Tasks {
-				checkedAttr := ""
-				if task.Checked {
-					checkedAttr = "checked"
-				}
+				<div class="kanban-column-content">`, kanbanColumnAttrs(column), column.Name, renderKanbanColumnPolicy(column), i18n.Translate("kanban.rename_column"), i18n.Translate("kanban.add_task"), i18n.Translate("kanban.delete_column")))
 
-				// Add indentation level attribute if needed
-				indentAttr := ""
-				if task.IndentLevel > 0 {
-					indentAttr = ` data-indent-level="` + strconv.Itoa(task.IndentLevel) + `"`
-				}
+			// Add a task list for each column, or for each swimlane of it. Tasks
+			// use the same structure as the regular task list items, which
+			// ensures compatibility with tasklist-live.js
+			html.WriteString(renderKanbanColumnTasks(column, board.Lanes, now))
 
-				// Use the same structure as the regular task list items
-				// This ensures compatibility with tasklist-live.js
-				html.WriteString(fmt.Sprintf(`<li class="task-list-item-container" style="list-style-type: none;"%s>
-					<span class="task-list-item">
-						<input type="checkbox" class="task-checkbox" %s disabled>
-						<span class="task-text">%s</span>
-						<span class="save-state"></span>
-					</span>
-				</li>`, indentAttr, checkedAttr, task.HTMLText))
-			}
-
-			html.WriteString(`</ul></div></div>`)
+			html.WriteString(`</div></div>`)
 		}
 
 		html.WriteString(`</div>`) // Close kanban-board
@@ -197,6 +195,7 @@ func kanbanAwarePreprocess(content string) string {
 	inKanbanColumn := false
 	currentBoard := KanbanBoard{}
 	currentColumn := KanbanColumn{}
+	currentLane := ""
 	nonKanbanLines := []string{}
 
 	for _, line := range lines {
@@ -236,15 +235,20 @@ func kanbanAwarePreprocess(content string) string {
 
 				// Start new kanban column
 				inKanbanColumn = true
-				currentColumn = KanbanColumn{
-					Title: h5Match[1],
-					Tasks: []KanbanTask{},
-				}
+				currentColumn = newKanbanColumn(h5Match[1])
+				currentLane = ""
 				continue
 			}
 		}
 
 		if inKanbanBoard && inKanbanColumn {
+			// Check for H6 heading (swimlane)
+			if laneMatch := kanbanLaneLineRegex.FindStringSubmatch(line); laneMatch != nil {
+				currentLane = laneMatch[1]
+				currentBoard.addLane(currentLane)
+				continue
+			}
+
 			// Check if this is a task line
 			trimmedLine := strings.TrimSpace(line)
 			indent := line[:len(line)-len(trimmedLine)]
@@ -260,12 +264,9 @@ func kanbanAwarePreprocess(content string) string {
 				isChecked := taskMatch[1] == "x" || taskMatch[1] == "X"
 				taskText := taskMatch[2]
 
-				currentColumn.Tasks = append(currentColumn.Tasks, KanbanTask{
-					Text:        taskText,
-					Checked:     isChecked,
-					HTMLText:    taskText, // Use raw text for basic fallback
-					IndentLevel: indentLevel,
-				})
+				task := newKanbanTask(taskText, isChecked, indentLevel)
+				task.Lane = currentLane
+				currentColumn.Tasks = append(currentColumn.Tasks, task)
 				continue
 			} else if trimmedLine == "" {
 				// Empty line in kanban column - continue
@@ -318,6 +319,8 @@ func kanbanAwarePreprocess(content string) string {
 
 // saveKanbanBoard saves a kanban board and adds a placeholder to the result
 func saveKanbanBoard(board KanbanBoard, result *[]string) {
+	finishKanbanBoard(&board)
+
 	kanbanBoardCount++
 	id := fmt.Sprintf("KANBAN_BOARD_%d", kanbanBoardCount)
 	kanbanBoards[id] = board
@@ -328,7 +331,7 @@ func saveKanbanBoard(board KanbanBoard, result *[]string) {
 }
 
 // renderWithGoldmark renders the processed content using goldmark
-func renderWithGoldmark(content string) string {
+func renderWithGoldmark(content string, extensions []goldmark.Extender) string {
 	// Configure Goldmark with all needed extensions (same as regular markdown processing)
 	markdown := goldmark.New(
 		goldmark.WithExtensions(
@@ -339,6 +342,7 @@ func renderWithGoldmark(content string) string {
 			extension.DefinitionList,
 			extension.GFM,
 		),
+		goldmark.WithExtensions(extensions...),
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(),
 			parser.WithAttribute(),
@@ -358,7 +362,7 @@ func renderWithGoldmark(content string) string {
 }
 
 // restoreKanbanBoards replaces placeholders with kanban HTML and builds the final result
-func restoreKanbanBoards(htmlContent string, preprocessors []PreprocessorFunc) string {
+func restoreKanbanBoards(htmlContent string, preprocessors []PreprocessorFunc, extensions []goldmark.Extender) string {
 	kanbanMutex.Lock()
 	defer kanbanMutex.Unlock()
 
@@ -366,6 +370,7 @@ func restoreKanbanBoards(htmlContent string, preprocessors []PreprocessorFunc) s
 	lines := strings.Split(htmlContent, "\n")
 	var finalHTML strings.Builder
 	boardIndex := 0
+	now := time.Now()
 
 	for _, line := range lines {
 		// Check if this line contains a kanban board placeholder
@@ -388,18 +393,11 @@ func restoreKanbanBoards(htmlContent string, preprocessors []PreprocessorFunc) s
 					for _, column := range board.Columns {
 						var processedTasks []KanbanTask
 						for _, task := range column.Tasks {
-							processedHTML := applyProcessorsToTaskText(task.Text, preprocessors)
-							processedTasks = append(processedTasks, KanbanTask{
-								Text:        task.Text,
-								Checked:     task.Checked,
-								HTMLText:    processedHTML,
-								IndentLevel: task.IndentLevel,
-							})
+							task.HTMLText = applyProcessorsToTaskText(task.DisplayText, preprocessors, extensions)
+							processedTasks = append(processedTasks, task)
 						}
-						processedColumns = append(processedColumns, KanbanColumn{
-							Title: column.Title,
-							Tasks: processedTasks,
-						})
+						column.Tasks = processedTasks
+						processedColumns = append(processedColumns, column)
 					}
 
 					// Build kanban board HTML with board ID
@@ -410,42 +408,25 @@ func restoreKanbanBoards(htmlContent string, preprocessors []PreprocessorFunc) s
 						finalHTML.WriteString(fmt.Sprintf(`<h4 class="kanban-board-title">%s</h4>`, board.Title))
 					}
 
-					finalHTML.WriteString(`<div class="kanban-board">`)
+					// Add assignee and label filters if tasks have metadata
+					finalHTML.WriteString(renderKanbanFilterBar(board))
+
+					finalHTML.WriteString(fmt.Sprintf(`<div %s>`, kanbanBoardAttrs(board)))
 
 					for _, column := range processedColumns {
-						finalHTML.WriteString(fmt.Sprintf(`<div class="kanban-column">
+						finalHTML.WriteString(fmt.Sprintf(`<div %s>
 							<div class="kanban-column-header">
-								<span class="column-title">%s</span>
+								<span class="column-title">%s</span>%s
 								<span class="kanban-status"></span>
 								<button class="rename-column-btn editor-admin-only" title="%s"><i class="fa fa-pencil"></i></button>
 								<button class="add-task-btn editor-admin-only" title="%s"><i class="fa fa-plus"></i></button>
 								<button class="delete-column-btn editor-admin-only" title="%s"><i class="fa fa-trash"></i></button>
 							</div>
-							<div class="kanban-column-content">`, column.Title, i18n.Translate("kanban.rename_column"), i18n.Translate("kanban.add_task"), i18n.Translate("kanban.delete_column_title")))
+							<div class="kanban-column-content">`, kanbanColumnAttrs(column), column.Name, renderKanbanColumnPolicy(column), i18n.Translate("kanban.rename_column"), i18n.Translate("kanban.add_task"), i18n.Translate("kanban.delete_column_title")))
 
-						finalHTML.WriteString(`<ul class="task-list">`)
+						finalHTML.WriteString(renderKanbanColumnTasks(column, board.Lanes, now))
 
-						for _, task := range column.Tasks {
-							checkedAttr := ""
-							if task.Checked {
-								checkedAttr = "checked"
-							}
-
-							indentAttr := ""
-							if task.IndentLevel > 0 {
-								indentAttr = ` data-indent-level="` + strconv.Itoa(task.IndentLevel) + `"`
-							}
-
-							finalHTML.WriteString(fmt.Sprintf(`<li class="task-list-item-container" style="list-style-type: none;"%s>
-								<span class="task-list-item">
-									<input type="checkbox" class="task-checkbox" %s disabled>
-									<span class="task-text">%s</span>
-									<span class="save-state"></span>
-								</span>
-							</li>`, indentAttr, checkedAttr, task.HTMLText))
-						}
-
-						finalHTML.WriteString(`</ul></div></div>`)
+						finalHTML.WriteString(`</div></div>`)
 					}
 
 					finalHTML.WriteString(`</div>`) // Close kanban-board
@@ -471,7 +452,7 @@ func restoreKanbanBoards(htmlContent string, preprocessors []PreprocessorFunc) s
 }
 
 // applyProcessorsToTaskText applies preprocessors to individual task text
-func applyProcessorsToTaskText(taskText string, preprocessors []PreprocessorFunc) string {
+func applyProcessorsToTaskText(taskText string, preprocessors []PreprocessorFunc, extensions []goldmark.Extender) string {
 	// Apply preprocessors to task text
 	processed := taskText
 	for _, preprocessor := range preprocessors {
@@ -487,6 +468,7 @@ func applyProcessorsToTaskText(taskText string, preprocessors []PreprocessorFunc
 			extension.Linkify,
 			extension.GFM,
 		),
+		goldmark.WithExtensions(extensions...),
 		goldmark.WithRendererOptions(
 			html.WithUnsafe(),
 		),
@@ -519,6 +501,7 @@ func parseKanbanContentBasic(content string) (string, []KanbanBoard) {
 	var boards []KanbanBoard
 	var currentBoard *KanbanBoard
 	var currentColumn *KanbanColumn
+	var currentLane string
 	var headerLines []string
 	var foundFirstBoard bool
 
@@ -536,6 +519,7 @@ func parseKanbanContentBasic(content string) (string, []KanbanBoard) {
 
 			// Save current board if exists
 			if currentBoard != nil {
+				finishKanbanBoard(currentBoard)
 				boards = append(boards, *currentBoard)
 			}
 
@@ -553,10 +537,13 @@ func parseKanbanContentBasic(content string) (string, []KanbanBoard) {
 				}
 
 				// Start new column
-				currentColumn = &KanbanColumn{
-					Title: h5Match[1],
-					Tasks: []KanbanTask{},
-				}
+				column := newKanbanColumn(h5Match[1])
+				currentColumn = &column
+				currentLane = ""
+			} else if laneMatch := kanbanLaneLineRegex.FindStringSubmatch(line); laneMatch != nil && currentColumn != nil {
+				// Start new swimlane within the column
+				currentLane = laneMatch[1]
+				currentBoard.addLane(currentLane)
 			} else if currentColumn != nil {
 				// Check if this is a task line
 				trimmedLine := strings.TrimSpace(line)
@@ -573,16 +560,14 @@ func parseKanbanContentBasic(content string) (string, []KanbanBoard) {
 					isChecked := taskMatch[1] == "x" || taskMatch[1] == "X"
 					taskText := taskMatch[2]
 
+					task := newKanbanTask(taskText, isChecked, indentLevel)
+					task.Lane = currentLane
+
 					// Process task text for basic markdown formatting
-					htmlText := processInlineFormattingBasic(taskText)
+					task.HTMLText = processInlineFormattingBasic(task.DisplayText)
 
 					// Add the task to the current column
-					currentColumn.Tasks = append(currentColumn.Tasks, KanbanTask{
-						Text:        taskText,
-						Checked:     isChecked,
-						HTMLText:    htmlText,
-						IndentLevel: indentLevel,
-					})
+					currentColumn.Tasks = append(currentColumn.Tasks, task)
 				}
 			}
 		} else if !foundFirstBoard {
@@ -596,6 +581,7 @@ func parseKanbanContentBasic(content string) (string, []KanbanBoard) {
 		currentBoard.Columns = append(currentBoard.Columns, *currentColumn)
 	}
 	if currentBoard != nil {
+		finishKanbanBoard(currentBoard)
 		boards = append(boards, *currentBoard)
 	}
 
@@ -0,0 +1,479 @@
+package frontmatter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Errors returned by KanbanDocument mutations
+var (
+	// ErrKanbanNotFound is returned when a board, column or task index is out of range
+	ErrKanbanNotFound = errors.New("kanban board, column or task not found")
+
+	// ErrKanbanConflict is returned when a task no longer has the text the
+	// caller expected, usually because the document changed in the meantime
+	ErrKanbanConflict = errors.New("kanban task has changed")
+
+	// ErrKanbanWIPLimit is returned when a task would exceed the WIP limit of
+	// a column on a board that enforces its limits
+	ErrKanbanWIPLimit = errors.New("kanban column is at its WIP limit")
+)
+
+// Line patterns of the kanban markdown format, matching the renderer:
+// H4 for board titles, H5 for column titles and task list items
+var (
+	kanbanBoardLineRegex  = regexp.MustCompile(`^#{4}\s+(.+)$`)
+	kanbanColumnLineRegex = regexp.MustCompile(`^#{5}\s+(.+)$`)
+	kanbanTaskLineRegex   = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s+(.+)$`)
+)
+
+// KanbanDocument is a kanban-layout document split into its boards and the
+// surrounding markdown, so boards can be changed and written back without
+// touching anything else in the document
+type KanbanDocument struct {
+	Boards []KanbanBoard
+
+	// EnforceWIP refuses to add or move tasks into columns at their WIP
+	// limit, set with "kanban_wip: strict" in the frontmatter
+	EnforceWIP bool
+
+	// segments holds the document in order: a segment is either raw lines
+	// or a reference to a board
+	segments []kanbanSegment
+}
+
+// kanbanSegment is a run of raw lines, or a board if board is not negative
+type kanbanSegment struct {
+	lines []string
+	board int
+}
+
+// ParseKanbanDocument parses a kanban document. Frontmatter and content
+// outside boards are kept verbatim; boards are recognised with the same
+// rules used when rendering, so board indexes match the rendered page.
+func ParseKanbanDocument(content string) *KanbanDocument {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	metadata, _, _ := Parse(content)
+	doc := &KanbanDocument{EnforceWIP: strings.EqualFold(metadata.KanbanWIP, "strict")}
+	lines := strings.Split(content, "\n")
+
+	var text []string
+	flushText := func() {
+		if len(text) > 0 {
+			doc.segments = append(doc.segments, kanbanSegment{lines: text, board: -1})
+			text = nil
+		}
+	}
+
+	i := 0
+
+	// Keep frontmatter as is, whatever it contains
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for j := 1; j < len(lines); j++ {
+			if strings.TrimSpace(lines[j]) == "---" {
+				text = append(text, lines[:j+1]...)
+				i = j + 1
+				break
+			}
+		}
+	}
+
+	for i < len(lines) {
+		boardMatch := kanbanBoardLineRegex.FindStringSubmatch(lines[i])
+		if boardMatch == nil {
+			text = append(text, lines[i])
+			i++
+			continue
+		}
+
+		flushText()
+
+		board := KanbanBoard{Title: boardMatch[1], Columns: []KanbanColumn{}}
+		var column *KanbanColumn
+		lane := ""
+		i++
+
+	boardLines:
+		for ; i < len(lines); i++ {
+			line := lines[i]
+			trimmed := strings.TrimSpace(line)
+
+			switch {
+			case trimmed == "":
+				continue
+			case kanbanBoardLineRegex.MatchString(line):
+				break boardLines
+			}
+
+			if columnMatch := kanbanColumnLineRegex.FindStringSubmatch(line); columnMatch != nil {
+				if column != nil {
+					board.Columns = append(board.Columns, *column)
+				}
+				newColumn := newKanbanColumn(columnMatch[1])
+				column = &newColumn
+				lane = ""
+				continue
+			}
+
+			if laneMatch := kanbanLaneLineRegex.FindStringSubmatch(line); laneMatch != nil && column != nil {
+				lane = laneMatch[1]
+				board.addLane(lane)
+				continue
+			}
+
+			taskMatch := kanbanTaskLineRegex.FindStringSubmatch(trimmed)
+			if column == nil || taskMatch == nil {
+				// Any other content ends the board
+				break
+			}
+
+			// Calculate indentation level the same way as the renderer
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			indentLevel := len(indent) / 2
+			if indentLevel == 0 && len(indent) > 0 {
+				indentLevel = 1 // Handle tab indentation
+			}
+
+			checked := taskMatch[1] == "x" || taskMatch[1] == "X"
+			task := newKanbanTask(taskMatch[2], checked, indentLevel)
+			task.Lane = lane
+			column.Tasks = append(column.Tasks, task)
+		}
+
+		if column != nil {
+			board.Columns = append(board.Columns, *column)
+		}
+		finishKanbanBoard(&board)
+
+		doc.segments = append(doc.segments, kanbanSegment{board: len(doc.Boards)})
+		doc.Boards = append(doc.Boards, board)
+	}
+
+	flushText()
+	return doc
+}
+
+// Markdown returns the document with its boards written in canonical form
+func (d *KanbanDocument) Markdown() string {
+	var lines []string
+
+	for _, segment := range d.segments {
+		if segment.board < 0 {
+			lines = append(lines, segment.lines...)
+			continue
+		}
+
+		board := d.Boards[segment.board]
+		lines = append(lines, "#### "+board.Title, "")
+		for c, column := range board.Columns {
+			lines = append(lines, "##### "+column.Title)
+			if !board.HasSwimlanes() {
+				for _, task := range column.Tasks {
+					lines = append(lines, kanbanTaskLine(task))
+				}
+				lines = append(lines, "")
+				continue
+			}
+
+			// Lanes are written where they have tasks, and all of them in the
+			// first column so empty lanes are kept
+			for _, lane := range board.Lanes {
+				var laneLines []string
+				for _, task := range column.Tasks {
+					if task.Lane == lane {
+						laneLines = append(laneLines, kanbanTaskLine(task))
+					}
+				}
+				if lane != "" && (len(laneLines) > 0 || c == 0) {
+					lines = append(lines, "###### "+lane)
+				}
+				lines = append(lines, laneLines...)
+			}
+			lines = append(lines, "")
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// kanbanTaskLine returns the markdown list item for a task
+func kanbanTaskLine(task KanbanTask) string {
+	mark := " "
+	if task.Checked {
+		mark = "x"
+	}
+	return fmt.Sprintf("%s- [%s] %s", strings.Repeat("  ", task.IndentLevel), mark, task.Text)
+}
+
+// KanbanTaskSource returns the markdown of a task without trailing HTML
+// comments such as task IDs, as used to identify tasks in the browser
+func KanbanTaskSource(text string) string {
+	return strings.TrimSpace(strings.Split(text, "<!--")[0])
+}
+
+// column returns a board column by index
+func (d *KanbanDocument) column(board, column int) (*KanbanColumn, error) {
+	if board < 0 || board >= len(d.Boards) {
+		return nil, ErrKanbanNotFound
+	}
+	if column < 0 || column >= len(d.Boards[board].Columns) {
+		return nil, ErrKanbanNotFound
+	}
+	return &d.Boards[board].Columns[column], nil
+}
+
+// task returns a task by index. If expect is not empty it must match the
+// task's source text, so a stale client cannot change the wrong task.
+func (d *KanbanDocument) task(board, column, task int, expect string) (*KanbanColumn, error) {
+	col, err := d.column(board, column)
+	if err != nil {
+		return nil, err
+	}
+	if task < 0 || task >= len(col.Tasks) {
+		return nil, ErrKanbanNotFound
+	}
+	if expect != "" && KanbanTaskSource(col.Tasks[task].Text) != strings.TrimSpace(expect) {
+		return nil, ErrKanbanConflict
+	}
+	return col, nil
+}
+
+// cleanKanbanText validates and normalizes a task or heading text, which must
+// fit on one line
+func cleanKanbanText(text string) (string, error) {
+	text = strings.TrimSpace(strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(text))
+	if text == "" {
+		return "", errors.New("text is required")
+	}
+	return text, nil
+}
+
+// AddTask inserts a task at index in a column, or appends it if index is
+// negative or past the end. lane is the swimlane of the task on boards that
+// have them.
+func (d *KanbanDocument) AddTask(board, column, index int, lane, text string) error {
+	col, err := d.column(board, column)
+	if err != nil {
+		return err
+	}
+	if text, err = cleanKanbanText(text); err != nil {
+		return err
+	}
+	if lane, err = d.lane(board, lane); err != nil {
+		return err
+	}
+	if d.EnforceWIP && col.WIPLimit > 0 && col.TaskCount() >= col.WIPLimit {
+		return ErrKanbanWIPLimit
+	}
+
+	if index < 0 || index > len(col.Tasks) {
+		index = len(col.Tasks)
+	}
+	task := newKanbanTask(text, false, 0)
+	task.Lane = lane
+	col.Tasks = append(col.Tasks[:index], append([]KanbanTask{task}, col.Tasks[index:]...)...)
+	return nil
+}
+
+// EditTask replaces the text of a task, keeping its state and indentation
+func (d *KanbanDocument) EditTask(board, column, task int, expect, text string) error {
+	col, err := d.task(board, column, task, expect)
+	if err != nil {
+		return err
+	}
+	if text, err = cleanKanbanText(text); err != nil {
+		return err
+	}
+
+	// Keep trailing comments such as task IDs
+	current := col.Tasks[task]
+	if i := strings.Index(current.Text, "<!--"); i >= 0 {
+		text += " " + current.Text[i:]
+	}
+	col.Tasks[task] = newKanbanTask(text, current.Checked, current.IndentLevel)
+	col.Tasks[task].Lane = current.Lane
+	return nil
+}
+
+// SetTaskChecked checks or unchecks a task
+func (d *KanbanDocument) SetTaskChecked(board, column, task int, expect string, checked bool) error {
+	col, err := d.task(board, column, task, expect)
+	if err != nil {
+		return err
+	}
+	col.Tasks[task].Checked = checked
+	return nil
+}
+
+// DeleteTask removes a task together with its subtasks
+func (d *KanbanDocument) DeleteTask(board, column, task int, expect string) error {
+	col, err := d.task(board, column, task, expect)
+	if err != nil {
+		return err
+	}
+	end := task + 1 + len(subtasks(col.Tasks, task))
+	col.Tasks = append(col.Tasks[:task], col.Tasks[end:]...)
+	return nil
+}
+
+// MoveTask moves a task and its subtasks to another position, possibly in
+// another column, swimlane or board. toIndex is the position of the task in
+// the target column after the move, and indent its new indentation level;
+// subtasks keep their indentation relative to it.
+//
+// A task moved into a done column is checked and stamped with the completion
+// date; moving it out of done columns again removes the stamp.
+func (d *KanbanDocument) MoveTask(board, column, task int, expect string, toBoard, toColumn, toIndex, indent int, lane string) error {
+	col, err := d.task(board, column, task, expect)
+	if err != nil {
+		return err
+	}
+	target, err := d.column(toBoard, toColumn)
+	if err != nil {
+		return err
+	}
+	if lane, err = d.lane(toBoard, lane); err != nil {
+		return err
+	}
+	if indent < 0 {
+		indent = 0
+	}
+
+	// Subtasks moved along with a card don't count against the limit
+	if d.EnforceWIP && target != col && indent == 0 && target.WIPLimit > 0 && target.TaskCount() >= target.WIPLimit {
+		return ErrKanbanWIPLimit
+	}
+
+	// Take the task and its subtasks out of the source column
+	end := task + 1 + len(subtasks(col.Tasks, task))
+	block := make([]KanbanTask, end-task)
+	copy(block, col.Tasks[task:end])
+	col.Tasks = append(col.Tasks[:task], col.Tasks[end:]...)
+
+	// Re-indent, keeping subtasks below the moved task
+	delta := indent - block[0].IndentLevel
+	block[0].IndentLevel = indent
+	for i := range block {
+		if i > 0 {
+			block[i].IndentLevel += delta
+			if block[i].IndentLevel < 1 {
+				block[i].IndentLevel = 1
+			}
+		}
+		block[i].Lane = lane
+	}
+
+	switch {
+	case target.Done && !col.Done:
+		block[0] = stampTaskCompleted(block[0], time.Now())
+	case col.Done && !target.Done:
+		block[0] = clearTaskCompleted(block[0])
+	}
+
+	if toIndex < 0 || toIndex > len(target.Tasks) {
+		toIndex = len(target.Tasks)
+	}
+	target.Tasks = append(target.Tasks[:toIndex], append(block, target.Tasks[toIndex:]...)...)
+	return nil
+}
+
+// lane validates a swimlane of a board. The unnamed lane is always valid and
+// added to the board when first used; boards without swimlanes have no other.
+func (d *KanbanDocument) lane(board int, lane string) (string, error) {
+	b := &d.Boards[board]
+	if !b.HasSwimlanes() {
+		return "", nil
+	}
+	if lane == "" {
+		if b.Lanes[0] != "" {
+			b.Lanes = append([]string{""}, b.Lanes...)
+		}
+		return "", nil
+	}
+	for _, l := range b.Lanes {
+		if l == lane {
+			return lane, nil
+		}
+	}
+	return "", ErrKanbanNotFound
+}
+
+// subtasks returns the tasks nested below the task at index
+func subtasks(tasks []KanbanTask, index int) []KanbanTask {
+	end := index + 1
+	for end < len(tasks) && tasks[end].IndentLevel > tasks[index].IndentLevel {
+		end++
+	}
+	return tasks[index+1 : end]
+}
+
+// AddColumn appends an empty column to a board
+func (d *KanbanDocument) AddColumn(board int, title string) error {
+	if board < 0 || board >= len(d.Boards) {
+		return ErrKanbanNotFound
+	}
+	title, err := cleanKanbanText(title)
+	if err != nil {
+		return err
+	}
+	d.Boards[board].Columns = append(d.Boards[board].Columns, newKanbanColumn(title))
+	return nil
+}
+
+// RenameColumn changes the title of a column. The column keeps its
+// policies unless the new title has its own.
+func (d *KanbanDocument) RenameColumn(board, column int, title string) error {
+	col, err := d.column(board, column)
+	if err != nil {
+		return err
+	}
+	if title, err = cleanKanbanText(title); err != nil {
+		return err
+	}
+
+	renamed := newKanbanColumn(title)
+	if renamed.Name == renamed.Title {
+		renamed = newKanbanColumn(title + col.columnPolicySuffix())
+	}
+	renamed.Tasks = col.Tasks
+	*col = renamed
+	return nil
+}
+
+// DeleteColumn removes a column and all of its tasks
+func (d *KanbanDocument) DeleteColumn(board, column int) error {
+	if _, err := d.column(board, column); err != nil {
+		return err
+	}
+	columns := d.Boards[board].Columns
+	d.Boards[board].Columns = append(columns[:column], columns[column+1:]...)
+	return nil
+}
+
+// ReorderColumns rearranges the columns of a board. order lists the current
+// column indexes in their new order and must contain each index exactly once.
+func (d *KanbanDocument) ReorderColumns(board int, order []int) error {
+	if board < 0 || board >= len(d.Boards) {
+		return ErrKanbanNotFound
+	}
+	columns := d.Boards[board].Columns
+	if len(order) != len(columns) {
+		return fmt.Errorf("order must list all %d columns", len(columns))
+	}
+
+	seen := make(map[int]bool, len(order))
+	reordered := make([]KanbanColumn, 0, len(columns))
+	for _, index := range order {
+		if index < 0 || index >= len(columns) || seen[index] {
+			return fmt.Errorf("invalid column order %v", order)
+		}
+		seen[index] = true
+		reordered = append(reordered, columns[index])
+	}
+
+	d.Boards[board].Columns = reordered
+	return nil
+}
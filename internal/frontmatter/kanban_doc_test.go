@@ -0,0 +1,116 @@
+package frontmatter
+
+import (
+	"errors"
+	"testing"
+)
+
+const kanbanDocFixture = `---
+layout: kanban
+---
+
+# Project
+
+Intro paragraph.
+
+#### Sprint 1
+
+##### Todo
+- [ ] Write spec @alice
+  - [ ] Review spec
+* [X] Set up repo <!-- task-id: task_1 -->
+
+##### Done
+
+- [x] Kickoff
+
+Notes after the board.
+`
+
+func TestKanbanDocumentRoundTrip(t *testing.T) {
+	doc := ParseKanbanDocument(kanbanDocFixture)
+
+	if len(doc.Boards) != 1 || len(doc.Boards[0].Columns) != 2 {
+		t.Fatalf("unexpected boards: %+v", doc.Boards)
+	}
+
+	want := `---
+layout: kanban
+---
+
+# Project
+
+Intro paragraph.
+
+#### Sprint 1
+
+##### Todo
+- [ ] Write spec @alice
+  - [ ] Review spec
+- [x] Set up repo <!-- task-id: task_1 -->
+
+##### Done
+- [x] Kickoff
+
+Notes after the board.
+`
+	if got := doc.Markdown(); got != want {
+		t.Errorf("unexpected markdown:\n%s", got)
+	}
+
+	// Canonical output must be stable
+	if again := ParseKanbanDocument(want).Markdown(); again != want {
+		t.Errorf("canonical markdown changed on reparse:\n%s", again)
+	}
+}
+
+func TestKanbanDocumentMoveTask(t *testing.T) {
+	doc := ParseKanbanDocument(kanbanDocFixture)
+
+	// Move "Write spec" with its subtask to the end of Done, nested under Kickoff
+	if err := doc.MoveTask(0, 0, 0, "Write spec @alice", 0, 1, 1, 1, ""); err != nil {
+		t.Fatalf("MoveTask failed: %v", err)
+	}
+
+	todo, done := doc.Boards[0].Columns[0].Tasks, doc.Boards[0].Columns[1].Tasks
+	if len(todo) != 1 || todo[0].Text != "Set up repo <!-- task-id: task_1 -->" {
+		t.Errorf("unexpected source column: %+v", todo)
+	}
+	if len(done) != 3 || done[1].Text != "Write spec @alice" || done[1].IndentLevel != 1 ||
+		done[2].Text != "Review spec" || done[2].IndentLevel != 2 {
+		t.Errorf("unexpected target column: %+v", done)
+	}
+
+	if err := doc.MoveTask(0, 0, 0, "Something else", 0, 1, 0, 0, ""); !errors.Is(err, ErrKanbanConflict) {
+		t.Errorf("expected conflict for stale task text, got %v", err)
+	}
+	if err := doc.MoveTask(0, 5, 0, "", 0, 1, 0, 0, ""); !errors.Is(err, ErrKanbanNotFound) {
+		t.Errorf("expected not found for missing column, got %v", err)
+	}
+}
+
+func TestKanbanDocumentColumns(t *testing.T) {
+	doc := ParseKanbanDocument(kanbanDocFixture)
+
+	if err := doc.AddColumn(0, "Review"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.ReorderColumns(0, []int{2, 0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.RenameColumn(0, 1, "Backlog"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.DeleteColumn(0, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	columns := doc.Boards[0].Columns
+	if len(columns) != 2 || columns[0].Title != "Review" || columns[1].Title != "Backlog" || len(columns[1].Tasks) != 3 {
+		t.Errorf("unexpected columns: %+v", columns)
+	}
+
+	if err := doc.ReorderColumns(0, []int{0, 0}); err == nil {
+		t.Error("expected an error for a duplicate column index")
+	}
+}
@@ -0,0 +1,420 @@
+package frontmatter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// KanbanCSVHeader lists the columns of exported kanban CSV files. Imports
+// need the column and title fields; the others are optional and may come in
+// any order.
+var KanbanCSVHeader = []string{"board", "column", "lane", "title", "done", "labels", "due", "assignees", "priority"}
+
+// importLabelRegex matches characters that can't be part of a #label token
+var importLabelRegex = regexp.MustCompile(`[^\p{L}\p{N}_/-]+`)
+
+// ParseKanbanBoards returns the boards of a kanban document, parsed the same
+// way as when the document is rendered
+func ParseKanbanBoards(content string) []KanbanBoard {
+	_, body, _ := Parse(content)
+	_, boards := parseKanbanContentBasic(body)
+	return boards
+}
+
+// NewKanbanMarkdown returns a kanban-layout document with a title and boards
+func NewKanbanMarkdown(title string, boards []KanbanBoard) string {
+	doc := &KanbanDocument{Boards: boards}
+
+	header := []string{"---", "layout: kanban", "---", ""}
+	if title != "" {
+		header = append(header, "# "+title, "")
+	}
+	doc.segments = append(doc.segments, kanbanSegment{lines: header, board: -1})
+	for i := range boards {
+		doc.segments = append(doc.segments, kanbanSegment{board: i})
+	}
+
+	return doc.Markdown()
+}
+
+// kanbanImportCard is a card read from an import file
+type kanbanImportCard struct {
+	Board     string
+	Column    string
+	Lane      string
+	Title     string
+	Done      bool
+	Labels    []string
+	Due       string
+	Assignees []string
+	Priority  string
+	Subtasks  []kanbanImportCard
+}
+
+// taskText returns the markdown text of a card, with its metadata as inline
+// tokens
+func (c kanbanImportCard) taskText() string {
+	parts := []string{strings.Join(strings.Fields(c.Title), " ")}
+	for _, assignee := range c.Assignees {
+		if assignee = importToken(assignee, "@"); assignee != "" {
+			parts = append(parts, "@"+assignee)
+		}
+	}
+	for _, label := range c.Labels {
+		if label = importToken(label, "#"); label != "" {
+			parts = append(parts, "#"+label)
+		}
+	}
+	if c.Due != "" {
+		parts = append(parts, "due:"+c.Due)
+	}
+	if priorityTokenRegex.MatchString("!" + c.Priority) {
+		parts = append(parts, "!"+strings.ToLower(c.Priority))
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+// importToken turns an imported label or assignee name into the word used
+// in its metadata token, replacing characters a token can't contain
+func importToken(name, prefix string) string {
+	name = strings.TrimPrefix(strings.TrimSpace(name), prefix)
+	return strings.Trim(importLabelRegex.ReplaceAllString(name, "-"), "-.")
+}
+
+// importHeading normalizes an imported board, column or lane name, which
+// must fit on one heading line
+func importHeading(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// buildImportedBoards groups cards into boards and columns in the order they
+// first appear. Cards without a board go on defaultBoard.
+func buildImportedBoards(defaultBoard string, cards []kanbanImportCard, columnOrder []string) []KanbanBoard {
+	var boards []KanbanBoard
+	boardIndex := make(map[string]int)
+	columnIndex := make(map[string]int)
+
+	getColumn := func(boardTitle, columnTitle string) *KanbanColumn {
+		boardTitle = boardOrDefault(importHeading(boardTitle), defaultBoard)
+		columnTitle = importHeading(columnTitle)
+		b, ok := boardIndex[boardTitle]
+		if !ok {
+			b = len(boards)
+			boardIndex[boardTitle] = b
+			boards = append(boards, KanbanBoard{Title: boardTitle, Columns: []KanbanColumn{}})
+		}
+		key := boardTitle + "\x00" + columnTitle
+		c, ok := columnIndex[key]
+		if !ok {
+			c = len(boards[b].Columns)
+			columnIndex[key] = c
+			boards[b].Columns = append(boards[b].Columns, newKanbanColumn(columnTitle))
+		}
+		return &boards[b].Columns[c]
+	}
+
+	// Columns without cards are kept, in their original order
+	for _, column := range columnOrder {
+		getColumn("", column)
+	}
+
+	for _, card := range cards {
+		title := card.taskText()
+		if title == "" {
+			continue
+		}
+		column := getColumn(card.Board, card.Column)
+
+		lane := importHeading(card.Lane)
+		task := newKanbanTask(title, card.Done, 0)
+		task.Lane = lane
+		column.Tasks = append(column.Tasks, task)
+
+		for _, subtask := range card.Subtasks {
+			if text := subtask.taskText(); text != "" {
+				sub := newKanbanTask(text, subtask.Done, 1)
+				sub.Lane = lane
+				column.Tasks = append(column.Tasks, sub)
+			}
+		}
+
+		if lane != "" {
+			boards[boardIndex[boardOrDefault(importHeading(card.Board), defaultBoard)]].addLane(lane)
+		}
+	}
+
+	for i := range boards {
+		finishKanbanBoard(&boards[i])
+	}
+	return boards
+}
+
+// boardOrDefault returns board, or fallback if board is empty
+func boardOrDefault(board, fallback string) string {
+	if board == "" {
+		return fallback
+	}
+	return board
+}
+
+// trelloBoard is the part of a Trello board JSON export that is imported
+type trelloBoard struct {
+	Name  string `json:"name"`
+	Lists []struct {
+		ID     string  `json:"id"`
+		Name   string  `json:"name"`
+		Closed bool    `json:"closed"`
+		Pos    float64 `json:"pos"`
+	} `json:"lists"`
+	Cards []struct {
+		ID          string  `json:"id"`
+		Name        string  `json:"name"`
+		IDList      string  `json:"idList"`
+		Closed      bool    `json:"closed"`
+		Pos         float64 `json:"pos"`
+		Due         string  `json:"due"`
+		DueComplete bool    `json:"dueComplete"`
+		Labels      []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"labels"`
+		IDMembers []string `json:"idMembers"`
+	} `json:"cards"`
+	Members []struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"members"`
+	Checklists []struct {
+		IDCard     string `json:"idCard"`
+		CheckItems []struct {
+			Name  string  `json:"name"`
+			State string  `json:"state"`
+			Pos   float64 `json:"pos"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+}
+
+// ImportTrelloBoard converts a Trello board JSON export into a kanban board.
+// Open lists become columns and open cards become tasks, with their labels,
+// members, due dates and checklist items. Cards whose due date is marked
+// complete are checked. It returns the board name, or defaultName if the
+// export has none, and the boards.
+func ImportTrelloBoard(data []byte, defaultName string) (string, []KanbanBoard, error) {
+	var board trelloBoard
+	if err := json.Unmarshal(data, &board); err != nil {
+		return "", nil, fmt.Errorf("invalid Trello export: %w", err)
+	}
+	if len(board.Lists) == 0 {
+		return "", nil, errors.New("invalid Trello export: no lists found")
+	}
+
+	lists := board.Lists
+	sort.SliceStable(lists, func(i, j int) bool { return lists[i].Pos < lists[j].Pos })
+
+	listNames := make(map[string]string)
+	var columnOrder []string
+	for _, list := range lists {
+		if list.Closed {
+			continue
+		}
+		listNames[list.ID] = list.Name
+		columnOrder = append(columnOrder, list.Name)
+	}
+
+	members := make(map[string]string)
+	for _, member := range board.Members {
+		members[member.ID] = member.Username
+	}
+
+	checkItems := make(map[string][]kanbanImportCard)
+	for _, checklist := range board.Checklists {
+		items := checklist.CheckItems
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+		for _, item := range items {
+			checkItems[checklist.IDCard] = append(checkItems[checklist.IDCard], kanbanImportCard{
+				Title: item.Name,
+				Done:  item.State == "complete",
+			})
+		}
+	}
+
+	cards := board.Cards
+	sort.SliceStable(cards, func(i, j int) bool { return cards[i].Pos < cards[j].Pos })
+
+	var imported []kanbanImportCard
+	for _, card := range cards {
+		column, ok := listNames[card.IDList]
+		if card.Closed || !ok {
+			continue
+		}
+
+		importCard := kanbanImportCard{
+			Column:   column,
+			Title:    card.Name,
+			Done:     card.DueComplete,
+			Due:      importDate(card.Due),
+			Subtasks: checkItems[card.ID],
+		}
+		for _, label := range card.Labels {
+			// Trello labels may have only a colour
+			name := label.Name
+			if name == "" {
+				name = label.Color
+			}
+			importCard.Labels = append(importCard.Labels, name)
+		}
+		for _, id := range card.IDMembers {
+			if username := members[id]; username != "" {
+				importCard.Assignees = append(importCard.Assignees, username)
+			}
+		}
+		imported = append(imported, importCard)
+	}
+
+	title := boardOrDefault(importHeading(board.Name), defaultName)
+	return title, buildImportedBoards(title, imported, columnOrder), nil
+}
+
+// ImportKanbanCSV converts a CSV file into kanban boards. The first row must
+// name the fields, see KanbanCSVHeader; column and title are required. Cards
+// without a board go on defaultBoard.
+func ImportKanbanCSV(data []byte, defaultBoard string) ([]KanbanBoard, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV file: %w", err)
+	}
+
+	fields := make(map[string]int)
+	for i, name := range header {
+		fields[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"column", "title"} {
+		if _, ok := fields[required]; !ok {
+			return nil, fmt.Errorf("invalid CSV file: missing %q column", required)
+		}
+	}
+
+	var cards []kanbanImportCard
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV file: %w", err)
+		}
+
+		field := func(name string) string {
+			if i, ok := fields[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		column := field("column")
+		if column == "" || field("title") == "" {
+			continue
+		}
+
+		cards = append(cards, kanbanImportCard{
+			Board:     field("board"),
+			Column:    column,
+			Lane:      field("lane"),
+			Title:     field("title"),
+			Done:      parseImportBool(field("done")),
+			Labels:    splitImportList(field("labels")),
+			Due:       importDate(field("due")),
+			Assignees: splitImportList(field("assignees")),
+			Priority:  field("priority"),
+		})
+	}
+
+	if len(cards) == 0 {
+		return nil, errors.New("no cards found in CSV file")
+	}
+	return buildImportedBoards(defaultBoard, cards, nil), nil
+}
+
+// ExportKanbanCSV writes the boards as CSV with the KanbanCSVHeader fields.
+// Subtasks are exported as cards of their own.
+func ExportKanbanCSV(boards []KanbanBoard) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	if err := writer.Write(KanbanCSVHeader); err != nil {
+		return nil, err
+	}
+
+	for _, board := range boards {
+		for _, column := range board.Columns {
+			for _, task := range column.Tasks {
+				due := ""
+				if !task.Due.IsZero() {
+					due = task.Due.Format("2006-01-02")
+				}
+				record := []string{
+					board.Title,
+					column.Title,
+					task.Lane,
+					KanbanTaskSource(task.DisplayText),
+					fmt.Sprint(task.Checked),
+					strings.Join(task.Labels, ";"),
+					due,
+					strings.Join(task.Assignees, ";"),
+					task.Priority,
+				}
+				if err := writer.Write(record); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// importDate returns the YYYY-MM-DD date of an imported due date, which may
+// also be a full timestamp, or an empty string if it is not a date
+func importDate(value string) string {
+	if len(value) < 10 {
+		return ""
+	}
+	date, err := time.Parse("2006-01-02", value[:10])
+	if err != nil {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
+// parseImportBool reports whether a CSV done field means the card is done
+func parseImportBool(value string) bool {
+	switch strings.ToLower(value) {
+	case "1", "x", "y", "yes", "true", "done", "complete", "completed":
+		return true
+	}
+	return false
+}
+
+// splitImportList splits a CSV list field on semicolons or commas
+func splitImportList(value string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
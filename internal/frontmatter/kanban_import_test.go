@@ -0,0 +1,94 @@
+package frontmatter
+
+import (
+	"strings"
+	"testing"
+)
+
+const trelloFixture = `{
+  "name": "Release",
+  "lists": [
+    {"id": "l2", "name": "Doing", "pos": 2},
+    {"id": "l1", "name": "To Do", "pos": 1},
+    {"id": "l3", "name": "Archive", "pos": 3, "closed": true}
+  ],
+  "cards": [
+    {"id": "c2", "name": "Write notes", "idList": "l1", "pos": 2, "labels": [{"name": "docs"}]},
+    {"id": "c1", "name": "Fix build", "idList": "l1", "pos": 1, "due": "2026-03-01T12:00:00.000Z", "idMembers": ["m1"], "labels": [{"color": "red"}]},
+    {"id": "c3", "name": "Ship", "idList": "l2", "pos": 1, "dueComplete": true},
+    {"id": "c4", "name": "Old", "idList": "l3", "pos": 1}
+  ],
+  "members": [{"id": "m1", "username": "alice"}],
+  "checklists": [
+    {"idCard": "c3", "checkItems": [{"name": "Tag", "state": "complete", "pos": 1}]}
+  ]
+}`
+
+func TestImportTrelloBoard(t *testing.T) {
+	title, boards, err := ImportTrelloBoard([]byte(trelloFixture), "Fallback")
+	if err != nil {
+		t.Fatalf("ImportTrelloBoard: %v", err)
+	}
+	if title != "Release" {
+		t.Errorf("title = %q, want Release", title)
+	}
+	if len(boards) != 1 || len(boards[0].Columns) != 2 {
+		t.Fatalf("boards = %+v, want one board with two columns", boards)
+	}
+
+	todo := boards[0].Columns[0]
+	if todo.Name != "To Do" || len(todo.Tasks) != 2 {
+		t.Fatalf("first column = %+v", todo)
+	}
+	first := todo.Tasks[0]
+	if first.DisplayText != "Fix build" || first.Due.Format("2006-01-02") != "2026-03-01" ||
+		len(first.Assignees) != 1 || first.Assignees[0] != "alice" || len(first.Labels) != 1 || first.Labels[0] != "red" {
+		t.Errorf("first task = %+v", first)
+	}
+
+	doing := boards[0].Columns[1]
+	if len(doing.Tasks) != 2 || !doing.Tasks[0].Checked || doing.Tasks[1].IndentLevel != 1 || !doing.Tasks[1].Checked {
+		t.Errorf("second column = %+v", doing)
+	}
+}
+
+func TestKanbanCSVRoundTrip(t *testing.T) {
+	input := "title,column,done,labels,due,board\n" +
+		"Fix build,Todo,,bug;urgent,2026-03-01,\n" +
+		"Ship,Done (done),yes,,,\n" +
+		"Plan,Todo,,,,Next\n"
+
+	boards, err := ImportKanbanCSV([]byte(input), "Main")
+	if err != nil {
+		t.Fatalf("ImportKanbanCSV: %v", err)
+	}
+	if len(boards) != 2 || boards[0].Title != "Main" || boards[1].Title != "Next" {
+		t.Fatalf("boards = %+v", boards)
+	}
+	if !boards[0].Columns[1].Done || !boards[0].Columns[1].Tasks[0].Checked {
+		t.Errorf("done column = %+v", boards[0].Columns[1])
+	}
+
+	markdown := NewKanbanMarkdown("Imported", boards)
+	if !strings.Contains(markdown, "layout: kanban") || !strings.Contains(markdown, "- [ ] Fix build") {
+		t.Errorf("markdown = %q", markdown)
+	}
+
+	exported, err := ExportKanbanCSV(ParseKanbanBoards(markdown))
+	if err != nil {
+		t.Fatalf("ExportKanbanCSV: %v", err)
+	}
+	again, err := ImportKanbanCSV(exported, "Other")
+	if err != nil {
+		t.Fatalf("re-import: %v", err)
+	}
+	if NewKanbanMarkdown("Imported", again) != markdown {
+		t.Errorf("round trip changed the board:\n%s\n---\n%s", NewKanbanMarkdown("Imported", again), markdown)
+	}
+}
+
+func TestImportKanbanCSVRequiresHeader(t *testing.T) {
+	if _, err := ImportKanbanCSV([]byte("a,b\n1,2\n"), "Main"); err == nil {
+		t.Error("expected an error for a file without column and title fields")
+	}
+}
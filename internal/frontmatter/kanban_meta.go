@@ -0,0 +1,256 @@
+package frontmatter
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"wiki-go/internal/i18n"
+)
+
+// Inline task metadata tokens, matched against whitespace-separated words
+var (
+	assigneeTokenRegex = regexp.MustCompile(`^@([\p{L}\p{N}_.-]*[\p{L}\p{N}_])$`)
+	dueTokenRegex      = regexp.MustCompile(`^due:(\d{4}-\d{2}-\d{2})$`)
+	doneTokenRegex     = regexp.MustCompile(`^done:(\d{4}-\d{2}-\d{2})$`)
+	labelTokenRegex    = regexp.MustCompile(`^#(\p{L}[\p{L}\p{N}_/-]*)$`)
+	priorityTokenRegex = regexp.MustCompile(`^!(?i)(high|medium|low)$`)
+
+	// Parts of task text where tokens are not metadata: inline code, link
+	// destinations and HTML comments such as task IDs
+	taskMetaMaskRegex = regexp.MustCompile("`[^`]*`|\\]\\([^)]*\\)|<!--.*?-->")
+
+	taskWordRegex = regexp.MustCompile(`\S+`)
+)
+
+// TaskMetadata holds the inline metadata parsed from a task's text
+type TaskMetadata struct {
+	Assignees   []string  // @user mentions
+	Labels      []string  // #label tags
+	Due         time.Time // due:YYYY-MM-DD, zero if not set
+	Completed   time.Time // done:YYYY-MM-DD, zero if not set
+	Priority    string    // !high, !medium or !low, lower case
+	DisplayText string    // Task text with the metadata tokens removed
+}
+
+// ParseTaskMetadata extracts @assignee, due:YYYY-MM-DD, done:YYYY-MM-DD,
+// #label and !priority tokens from task text. Tokens inside inline code and link destinations are
+// ignored. The markdown itself is never rewritten, so the source stays the
+// only storage for the metadata.
+func ParseTaskMetadata(text string) TaskMetadata {
+	var meta TaskMetadata
+
+	// Blank out regions that must not be scanned, keeping byte offsets intact
+	masked := taskMetaMaskRegex.ReplaceAllStringFunc(text, func(m string) string {
+		return strings.Repeat(" ", len(m))
+	})
+
+	type span struct{ start, end int }
+	var tokens []span
+
+	for _, loc := range taskWordRegex.FindAllStringIndex(masked, -1) {
+		word := strings.TrimRight(masked[loc[0]:loc[1]], ",;:.)")
+		end := loc[0] + len(word)
+
+		if m := assigneeTokenRegex.FindStringSubmatch(word); m != nil {
+			meta.Assignees = appendUnique(meta.Assignees, m[1])
+		} else if m := dueTokenRegex.FindStringSubmatch(word); m != nil {
+			due, err := time.Parse("2006-01-02", m[1])
+			if err != nil {
+				continue
+			}
+			meta.Due = due
+		} else if m := doneTokenRegex.FindStringSubmatch(word); m != nil {
+			completed, err := time.Parse("2006-01-02", m[1])
+			if err != nil {
+				continue
+			}
+			meta.Completed = completed
+		} else if m := labelTokenRegex.FindStringSubmatch(word); m != nil {
+			meta.Labels = appendUnique(meta.Labels, m[1])
+		} else if m := priorityTokenRegex.FindStringSubmatch(word); m != nil {
+			meta.Priority = strings.ToLower(m[1])
+		} else {
+			continue
+		}
+
+		tokens = append(tokens, span{loc[0], end})
+	}
+
+	// Remove the tokens from the displayed text
+	if len(tokens) == 0 {
+		meta.DisplayText = text
+		return meta
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, t := range tokens {
+		b.WriteString(text[last:t.start])
+		last = t.end
+	}
+	b.WriteString(text[last:])
+	meta.DisplayText = strings.Join(strings.Fields(b.String()), " ")
+
+	return meta
+}
+
+// newKanbanTask creates a task from its markdown text, parsing inline metadata
+func newKanbanTask(text string, checked bool, indentLevel int) KanbanTask {
+	meta := ParseTaskMetadata(text)
+	return KanbanTask{
+		Text:        text,
+		Checked:     checked,
+		HTMLText:    meta.DisplayText,
+		IndentLevel: indentLevel,
+		Assignees:   meta.Assignees,
+		Labels:      meta.Labels,
+		Due:         meta.Due,
+		Completed:   meta.Completed,
+		Priority:    meta.Priority,
+		DisplayText: meta.DisplayText,
+	}
+}
+
+// IsOverdue reports whether an unchecked task is past its due date
+func (t KanbanTask) IsOverdue(now time.Time) bool {
+	if t.Checked || t.Due.IsZero() {
+		return false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return t.Due.Before(today)
+}
+
+// renderKanbanTask renders a task as a list item compatible with tasklist-live.js,
+// with metadata chips and data attributes used for filtering
+func renderKanbanTask(task KanbanTask, now time.Time) string {
+	checkedAttr := ""
+	if task.Checked {
+		checkedAttr = "checked"
+	}
+
+	var attrs strings.Builder
+
+	// Add indentation level attribute if needed
+	if task.IndentLevel > 0 {
+		fmt.Fprintf(&attrs, ` data-indent-level="%d"`, task.IndentLevel)
+	}
+
+	// Keep the source markdown so client-side edits don't lose the metadata
+	fmt.Fprintf(&attrs, ` data-original-markdown="%s"`, html.EscapeString(KanbanTaskSource(task.Text)))
+
+	if len(task.Assignees) > 0 {
+		fmt.Fprintf(&attrs, ` data-assignees="%s"`, html.EscapeString(strings.Join(task.Assignees, " ")))
+	}
+	if len(task.Labels) > 0 {
+		fmt.Fprintf(&attrs, ` data-labels="%s"`, html.EscapeString(strings.Join(task.Labels, " ")))
+	}
+	if !task.Due.IsZero() {
+		fmt.Fprintf(&attrs, ` data-due="%s"`, task.Due.Format("2006-01-02"))
+	}
+	if task.Priority != "" {
+		fmt.Fprintf(&attrs, ` data-priority="%s"`, task.Priority)
+	}
+
+	classes := "task-list-item-container"
+	if task.IsOverdue(now) {
+		classes += " task-overdue"
+	}
+
+	return fmt.Sprintf(`<li class="%s" style="list-style-type: none;"%s>
+		<span class="task-list-item">
+			<input type="checkbox" class="task-checkbox" %s disabled>
+			<span class="task-text">%s</span>%s
+			<span class="save-state"></span>
+		</span>
+	</li>`, classes, attrs.String(), checkedAttr, task.HTMLText, renderTaskChips(task, now))
+}
+
+// renderTaskChips renders the metadata of a task as chips
+func renderTaskChips(task KanbanTask, now time.Time) string {
+	if len(task.Assignees) == 0 && len(task.Labels) == 0 && task.Due.IsZero() && task.Completed.IsZero() && task.Priority == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(`<span class="task-meta">`)
+
+	if task.Priority != "" {
+		fmt.Fprintf(&b, `<span class="task-chip task-priority priority-%s">!%s</span>`, task.Priority, task.Priority)
+	}
+	for _, assignee := range task.Assignees {
+		fmt.Fprintf(&b, `<span class="task-chip task-assignee">@%s</span>`, html.EscapeString(assignee))
+	}
+	for _, label := range task.Labels {
+		fmt.Fprintf(&b, `<span class="task-chip task-label">#%s</span>`, html.EscapeString(label))
+	}
+	if !task.Due.IsZero() {
+		class := "task-chip task-due"
+		title := i18n.Translate("kanban.due_date")
+		if task.IsOverdue(now) {
+			class += " overdue"
+			title = i18n.Translate("kanban.overdue")
+		}
+		fmt.Fprintf(&b, `<span class="%s" title="%s"><i class="fa fa-calendar"></i> %s</span>`, class, html.EscapeString(title), task.Due.Format("2006-01-02"))
+	}
+	if !task.Completed.IsZero() {
+		fmt.Fprintf(&b, `<span class="task-chip task-completed" title="%s"><i class="fa fa-check"></i> %s</span>`, html.EscapeString(i18n.Translate("kanban.completed_date")), task.Completed.Format("2006-01-02"))
+	}
+
+	b.WriteString(`</span>`)
+	return b.String()
+}
+
+// renderKanbanFilterBar renders assignee and label filters for a board, or
+// nothing if no task on the board has either
+func renderKanbanFilterBar(board KanbanBoard) string {
+	var assignees, labels []string
+	for _, column := range board.Columns {
+		for _, task := range column.Tasks {
+			for _, assignee := range task.Assignees {
+				assignees = appendUnique(assignees, assignee)
+			}
+			for _, label := range task.Labels {
+				labels = appendUnique(labels, label)
+			}
+		}
+	}
+
+	if len(assignees) == 0 && len(labels) == 0 {
+		return ""
+	}
+
+	sort.Strings(assignees)
+	sort.Strings(labels)
+
+	var b strings.Builder
+	b.WriteString(`<div class="kanban-filter-bar">`)
+	writeFilter := func(name, allLabel, prefix string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, `<select class="kanban-filter" data-filter="%s"><option value="">%s</option>`, name, html.EscapeString(allLabel))
+		for _, value := range values {
+			fmt.Fprintf(&b, `<option value="%s">%s%s</option>`, html.EscapeString(value), prefix, html.EscapeString(value))
+		}
+		b.WriteString(`</select>`)
+	}
+	writeFilter("assignee", i18n.Translate("kanban.all_assignees"), "@", assignees)
+	writeFilter("label", i18n.Translate("kanban.all_labels"), "#", labels)
+	b.WriteString(`</div>`)
+
+	return b.String()
+}
+
+// appendUnique appends value to values unless it is already present
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,48 @@
+package frontmatter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTaskMetadata(t *testing.T) {
+	meta := ParseTaskMetadata("Fix login @alice @bob.smith, #bug #ui due:2024-03-01 !HIGH see `@not #meta` and [docs](https://x.test/#anchor) <!-- id:1 -->")
+
+	if !reflect.DeepEqual(meta.Assignees, []string{"alice", "bob.smith"}) {
+		t.Errorf("unexpected assignees: %v", meta.Assignees)
+	}
+	if !reflect.DeepEqual(meta.Labels, []string{"bug", "ui"}) {
+		t.Errorf("unexpected labels: %v", meta.Labels)
+	}
+	if !meta.Due.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected due date: %v", meta.Due)
+	}
+	if meta.Priority != "high" {
+		t.Errorf("unexpected priority: %q", meta.Priority)
+	}
+
+	want := "Fix login , see `@not #meta` and [docs](https://x.test/#anchor) <!-- id:1 -->"
+	if meta.DisplayText != want {
+		t.Errorf("unexpected display text:\n got: %q\nwant: %q", meta.DisplayText, want)
+	}
+}
+
+func TestKanbanTaskIsOverdue(t *testing.T) {
+	now := time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	task := newKanbanTask("Ship it due:2024-03-01", false, 0)
+	if !task.IsOverdue(now) {
+		t.Error("expected unchecked task past its due date to be overdue")
+	}
+
+	task = newKanbanTask("Ship it due:2024-03-01", true, 0)
+	if task.IsOverdue(now) {
+		t.Error("expected checked task not to be overdue")
+	}
+
+	task = newKanbanTask("Ship it due:2024-03-02", false, 0)
+	if task.IsOverdue(now) {
+		t.Error("expected task due today not to be overdue")
+	}
+}
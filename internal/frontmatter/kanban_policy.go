@@ -0,0 +1,237 @@
+package frontmatter
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"wiki-go/internal/i18n"
+)
+
+// Column policies are written in parentheses at the end of a column title,
+// for example "##### In Progress (WIP 3)" or "##### Done (done, WIP 10)"
+var (
+	columnPolicyRegex = regexp.MustCompile(`^(.*?)\s*\(([^()]*)\)\s*$`)
+	wipPolicyRegex    = regexp.MustCompile(`^(?i)wip\s*:?\s*(\d+)$`)
+
+	// H6 headings inside a column start a swimlane
+	kanbanLaneLineRegex = regexp.MustCompile(`^#{6}\s+(.+)$`)
+
+	// completedTokenRegex matches a completion stamp in task text
+	completedTokenRegex = regexp.MustCompile(`(?:^|\s+)done:\d{4}-\d{2}-\d{2}\b`)
+)
+
+// newKanbanColumn creates an empty column from its heading text, parsing
+// any column policies
+func newKanbanColumn(title string) KanbanColumn {
+	column := KanbanColumn{Title: title, Name: title, Tasks: []KanbanTask{}}
+
+	m := columnPolicyRegex.FindStringSubmatch(title)
+	if m == nil || m[1] == "" {
+		return column
+	}
+
+	// Only treat the parentheses as policies if every part is one, so
+	// titles such as "Backlog (old)" are left alone
+	var wip int
+	var done bool
+	for _, part := range strings.Split(m[2], ",") {
+		part = strings.TrimSpace(part)
+		if wm := wipPolicyRegex.FindStringSubmatch(part); wm != nil {
+			wip, _ = strconv.Atoi(wm[1])
+		} else if strings.EqualFold(part, "done") {
+			done = true
+		} else {
+			return column
+		}
+	}
+
+	column.Name = m[1]
+	column.WIPLimit = wip
+	column.Done = done
+	return column
+}
+
+// columnPolicySuffix returns the policy part of a column title, including
+// its leading space, or an empty string if the column has no policies
+func (c KanbanColumn) columnPolicySuffix() string {
+	return strings.TrimPrefix(c.Title, c.Name)
+}
+
+// TaskCount returns the number of cards in a column, not counting subtasks
+func (c KanbanColumn) TaskCount() int {
+	count := 0
+	for _, task := range c.Tasks {
+		if task.IndentLevel == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// OverWIPLimit reports whether a column holds more cards than its WIP limit
+func (c KanbanColumn) OverWIPLimit() bool {
+	return c.WIPLimit > 0 && c.TaskCount() > c.WIPLimit
+}
+
+// addLane records a swimlane in the order it first appears on the board
+func (b *KanbanBoard) addLane(lane string) {
+	for _, l := range b.Lanes {
+		if l == lane {
+			return
+		}
+	}
+	b.Lanes = append(b.Lanes, lane)
+}
+
+// HasSwimlanes reports whether a board groups its tasks into swimlanes
+func (b KanbanBoard) HasSwimlanes() bool {
+	return len(b.Lanes) > 0
+}
+
+// finishKanbanBoard completes a parsed board. Subtasks are moved into the
+// lane of their parent, and tasks are ordered by lane so the tasks of a
+// column are in the same order as on the rendered board. Tasks outside any
+// lane are shown in an unnamed first lane.
+func finishKanbanBoard(board *KanbanBoard) {
+	if len(board.Lanes) == 0 {
+		return
+	}
+
+	hasDefaultLane := false
+	for c := range board.Columns {
+		tasks := board.Columns[c].Tasks
+		lane := ""
+		for t := range tasks {
+			if tasks[t].IndentLevel == 0 {
+				lane = tasks[t].Lane
+			} else if t > 0 {
+				tasks[t].Lane = lane
+			}
+			if tasks[t].Lane == "" {
+				hasDefaultLane = true
+			}
+		}
+	}
+
+	if hasDefaultLane && board.Lanes[0] != "" {
+		board.Lanes = append([]string{""}, board.Lanes...)
+	}
+
+	order := make(map[string]int, len(board.Lanes))
+	for i, lane := range board.Lanes {
+		order[lane] = i
+	}
+	for c := range board.Columns {
+		tasks := board.Columns[c].Tasks
+		sort.SliceStable(tasks, func(i, j int) bool {
+			return order[tasks[i].Lane] < order[tasks[j].Lane]
+		})
+	}
+}
+
+// renderKanbanColumnPolicy renders the WIP count and done marker shown next
+// to a column title
+func renderKanbanColumnPolicy(column KanbanColumn) string {
+	var b strings.Builder
+	if column.Done {
+		fmt.Fprintf(&b, `<span class="kanban-done-marker" title="%s"><i class="fa fa-check"></i></span>`, html.EscapeString(i18n.Translate("kanban.done_column")))
+	}
+	if column.WIPLimit > 0 {
+		fmt.Fprintf(&b, `<span class="kanban-wip" title="%s">%d/%d</span>`, html.EscapeString(i18n.Translate("kanban.wip_limit")), column.TaskCount(), column.WIPLimit)
+	}
+	return b.String()
+}
+
+// kanbanColumnAttrs returns the class and data attributes of a column element
+func kanbanColumnAttrs(column KanbanColumn) string {
+	classes := "kanban-column"
+	if column.OverWIPLimit() {
+		classes += " over-wip-limit"
+	}
+	if column.Done {
+		classes += " kanban-column-done"
+	}
+
+	attrs := fmt.Sprintf(`class="%s"`, classes)
+	if column.WIPLimit > 0 {
+		attrs += fmt.Sprintf(` data-wip-limit="%d"`, column.WIPLimit)
+	}
+	if column.Done {
+		attrs += ` data-done-column="true"`
+	}
+	return attrs
+}
+
+// renderKanbanColumnTasks renders the task lists of a column: a single list,
+// or one list per swimlane if the board has lanes
+func renderKanbanColumnTasks(column KanbanColumn, lanes []string, now time.Time) string {
+	var b strings.Builder
+
+	if len(lanes) == 0 {
+		b.WriteString(`<ul class="task-list">`)
+		for _, task := range column.Tasks {
+			b.WriteString(renderKanbanTask(task, now))
+		}
+		b.WriteString(`</ul>`)
+		return b.String()
+	}
+
+	for _, lane := range lanes {
+		fmt.Fprintf(&b, `<div class="kanban-lane" data-lane="%s"><div class="kanban-lane-title">%s</div><ul class="task-list">`, html.EscapeString(lane), html.EscapeString(lane))
+		for _, task := range column.Tasks {
+			if task.Lane == lane {
+				b.WriteString(renderKanbanTask(task, now))
+			}
+		}
+		b.WriteString(`</ul></div>`)
+	}
+	return b.String()
+}
+
+// kanbanBoardAttrs returns the class and style attributes of a board's
+// column container
+func kanbanBoardAttrs(board KanbanBoard) string {
+	if !board.HasSwimlanes() {
+		return `class="kanban-board"`
+	}
+	return fmt.Sprintf(`class="kanban-board kanban-swimlanes" style="grid-template-rows: auto repeat(%d, auto)"`, len(board.Lanes))
+}
+
+// stampTaskCompleted adds a done:YYYY-MM-DD token to a task's text, before
+// any trailing comment, unless it already has one
+func stampTaskCompleted(task KanbanTask, now time.Time) KanbanTask {
+	if !task.Completed.IsZero() {
+		task.Checked = true
+		return task
+	}
+
+	text := task.Text
+	comment := ""
+	if i := strings.Index(text, "<!--"); i >= 0 {
+		text, comment = strings.TrimSpace(text[:i]), " "+text[i:]
+	}
+	text += " done:" + now.Format("2006-01-02") + comment
+
+	stamped := newKanbanTask(text, true, task.IndentLevel)
+	stamped.Lane = task.Lane
+	return stamped
+}
+
+// clearTaskCompleted removes the done:YYYY-MM-DD token from a task's text
+// and unchecks it
+func clearTaskCompleted(task KanbanTask) KanbanTask {
+	if task.Completed.IsZero() {
+		return task
+	}
+
+	text := strings.TrimSpace(completedTokenRegex.ReplaceAllString(task.Text, ""))
+
+	cleared := newKanbanTask(text, false, task.IndentLevel)
+	cleared.Lane = task.Lane
+	return cleared
+}
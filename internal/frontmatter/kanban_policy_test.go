@@ -0,0 +1,94 @@
+package frontmatter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewKanbanColumnPolicies(t *testing.T) {
+	tests := []struct {
+		title string
+		name  string
+		wip   int
+		done  bool
+	}{
+		{"In Progress (WIP 3)", "In Progress", 3, false},
+		{"Done (done, wip: 10)", "Done", 10, true},
+		{"Backlog (old)", "Backlog (old)", 0, false},
+		{"Todo", "Todo", 0, false},
+	}
+
+	for _, tt := range tests {
+		column := newKanbanColumn(tt.title)
+		if column.Name != tt.name || column.WIPLimit != tt.wip || column.Done != tt.done {
+			t.Errorf("newKanbanColumn(%q) = %q, %d, %v", tt.title, column.Name, column.WIPLimit, column.Done)
+		}
+	}
+}
+
+const kanbanLanesFixture = `---
+layout: kanban
+kanban_wip: strict
+---
+#### Sprint
+
+##### Todo
+- [ ] Unsorted
+###### Frontend
+- [ ] Login page
+  - [ ] Styles
+###### Backend
+- [ ] API
+
+##### Doing (WIP 1)
+###### Backend
+- [ ] Database
+
+##### Done (done)
+`
+
+func TestKanbanDocumentSwimlanes(t *testing.T) {
+	doc := ParseKanbanDocument(kanbanLanesFixture)
+	board := doc.Boards[0]
+
+	if strings.Join(board.Lanes, "|") != "|Frontend|Backend" {
+		t.Fatalf("unexpected lanes: %q", board.Lanes)
+	}
+	if styles := board.Columns[0].Tasks[2]; styles.Text != "Styles" || styles.Lane != "Frontend" {
+		t.Errorf("unexpected subtask: %+v", styles)
+	}
+
+	// Canonical output keeps the lanes and is stable
+	markdown := doc.Markdown()
+	if again := ParseKanbanDocument(markdown).Markdown(); again != markdown {
+		t.Errorf("canonical markdown changed on reparse:\n%s", again)
+	}
+
+	// Doing is at its limit and the board enforces it
+	if err := doc.MoveTask(0, 0, 3, "API", 0, 1, 0, 0, "Backend"); !errors.Is(err, ErrKanbanWIPLimit) {
+		t.Errorf("expected WIP limit error, got %v", err)
+	}
+	if err := doc.MoveTask(0, 0, 1, "Login page", 0, 0, 3, 0, "Nowhere"); !errors.Is(err, ErrKanbanNotFound) {
+		t.Errorf("expected not found for unknown lane, got %v", err)
+	}
+
+	// Moving into the done column checks and stamps the task
+	if err := doc.MoveTask(0, 0, 1, "Login page", 0, 2, 0, 0, "Frontend"); err != nil {
+		t.Fatalf("MoveTask failed: %v", err)
+	}
+	done := doc.Boards[0].Columns[2].Tasks
+	today := time.Now().Format("2006-01-02")
+	if len(done) != 2 || !done[0].Checked || done[0].Text != "Login page done:"+today || done[1].Lane != "Frontend" {
+		t.Errorf("unexpected done column: %+v", done)
+	}
+
+	// Moving it back out removes the stamp
+	if err := doc.MoveTask(0, 2, 0, "Login page done:"+today, 0, 0, 0, 0, ""); err != nil {
+		t.Fatalf("MoveTask failed: %v", err)
+	}
+	if task := doc.Boards[0].Columns[0].Tasks[0]; task.Checked || task.Text != "Login page" {
+		t.Errorf("unexpected reopened task: %+v", task)
+	}
+}
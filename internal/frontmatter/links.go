@@ -6,6 +6,7 @@ import (
 	"net/url"
 	urlPkg "net/url"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -25,6 +26,7 @@ type Link struct {
 type LinksData struct {
 	Title      string             `json:"title"`       // Document title (H1)
 	Categories map[string][]Link  `json:"categories"`  // Links organized by category
+	CategoryOrder []string        `json:"category_order"` // Category names in document order
 	TotalLinks int                `json:"total_links"` // Total number of links
 	Stats      LinksStats         `json:"stats"`       // Statistics for the links collection
 }
@@ -37,6 +39,23 @@ type LinksStats struct {
 	LatestAdded     time.Time `json:"latest_added"` // Most recent addition date
 }
 
+// LinkStatus is the result of the last dead-link check for a URL
+type LinkStatus struct {
+	Dead        bool
+	StatusCode  int
+	Error       string
+	RedirectURL string
+	CheckedAt   time.Time
+}
+
+// linkStatusProvider looks up the last check result for a URL, if any
+var linkStatusProvider func(url string) (LinkStatus, bool)
+
+// SetLinkStatusProvider registers the lookup used by RenderLinks to flag dead links
+func SetLinkStatusProvider(provider func(url string) (LinkStatus, bool)) {
+	linkStatusProvider = provider
+}
+
 // NewLinksData creates a new LinksData instance with initialized maps
 func NewLinksData() *LinksData {
 	return &LinksData{
@@ -50,6 +69,9 @@ func (ld *LinksData) AddLink(link Link) {
 	if ld.Categories == nil {
 		ld.Categories = make(map[string][]Link)
 	}
+	if _, exists := ld.Categories[link.Category]; !exists {
+		ld.CategoryOrder = append(ld.CategoryOrder, link.Category)
+	}
 	
 	ld.Categories[link.Category] = append(ld.Categories[link.Category], link)
 	ld.updateStats()
@@ -238,6 +260,7 @@ func ParseLinksContent(content string) (*LinksData, error) {
 			// Initialize category if it doesn't exist
 			if _, exists := data.Categories[currentCategory]; !exists {
 				data.Categories[currentCategory] = []Link{}
+				data.CategoryOrder = append(data.CategoryOrder, currentCategory)
 			}
 			continue
 		}
@@ -280,6 +303,7 @@ func ParseLinksContent(content string) (*LinksData, error) {
 			// Initialize category if it doesn't exist
 			if _, exists := data.Categories[currentCategory]; !exists {
 				data.Categories[currentCategory] = []Link{}
+				data.CategoryOrder = append(data.CategoryOrder, currentCategory)
 			}
 			
 			// Add link to category
@@ -326,6 +350,29 @@ func (ld *LinksData) calculateStats() {
 	}
 }
 
+// OrderedCategories returns the category names in document order, followed
+// by any categories added since parsing in alphabetical order
+func (ld *LinksData) OrderedCategories() []string {
+	seen := make(map[string]bool)
+	var ordered []string
+	for _, category := range ld.CategoryOrder {
+		if _, exists := ld.Categories[category]; exists && !seen[category] {
+			ordered = append(ordered, category)
+			seen[category] = true
+		}
+	}
+
+	var added []string
+	for category := range ld.Categories {
+		if !seen[category] {
+			added = append(added, category)
+		}
+	}
+	sort.Strings(added)
+
+	return append(ordered, added...)
+}
+
 // LinkValidationError represents an error in link validation
 type LinkValidationError struct {
 	Message string
@@ -346,6 +393,7 @@ func RenderLinks(content string) (string, error) {
 	// Create template with helper functions
 	t := template.New("links").Funcs(template.FuncMap{
 		"getFaviconURL": getFaviconURL,
+		"linkStatus":    getLinkStatus,
 	})
 	
 	// Read the external template file - for now we'll build a simple version
@@ -384,6 +432,11 @@ func RenderLinks(content string) (string, error) {
                 </div>
             </div>
         </div>
+        <div class="bookmark-actions">
+            <button type="button" class="clear-filters" id="exportBookmarks">` + i18n.Translate("links.export_bookmarks") + `</button>
+            <button type="button" class="clear-filters editor-admin-only" id="importBookmarks">` + i18n.Translate("links.import_bookmarks") + `</button>
+            <input type="file" id="importBookmarksFile" accept=".html,.htm" style="display: none;">
+        </div>
         <div class="search-results-info" id="searchResultsInfo" style="display: none;">
             <span class="results-count">0 links found</span>
             <button type="button" class="clear-filters" id="clearFilters">Clear all filters</button>
@@ -425,7 +478,8 @@ func RenderLinks(content string) (string, error) {
             </h2>
             
             {{range $links}}
-            <div class="link-item" data-category="{{.Category}}" data-title="{{.Title}}" data-description="{{.Description}}" data-url="{{.URL}}" data-date="{{.AddedAt.Unix}}">
+            {{$status := linkStatus .URL}}
+            <div class="link-item{{if and $status $status.Dead}} link-dead{{end}}" data-category="{{.Category}}" data-title="{{.Title}}" data-description="{{.Description}}" data-url="{{.URL}}" data-date="{{.AddedAt.Unix}}">
                 <div class="link-content">
                     <div class="link-title-row">
                         <div class="link-favicon">
@@ -434,6 +488,11 @@ func RenderLinks(content string) (string, error) {
                         <div class="link-title">
                             <a href="{{.URL}}" target="_blank" rel="noopener noreferrer" title="{{.URL}}">{{.Title}}</a>
                             <span class="external-icon">↗</span>
+                            {{if $status}}{{if $status.Dead}}
+                            <span class="link-status-badge dead" title="{{if $status.Error}}{{$status.Error}}{{else}}HTTP {{$status.StatusCode}}{{end}} - ` + i18n.Translate("links.checked") + ` {{$status.CheckedAt.Format "2006-01-02 15:04"}}">` + i18n.Translate("links.dead_link") + `</span>
+                            {{else if $status.RedirectURL}}
+                            <span class="link-status-badge redirect" title="{{$status.RedirectURL}}">` + i18n.Translate("links.redirected") + `</span>
+                            {{end}}{{end}}
                         </div>
                     </div>
                     <div class="link-description">{{.Description}}</div>
@@ -480,6 +539,17 @@ func RenderLinks(content string) (string, error) {
 
 // Helper functions for template rendering
 
+// getLinkStatus returns the last check result for a URL, or nil if it has not been checked
+func getLinkStatus(url string) *LinkStatus {
+	if linkStatusProvider == nil {
+		return nil
+	}
+	if status, ok := linkStatusProvider(url); ok {
+		return &status
+	}
+	return nil
+}
+
 // getFaviconURL returns the favicon URL for a given domain
 func getFaviconURL(url string) string {
 	// Try to extract domain from URL
@@ -0,0 +1,109 @@
+package frontmatter
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	// checklistHeadingRegex matches markdown headings above ordinary checklists
+	checklistHeadingRegex = regexp.MustCompile(`^#{1,6}\s+(.+?)\s*#*$`)
+	// taskHrefRegex matches the targets of links in formatted task text
+	taskHrefRegex = regexp.MustCompile(`href="([^"]*)"`)
+)
+
+// DocumentTask is a task found in a document, either on a kanban board or in
+// an ordinary checklist
+type DocumentTask struct {
+	KanbanTask
+	Board  string // Kanban board title, empty for checklists
+	Column string // Kanban column title, or the heading above a checklist
+}
+
+// FindDocumentTasks returns the tasks of a document. Tasks on kanban-layout
+// pages are read from their boards; on other pages every task list item
+// outside code blocks is returned, grouped under the heading above it.
+// HTMLText holds the task text without metadata, escaped, with basic
+// formatting.
+func FindDocumentTasks(content string) []DocumentTask {
+	var tasks []DocumentTask
+
+	metadata, body, _ := Parse(content)
+	if metadata.Layout == "kanban" {
+		for _, board := range ParseKanbanDocument(body).Boards {
+			for _, column := range board.Columns {
+				for _, task := range column.Tasks {
+					task.HTMLText = taskTextHTML(task.DisplayText)
+					tasks = append(tasks, DocumentTask{KanbanTask: task, Board: board.Title, Column: column.Name})
+				}
+			}
+		}
+		return tasks
+	}
+
+	heading := ""
+	inCodeBlock := false
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock {
+			continue
+		}
+
+		if m := checklistHeadingRegex.FindStringSubmatch(trimmed); m != nil && !strings.HasPrefix(line, " ") {
+			heading = m[1]
+			continue
+		}
+
+		m := kanbanTaskLineRegex.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		task := newKanbanTask(m[2], m[1] == "x" || m[1] == "X", len(indent)/2)
+		task.HTMLText = taskTextHTML(task.DisplayText)
+		tasks = append(tasks, DocumentTask{KanbanTask: task, Column: heading})
+	}
+
+	return tasks
+}
+
+// taskTextHTML returns the HTML of a task's text: the text is escaped
+// before its basic formatting is applied, so markup written in a task is
+// shown rather than run, and links other than safe ones point nowhere
+func taskTextHTML(text string) string {
+	formatted := processInlineFormattingBasic(html.EscapeString(text))
+	return taskHrefRegex.ReplaceAllStringFunc(formatted, func(m string) string {
+		if isSafeTaskLink(html.UnescapeString(taskHrefRegex.FindStringSubmatch(m)[1])) {
+			return m
+		}
+		return `href="#"`
+	})
+}
+
+// isSafeTaskLink reports whether a link in a task may be followed: http,
+// https and mailto URLs, paths on the wiki and anchors. Anything that does
+// not parse cleanly, such as a scheme split by a tab, is refused.
+func isSafeTaskLink(href string) bool {
+	if strings.HasPrefix(href, "#") || (strings.HasPrefix(href, "/") && !strings.HasPrefix(href, "//")) {
+		return !strings.ContainsAny(href, "\t\n\r")
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return true
+	}
+	return false
+}
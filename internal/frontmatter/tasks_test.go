@@ -0,0 +1,72 @@
+package frontmatter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindDocumentTasks(t *testing.T) {
+	checklist := "# Notes\n\n## Chores\n- [ ] Buy milk @alice\n  - [x] Check fridge\n\n```\n- [ ] not a task\n```\n"
+	tasks := FindDocumentTasks(checklist)
+	if len(tasks) != 2 {
+		t.Fatalf("expected 2 tasks, got %d", len(tasks))
+	}
+	if tasks[0].Column != "Chores" || tasks[0].Board != "" || tasks[0].Assignees[0] != "alice" {
+		t.Errorf("unexpected first task: %+v", tasks[0])
+	}
+	if !tasks[1].Checked || tasks[1].IndentLevel != 1 {
+		t.Errorf("unexpected subtask: %+v", tasks[1])
+	}
+
+	kanban := "---\nlayout: kanban\n---\n# Board\n\n#### Sprint\n\n##### Todo\n- [ ] Write docs\n##### Done\n- [x] Ship\n"
+	tasks = FindDocumentTasks(kanban)
+	if len(tasks) != 2 {
+		t.Fatalf("expected 2 kanban tasks, got %d", len(tasks))
+	}
+	if tasks[0].Board != "Sprint" || tasks[0].Column != "Todo" || tasks[1].Column != "Done" {
+		t.Errorf("unexpected kanban tasks: %+v", tasks)
+	}
+}
+
+func TestFindDocumentTasksEscapesHTML(t *testing.T) {
+	tasks := FindDocumentTasks("- [ ] fix <script>alert(1)</script> **now** <img src=x onerror=alert(2)> [go](javascript:alert(3))\n")
+	if len(tasks) != 1 {
+		t.Fatalf("expected 1 task, got %d", len(tasks))
+	}
+	got := tasks[0].HTMLText
+	for _, unwanted := range []string{"<script>", "<img", "javascript:"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("expected %q to be escaped in %q", unwanted, got)
+		}
+	}
+	for _, want := range []string{"&lt;script&gt;alert(1)&lt;/script&gt;", "<strong>now</strong>", `<a href="#">go</a>`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+}
+
+func TestTaskTextLinks(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"[docs](https://example.com/a?b=1&c=2)", `<a href="https://example.com/a?b=1&amp;c=2">docs</a>`},
+		{"[mail](mailto:team@example.com)", `<a href="mailto:team@example.com">mail</a>`},
+		{"[page](/guide/install#linux)", `<a href="/guide/install#linux">page</a>`},
+		{"[top](#top)", `<a href="#top">top</a>`},
+		{"[x](javascript:alert(1))", `<a href="#">x</a>`},
+		{"[x](java\tscript:alert(1))", `<a href="#">x</a>`},
+		{"[x](java\nscript:alert(1))", `<a href="#">x</a>`},
+		{"[x](&#106;avascript:alert(1))", `<a href="#">x</a>`},
+		{"[x](javascript&colon;alert(1))", `<a href="#">x</a>`},
+		{"[x](JaVaScRiPt:alert(1))", `<a href="#">x</a>`},
+		{"[x](data:text/html,hi)", `<a href="#">x</a>`},
+		{"[x](//evil.example.com)", `<a href="#">x</a>`},
+		{"[x](/\tjavascript:alert(1))", `<a href="#">x</a>`},
+	}
+	for _, tt := range tests {
+		if got := taskTextHTML(tt.text); !strings.Contains(got, tt.want) {
+			t.Errorf("taskTextHTML(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package goldext
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// BibEntry is a reference from a BibTeX file, such as
+// @book{knuth1984, author = {Donald E. Knuth}, ...}
+type BibEntry struct {
+	Type   string            // Entry type in lower case, e.g. "article"
+	Key    string            // Citation key
+	Fields map[string]string // Field values with lower case names, still in LaTeX
+}
+
+// Field returns a field of the entry as plain text
+func (e *BibEntry) Field(name string) string {
+	return latexToText(e.Fields[name])
+}
+
+// BibName is an author or editor of a BibTeX entry
+type BibName struct {
+	First string
+	Last  string
+}
+
+// Authors returns the authors of the entry, or its editors if it has none.
+// A trailing "and others" is returned as a name with Last "others".
+func (e *BibEntry) Authors() []BibName {
+	value := e.Fields["author"]
+	if strings.TrimSpace(value) == "" {
+		value = e.Fields["editor"]
+	}
+	var names []BibName
+	for _, part := range splitBibNames(value) {
+		names = append(names, parseBibName(part))
+	}
+	return names
+}
+
+// bibMonths are the month macros predefined by BibTeX
+var bibMonths = map[string]string{
+	"jan": "January", "feb": "February", "mar": "March", "apr": "April",
+	"may": "May", "jun": "June", "jul": "July", "aug": "August",
+	"sep": "September", "oct": "October", "nov": "November", "dec": "December",
+}
+
+// ParseBibTeX parses the entries of a BibTeX file. @string macros are
+// expanded, @comment and @preamble are skipped, and malformed entries are
+// reported as errors while parsing continues with the next entry.
+func ParseBibTeX(data string) ([]*BibEntry, []error) {
+	p := &bibParser{data: data, macros: map[string]string{}}
+	for k, v := range bibMonths {
+		p.macros[k] = v
+	}
+
+	var entries []*BibEntry
+	var errs []error
+	for {
+		at := strings.IndexByte(p.data[p.pos:], '@')
+		if at < 0 {
+			break
+		}
+		p.pos += at + 1
+		start := p.pos
+		entry, err := p.parseEntry()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", strings.Count(data[:start], "\n")+1, err))
+			continue
+		}
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, errs
+}
+
+// bibParser reads BibTeX entries from data
+type bibParser struct {
+	data   string
+	pos    int
+	macros map[string]string
+}
+
+func (p *bibParser) skipSpace() {
+	for p.pos < len(p.data) && unicode.IsSpace(rune(p.data[p.pos])) {
+		p.pos++
+	}
+}
+
+// ident reads a name such as an entry type, field name or macro
+func (p *bibParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if unicode.IsSpace(rune(c)) || strings.IndexByte("{}(),=#\"", c) >= 0 {
+			break
+		}
+		p.pos++
+	}
+	return p.data[start:p.pos]
+}
+
+// parseEntry parses an entry after its @. It returns nil for entries that
+// are not references.
+func (p *bibParser) parseEntry() (*BibEntry, error) {
+	entryType := strings.ToLower(p.ident())
+	p.skipSpace()
+	if p.pos >= len(p.data) || (p.data[p.pos] != '{' && p.data[p.pos] != '(') {
+		return nil, fmt.Errorf("expected { after @%s", entryType)
+	}
+	closing := byte('}')
+	if p.data[p.pos] == '(' {
+		closing = ')'
+	}
+	p.pos++
+
+	switch entryType {
+	case "comment", "preamble":
+		depth := 1
+		for p.pos < len(p.data) && depth > 0 {
+			switch p.data[p.pos] {
+			case '{', '(':
+				depth++
+			case '}', ')':
+				depth--
+			}
+			p.pos++
+		}
+		return nil, nil
+	case "string":
+		fields, err := p.parseFields(closing)
+		for name, value := range fields {
+			p.macros[name] = value
+		}
+		return nil, err
+	}
+
+	p.skipSpace()
+	keyEnd := strings.IndexAny(p.data[p.pos:], ",}\n")
+	if keyEnd < 0 {
+		return nil, fmt.Errorf("missing citation key in @%s", entryType)
+	}
+	key := strings.TrimSpace(p.data[p.pos : p.pos+keyEnd])
+	if key == "" || p.data[p.pos+keyEnd] != ',' {
+		return nil, fmt.Errorf("missing citation key in @%s", entryType)
+	}
+	p.pos += keyEnd + 1
+
+	fields, err := p.parseFields(closing)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return &BibEntry{Type: entryType, Key: key, Fields: fields}, nil
+}
+
+// parseFields parses name = value pairs up to the closing character
+func (p *bibParser) parseFields(closing byte) (map[string]string, error) {
+	fields := map[string]string{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return fields, fmt.Errorf("unexpected end of file")
+		}
+		if p.data[p.pos] == closing {
+			p.pos++
+			return fields, nil
+		}
+		if p.data[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+
+		name := strings.ToLower(p.ident())
+		p.skipSpace()
+		if name == "" || p.pos >= len(p.data) || p.data[p.pos] != '=' {
+			return fields, fmt.Errorf("expected field = value")
+		}
+		p.pos++
+		value, err := p.parseValue()
+		if err != nil {
+			return fields, fmt.Errorf("%s: %w", name, err)
+		}
+		fields[name] = value
+	}
+}
+
+// parseValue parses a field value: {braced}, "quoted", a number or a macro,
+// joined with #
+func (p *bibParser) parseValue() (string, error) {
+	var value strings.Builder
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return "", fmt.Errorf("missing value")
+		}
+		switch c := p.data[p.pos]; c {
+		case '{', '"':
+			part, err := p.delimited(c)
+			if err != nil {
+				return "", err
+			}
+			value.WriteString(part)
+		default:
+			word := p.ident()
+			if word == "" {
+				return "", fmt.Errorf("missing value")
+			}
+			if macro, ok := p.macros[strings.ToLower(word)]; ok {
+				word = macro
+			}
+			value.WriteString(word)
+		}
+
+		p.skipSpace()
+		if p.pos < len(p.data) && p.data[p.pos] == '#' {
+			p.pos++
+			continue
+		}
+		return strings.Join(strings.Fields(value.String()), " "), nil
+	}
+}
+
+// delimited reads a {braced} or "quoted" string with balanced braces and
+// returns its content
+func (p *bibParser) delimited(open byte) (string, error) {
+	p.pos++
+	start := p.pos
+	depth := 0
+	for ; p.pos < len(p.data); p.pos++ {
+		switch c := p.data[p.pos]; {
+		case c == '\\':
+			// Skip the escaped character, unless the input ends here
+			if p.pos+1 < len(p.data) {
+				p.pos++
+			}
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case (c == '}' && open == '{') || (c == '"' && open == '"' && depth == 0):
+			value := p.data[start:p.pos]
+			p.pos++
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("unterminated value")
+}
+
+// splitBibNames splits a name list on " and " outside braces
+func splitBibNames(value string) []string {
+	var names []string
+	depth, start := 0, 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		if depth == 0 && i > start && strings.HasPrefix(strings.ToLower(value[i:]), " and ") {
+			names = append(names, strings.TrimSpace(value[start:i]))
+			start = i + len(" and ")
+			i = start - 1
+		}
+	}
+	if last := strings.TrimSpace(value[start:]); last != "" {
+		names = append(names, last)
+	}
+	return names
+}
+
+// parseBibName splits a name written "Last, First" or "First Last". Words
+// in braces, such as {World Health Organization}, stay together.
+func parseBibName(name string) BibName {
+	if comma := topLevelIndex(name, ','); comma >= 0 {
+		return BibName{
+			Last:  latexToText(strings.TrimSpace(name[:comma])),
+			First: latexToText(strings.TrimSpace(name[comma+1:])),
+		}
+	}
+	if lastSpace := topLevelLastIndex(name, ' '); lastSpace >= 0 {
+		return BibName{
+			First: latexToText(strings.TrimSpace(name[:lastSpace])),
+			Last:  latexToText(strings.TrimSpace(name[lastSpace+1:])),
+		}
+	}
+	return BibName{Last: latexToText(name)}
+}
+
+// topLevelIndex returns the index of the first c outside braces, or -1
+func topLevelIndex(s string, c byte) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case c:
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// topLevelLastIndex returns the index of the last c outside braces, or -1
+func topLevelLastIndex(s string, c byte) int {
+	depth, last := 0, -1
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case c:
+			if depth == 0 {
+				last = i
+			}
+		}
+	}
+	return last
+}
+
+// latexAccents maps LaTeX accent commands to combining characters
+var latexAccents = map[string]rune{
+	"`": '\u0300', "'": '\u0301', "^": '\u0302', "~": '\u0303', "=": '\u0304',
+	"u": '\u0306', ".": '\u0307', "\"": '\u0308', "r": '\u030a', "H": '\u030b',
+	"v": '\u030c', "c": '\u0327', "k": '\u0328',
+}
+
+// latexSymbols maps LaTeX commands without arguments to text
+var latexSymbols = map[string]string{
+	"ss": "ß", "o": "ø", "O": "Ø", "ae": "æ", "AE": "Æ", "oe": "œ", "OE": "Œ",
+	"aa": "å", "AA": "Å", "l": "ł", "L": "Ł", "i": "ı", "j": "ȷ",
+	"&": "&", "%": "%", "$": "$", "#": "#", "_": "_", "{": "{", "}": "}",
+	"textendash": "–", "textemdash": "—", "LaTeX": "LaTeX", "TeX": "TeX",
+}
+
+// latexToText turns the LaTeX in a BibTeX value into plain text: accents
+// become accented letters, braces are dropped, and the arguments of
+// formatting commands such as \emph are kept
+func latexToText(s string) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '{' || c == '}' || c == '$':
+		case c == '~':
+			buf.WriteString(" ")
+		case c == '-' && strings.HasPrefix(s[i:], "---"):
+			buf.WriteString("—")
+			i += 2
+		case c == '-' && strings.HasPrefix(s[i:], "--"):
+			buf.WriteString("–")
+			i++
+		case c == '\\' && i+1 < len(s):
+			name := s[i+1 : i+2]
+			if unicode.IsLetter(rune(s[i+1])) {
+				end := i + 1
+				for end < len(s) && unicode.IsLetter(rune(s[end])) {
+					end++
+				}
+				name = s[i+1 : end]
+			}
+			i += len(name)
+
+			if mark, ok := latexAccents[name]; ok {
+				arg, n := latexArgument(s[i+1:])
+				i += n
+				buf.WriteString(norm.NFC.String(arg + string(mark)))
+				continue
+			}
+			if symbol, ok := latexSymbols[name]; ok {
+				buf.WriteString(symbol)
+			} else if name == "\\" || name == " " {
+				buf.WriteByte(' ')
+			}
+			// A space after a command name only ends the name
+			if unicode.IsLetter(rune(name[0])) && i+1 < len(s) && s[i+1] == ' ' {
+				i++
+			}
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// latexArgument returns the letter an accent applies to, written as {o}, o
+// or \i, and the number of bytes it used
+func latexArgument(s string) (string, int) {
+	n := 0
+	for n < len(s) && s[n] == ' ' {
+		n++
+	}
+	if n < len(s) && s[n] == '{' {
+		if end := strings.IndexByte(s[n:], '}'); end >= 0 {
+			return latexToText(s[n+1 : n+end]), n + end + 1
+		}
+	}
+	if strings.HasPrefix(s[n:], "\\i") {
+		return "i", n + 2
+	}
+	if strings.HasPrefix(s[n:], "\\j") {
+		return "j", n + 2
+	}
+	if n < len(s) {
+		_, size := utf8.DecodeRuneInString(s[n:])
+		return s[n : n+size], n + size
+	}
+	return "", n
+}
@@ -0,0 +1,466 @@
+package goldext
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"gopkg.in/yaml.v3"
+)
+
+// KindChart is the node kind of charts
+var KindChart = ast.NewNodeKind("Chart")
+
+// Chart is a ```chart block rendered to an inline SVG, or an error notice
+type Chart struct {
+	ast.BaseBlock
+	HTML string
+}
+
+// Kind implements ast.Node.Kind
+func (n *Chart) Kind() ast.NodeKind { return KindChart }
+
+// Dump implements ast.Node.Dump
+func (n *Chart) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// chartSpec describes a chart. It is read from the YAML in a ```chart
+// block, or built from CSV data with the options of the info string.
+type chartSpec struct {
+	Type   string        `yaml:"type"`   // bar, line or pie
+	Title  string        `yaml:"title"`  // Shown above the chart
+	File   string        `yaml:"file"`   // Attached CSV file to read the data from
+	Labels []string      `yaml:"labels"` // Category labels along the x axis
+	Series []chartSeries `yaml:"series"` // Values for each label
+	Data   yaml.Node     `yaml:"data"`   // A single series as label: value pairs
+}
+
+// chartSeries is a named list of values, one for each label
+type chartSeries struct {
+	Name   string    `yaml:"name"`
+	Values []float64 `yaml:"values"`
+}
+
+// chartKeys are the YAML keys that mark a ```chart block as YAML rather
+// than CSV
+var chartKeys = []string{"type", "title", "file", "labels", "series", "data"}
+
+// chartColors are the colors of the series, in order
+var chartColors = []string{"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"}
+
+// Chart dimensions in SVG units
+const (
+	chartWidth  = 640
+	chartHeight = 360
+)
+
+// chartTransformer replaces ```chart code blocks with Chart nodes
+type chartTransformer struct {
+	ctx *RenderContext
+}
+
+// Transform implements parser.ASTTransformer
+func (t *chartTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+
+	var blocks []*ast.FencedCodeBlock
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if code, ok := n.(*ast.FencedCodeBlock); ok && entering && string(code.Language(source)) == "chart" {
+			blocks = append(blocks, code)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, code := range blocks {
+		info := strings.TrimSpace(strings.TrimPrefix(string(code.Info.Segment.Value(source)), "chart"))
+
+		var content strings.Builder
+		for i := 0; i < code.Lines().Len(); i++ {
+			line := code.Lines().At(i)
+			content.Write(line.Value(source))
+		}
+
+		chart := &Chart{HTML: RenderChart(content.String(), info, t.ctx)}
+		code.Parent().ReplaceChild(code.Parent(), code, chart)
+	}
+}
+
+func (r *nodeRenderer) renderChart(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(node.(*Chart).HTML)
+	}
+	return ast.WalkContinue, nil
+}
+
+// RenderChart renders the content of a ```chart block as an inline SVG.
+// The content is YAML, or CSV with labels in the first column and a series
+// in each other column. options holds key=value settings from the info
+// string, such as type=line title="Sales" file=data.csv.
+func RenderChart(content, options string, ctx *RenderContext) string {
+	spec, err := parseChartSpec(content, options, ctx)
+	if err != nil {
+		return renderChartError(err.Error())
+	}
+
+	var buf strings.Builder
+	switch spec.Type {
+	case "", "bar":
+		writeAxisChart(&buf, spec, true)
+	case "line":
+		writeAxisChart(&buf, spec, false)
+	case "pie":
+		writePieChart(&buf, spec)
+	default:
+		return renderChartError("unknown chart type " + strconv.Quote(spec.Type) + ", use bar, line or pie")
+	}
+	return buf.String()
+}
+
+// parseChartSpec reads a chart from YAML or CSV content and its options
+func parseChartSpec(content, options string, ctx *RenderContext) (*chartSpec, error) {
+	spec := &chartSpec{}
+	_, params := ParseShortcodeArgs(options)
+	spec.Type = params["type"]
+	spec.Title = params["title"]
+	spec.File = params["file"]
+
+	if isChartYAML(content) {
+		if err := yaml.Unmarshal([]byte(content), spec); err != nil {
+			return nil, fmt.Errorf("invalid chart YAML: %v", err)
+		}
+		if err := spec.readDataMapping(); err != nil {
+			return nil, err
+		}
+	} else if strings.TrimSpace(content) != "" {
+		rows, err := readCSVRows([]byte(content), params["delimiter"])
+		if err != nil {
+			return nil, err
+		}
+		if err := spec.readRows(rows); err != nil {
+			return nil, err
+		}
+	}
+
+	if spec.File != "" && len(spec.Series) == 0 {
+		fsPath, err := tableFilePath(spec.File, ctx.DocPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", spec.File, err)
+		}
+		ctx.RecordFile(fsPath)
+		rows, err := readCSVTable(fsPath, params["delimiter"])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", spec.File, err)
+		}
+		if err := spec.readRows(rows); err != nil {
+			return nil, err
+		}
+	}
+
+	spec.Type = strings.ToLower(strings.TrimSpace(spec.Type))
+	if len(spec.Series) == 0 || len(spec.Labels) == 0 {
+		return nil, errors.New("the chart has no data")
+	}
+	for i := range spec.Series {
+		if spec.Series[i].Name == "" {
+			spec.Series[i].Name = "Series " + strconv.Itoa(i+1)
+		}
+		// Missing values count as zero
+		for len(spec.Series[i].Values) < len(spec.Labels) {
+			spec.Series[i].Values = append(spec.Series[i].Values, 0)
+		}
+		spec.Series[i].Values = spec.Series[i].Values[:len(spec.Labels)]
+	}
+	return spec, nil
+}
+
+// isChartYAML reports whether content is a YAML mapping with chart keys
+func isChartYAML(content string) bool {
+	var m map[string]interface{}
+	if err := yaml.Unmarshal([]byte(content), &m); err != nil {
+		return false
+	}
+	for _, key := range chartKeys {
+		if _, ok := m[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readDataMapping turns a data: mapping of label: value pairs into the
+// labels and a single series, keeping their order
+func (spec *chartSpec) readDataMapping() error {
+	if spec.Data.Kind == 0 {
+		return nil
+	}
+	if spec.Data.Kind != yaml.MappingNode {
+		return errors.New("data must be a list of label: value pairs")
+	}
+
+	series := chartSeries{Name: spec.Title}
+	spec.Labels = nil
+	for i := 0; i+1 < len(spec.Data.Content); i += 2 {
+		value, err := parseChartNumber(spec.Data.Content[i+1].Value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %v", spec.Data.Content[i].Value, err)
+		}
+		spec.Labels = append(spec.Labels, spec.Data.Content[i].Value)
+		series.Values = append(series.Values, value)
+	}
+	spec.Series = []chartSeries{series}
+	return nil
+}
+
+// readRows reads CSV rows: a header row, then a label and the values of
+// each series on every row
+func (spec *chartSpec) readRows(rows [][]string) error {
+	if len(rows) < 2 || len(rows[0]) < 2 {
+		return errors.New("CSV data needs a header row and at least two columns")
+	}
+
+	header := rows[0]
+	spec.Series = make([]chartSeries, len(header)-1)
+	for i := range spec.Series {
+		spec.Series[i].Name = strings.TrimSpace(header[i+1])
+	}
+	spec.Labels = nil
+	for _, row := range rows[1:] {
+		if len(row) == 0 || (len(row) == 1 && strings.TrimSpace(row[0]) == "") {
+			continue
+		}
+		spec.Labels = append(spec.Labels, strings.TrimSpace(row[0]))
+		for i := range spec.Series {
+			value := 0.0
+			if cell := strings.TrimSpace(tableCell(row, i+1)); cell != "" {
+				v, err := parseChartNumber(cell)
+				if err != nil {
+					return fmt.Errorf("invalid value %q for %s", cell, row[0])
+				}
+				value = v
+			}
+			spec.Series[i].Values = append(spec.Series[i].Values, value)
+		}
+	}
+	return nil
+}
+
+// parseChartNumber parses a value, allowing thousands separators,
+// currency and percent signs
+func parseChartNumber(s string) (float64, error) {
+	s = strings.Trim(strings.TrimSpace(s), "$€£%")
+	s = strings.ReplaceAll(s, ",", "")
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, errors.New("not a number")
+	}
+	return v, nil
+}
+
+// writeAxisChart writes a bar or line chart with a value axis
+func writeAxisChart(w *strings.Builder, spec *chartSpec, bars bool) {
+	top := 20.0
+	if spec.Title != "" {
+		top += 24
+	}
+	if len(spec.Series) > 1 {
+		top += 22
+	}
+	left, right, bottom := 56.0, float64(chartWidth-16), float64(chartHeight-40)
+
+	min, max := 0.0, 0.0
+	for _, s := range spec.Series {
+		for _, v := range s.Values {
+			min = math.Min(min, v)
+			max = math.Max(max, v)
+		}
+	}
+	lo, hi, step := niceScale(min, max)
+	y := func(v float64) float64 { return bottom - (v-lo)/(hi-lo)*(bottom-top) }
+
+	chartType := "line"
+	if bars {
+		chartType = "bar"
+	}
+	openChartSVG(w, spec, chartType)
+	writeChartLegend(w, spec, top-22)
+
+	// Value axis with grid lines
+	decimals := stepDecimals(step)
+	for v := lo; v <= hi+step/2; v += step {
+		fmt.Fprintf(w, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="currentColor" stroke-opacity="0.15"/>`+"\n", left, y(v), right, y(v))
+		fmt.Fprintf(w, `<text x="%.1f" y="%.1f" text-anchor="end" font-size="11" fill="currentColor">%s</text>`+"\n", left-6, y(v)+4, strconv.FormatFloat(v, 'f', decimals, 64))
+	}
+
+	// Category labels, thinned out when there are too many to fit
+	n := len(spec.Labels)
+	band := (right - left) / float64(n)
+	every := int(math.Ceil(float64(n) / 12))
+	for i, label := range spec.Labels {
+		if i%every != 0 {
+			continue
+		}
+		fmt.Fprintf(w, `<text x="%.1f" y="%.1f" text-anchor="middle" font-size="11" fill="currentColor">%s</text>`+"\n", left+band*(float64(i)+0.5), bottom+18, html.EscapeString(label))
+	}
+
+	if bars {
+		width := band * 0.8 / float64(len(spec.Series))
+		for si, s := range spec.Series {
+			for i, v := range s.Values {
+				x := left + band*float64(i) + band*0.1 + width*float64(si)
+				y0, y1 := y(0), y(v)
+				fmt.Fprintf(w, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s</title></rect>`+"\n",
+					x, math.Min(y0, y1), width, math.Abs(y1-y0), chartColor(si), chartTooltip(s.Name, spec.Labels[i], v))
+			}
+		}
+	} else {
+		for si, s := range spec.Series {
+			points := make([]string, len(s.Values))
+			for i, v := range s.Values {
+				points[i] = fmt.Sprintf("%.1f,%.1f", left+band*(float64(i)+0.5), y(v))
+			}
+			fmt.Fprintf(w, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n", strings.Join(points, " "), chartColor(si))
+			for i, v := range s.Values {
+				fmt.Fprintf(w, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s</title></circle>`+"\n",
+					left+band*(float64(i)+0.5), y(v), chartColor(si), chartTooltip(s.Name, spec.Labels[i], v))
+			}
+		}
+	}
+
+	// Zero line
+	fmt.Fprintf(w, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="currentColor" stroke-opacity="0.6"/>`+"\n", left, y(0), right, y(0))
+	w.WriteString("</svg>\n</figure>\n")
+}
+
+// writePieChart writes a pie chart of the first series. Values that are not
+// positive are left out.
+func writePieChart(w *strings.Builder, spec *chartSpec) {
+	top := 20.0
+	if spec.Title != "" {
+		top += 24
+	}
+	openChartSVG(w, spec, "pie")
+
+	values := spec.Series[0].Values
+	total := 0.0
+	for _, v := range values {
+		if v > 0 {
+			total += v
+		}
+	}
+
+	r := (float64(chartHeight) - top - 20) / 2
+	cx, cy := 40+r, top+r
+	angle := -math.Pi / 2
+	legendY := top + 14
+	for i, v := range values {
+		if v <= 0 {
+			continue
+		}
+		share := v / total
+		tooltip := chartTooltip(spec.Labels[i], "", v)
+		if share >= 0.9999 {
+			fmt.Fprintf(w, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s"><title>%s</title></circle>`+"\n", cx, cy, r, chartColor(i), tooltip)
+		} else {
+			end := angle + share*2*math.Pi
+			large := 0
+			if share > 0.5 {
+				large = 1
+			}
+			fmt.Fprintf(w, `<path d="M%.1f,%.1f L%.1f,%.1f A%.1f,%.1f 0 %d 1 %.1f,%.1f Z" fill="%s" stroke="#fff" stroke-width="1"><title>%s</title></path>`+"\n",
+				cx, cy, cx+r*math.Cos(angle), cy+r*math.Sin(angle), r, r, large, cx+r*math.Cos(end), cy+r*math.Sin(end), chartColor(i), tooltip)
+			angle = end
+		}
+
+		fmt.Fprintf(w, `<rect x="%.1f" y="%.1f" width="12" height="12" fill="%s"/>`+"\n", cx+r+40, legendY-10, chartColor(i))
+		fmt.Fprintf(w, `<text x="%.1f" y="%.1f" font-size="12" fill="currentColor">%s (%s%%)</text>`+"\n",
+			cx+r+58, legendY, html.EscapeString(spec.Labels[i]), strconv.FormatFloat(share*100, 'f', 1, 64))
+		legendY += 20
+	}
+	w.WriteString("</svg>\n</figure>\n")
+}
+
+// openChartSVG writes the start of a chart figure and its title
+func openChartSVG(w *strings.Builder, spec *chartSpec, chartType string) {
+	label := spec.Title
+	if label == "" {
+		label = strings.ToUpper(chartType[:1]) + chartType[1:] + " chart"
+	}
+	fmt.Fprintf(w, `<figure class="wiki-chart wiki-chart-%s">`+"\n", chartType)
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="100%%" role="img" aria-label="%s">`+"\n",
+		chartWidth, chartHeight, html.EscapeString(label))
+	fmt.Fprintf(w, "<title>%s</title>\n", html.EscapeString(label))
+	if spec.Title != "" {
+		fmt.Fprintf(w, `<text x="%d" y="24" text-anchor="middle" font-size="15" font-weight="600" fill="currentColor">%s</text>`+"\n",
+			chartWidth/2, html.EscapeString(spec.Title))
+	}
+}
+
+// writeChartLegend writes the series names in a row at y, if there is more
+// than one series
+func writeChartLegend(w *strings.Builder, spec *chartSpec, y float64) {
+	if len(spec.Series) < 2 {
+		return
+	}
+	x := 56.0
+	for i, s := range spec.Series {
+		fmt.Fprintf(w, `<rect x="%.1f" y="%.1f" width="12" height="12" fill="%s"/>`+"\n", x, y, chartColor(i))
+		fmt.Fprintf(w, `<text x="%.1f" y="%.1f" font-size="12" fill="currentColor">%s</text>`+"\n", x+16, y+10, html.EscapeString(s.Name))
+		x += 16 + 7*float64(len([]rune(s.Name))) + 20
+	}
+}
+
+// niceScale returns axis bounds around min and max that fall on round
+// steps, and the step
+func niceScale(min, max float64) (float64, float64, float64) {
+	if max == min {
+		max = min + 1
+	}
+	rough := (max - min) / 5
+	mag := math.Pow(10, math.Floor(math.Log10(rough)))
+	step := 10 * mag
+	for _, m := range []float64{1, 2, 5} {
+		if rough <= m*mag {
+			step = m * mag
+			break
+		}
+	}
+	return math.Floor(min/step) * step, math.Ceil(max/step) * step, step
+}
+
+// stepDecimals returns the number of decimals needed to show multiples of
+// step
+func stepDecimals(step float64) int {
+	if step >= 1 {
+		return 0
+	}
+	return int(math.Ceil(-math.Log10(step)))
+}
+
+// chartColor returns the color of the i-th series or slice
+func chartColor(i int) string {
+	return chartColors[i%len(chartColors)]
+}
+
+// chartTooltip returns the escaped hover text of a value
+func chartTooltip(name, label string, v float64) string {
+	parts := []string{}
+	for _, s := range []string{name, label} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return html.EscapeString(strings.Join(parts, ", ") + ": " + strconv.FormatFloat(v, 'f', -1, 64))
+}
+
+// renderChartError renders a notice in place of a chart that can't be drawn
+func renderChartError(message string) string {
+	return "<div class=\"wiki-chart-error\">Cannot draw chart: " + html.EscapeString(message) + "</div>\n"
+}
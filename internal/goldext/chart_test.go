@@ -0,0 +1,69 @@
+package goldext
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderChart(t *testing.T) {
+	t.Chdir(t.TempDir())
+	dir := filepath.Join("data", "documents", "reports")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "visits.csv"), []byte("Day,Visits\nMon,120\nTue,80\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &RenderContext{DocPath: "reports", Deps: &RenderDeps{}}
+
+	tests := []struct {
+		name     string
+		content  string
+		options  string
+		contains []string
+	}{
+		{
+			name:     "YAML series",
+			content:  "type: bar\ntitle: A <b>\nlabels: [Jan, Feb]\nseries:\n  - name: Sales\n    values: [10, 20]\n",
+			contains: []string{`<figure class="wiki-chart wiki-chart-bar">`, `aria-label="A &lt;b&gt;"`, `<title>Sales, Feb: 20</title>`},
+		},
+		{
+			name:     "YAML data in order",
+			content:  "type: pie\ndata:\n  Zed: 1\n  Amy: 3\n",
+			contains: []string{`Zed (25.0%)`, `Amy (75.0%)`},
+		},
+		{
+			name:     "CSV with options",
+			content:  "Month,Sales,Costs\nJan,10,4\nFeb,12,\n",
+			options:  `type=line title="Q1"`,
+			contains: []string{`wiki-chart-line`, `>Costs</text>`, `<title>Costs, Feb: 0</title>`},
+		},
+		{
+			name:     "Attached CSV",
+			options:  "file=visits.csv",
+			contains: []string{`<title>Visits, Mon: 120</title>`},
+		},
+		{
+			name:     "Bad value",
+			content:  "Month,Sales\nJan,lots\n",
+			contains: []string{`<div class="wiki-chart-error">Cannot draw chart: invalid value &#34;lots&#34; for Jan</div>`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RenderChart(tt.content, tt.options, ctx)
+			for _, want := range tt.contains {
+				if !strings.Contains(result, want) {
+					t.Errorf("Expected %q in %q", want, result)
+				}
+			}
+		})
+	}
+
+	if _, ok := ctx.Deps.Files[filepath.Join(dir, "visits.csv")]; !ok {
+		t.Errorf("expected the attached CSV to be recorded as a dependency")
+	}
+}
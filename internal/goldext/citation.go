@@ -0,0 +1,588 @@
+package goldext
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Citation styles
+const (
+	CitationNumeric    = "numeric"     // [1], with references numbered in order of citation
+	CitationAuthorYear = "author-year" // (Knuth, 1984), with references sorted by author
+)
+
+// citationDefaults are the wiki-wide bibliography and citation style, set
+// with SetCitationDefaults
+var (
+	citationMu           sync.RWMutex
+	defaultBibliography  string
+	defaultCitationStyle = CitationNumeric
+)
+
+// SetCitationDefaults configures the bibliography used by every page, an
+// attachment path such as /research/library.bib, and the default citation
+// style. References attached to a page take precedence over it.
+func SetCitationDefaults(bibliography, style string) {
+	citationMu.Lock()
+	defer citationMu.Unlock()
+	defaultBibliography = bibliography
+	defaultCitationStyle = citationStyle(style)
+}
+
+// citationStyle returns the citation style named by style, numeric unless
+// it names the author-year style
+func citationStyle(style string) string {
+	switch strings.ToLower(strings.TrimSpace(style)) {
+	case "author-year", "authoryear", "author-date":
+		return CitationAuthorYear
+	}
+	return CitationNumeric
+}
+
+// parsedBibliography is a parsed .bib file and the modification time it was
+// parsed at
+type parsedBibliography struct {
+	modTime int64
+	entries []*BibEntry
+}
+
+// bibliographyCache keeps parsed .bib files by file system path, so a file
+// is only parsed again after it changes
+var (
+	bibliographyCacheMu sync.Mutex
+	bibliographyCache   = map[string]parsedBibliography{}
+)
+
+// loadBibliographyFile returns the entries of the .bib file at fsPath
+func loadBibliographyFile(fsPath string) []*BibEntry {
+	modTime := FileModTime(fsPath)
+
+	bibliographyCacheMu.Lock()
+	defer bibliographyCacheMu.Unlock()
+	if cached, ok := bibliographyCache[fsPath]; ok && cached.modTime == modTime {
+		return cached.entries
+	}
+
+	data, err := os.ReadFile(fsPath)
+	if err != nil {
+		return nil
+	}
+	entries, errs := ParseBibTeX(string(data))
+	for _, err := range errs {
+		log.Printf("goldext: bibliography %s: %v", fsPath, err)
+	}
+	bibliographyCache[fsPath] = parsedBibliography{modTime: modTime, entries: entries}
+	return entries
+}
+
+// bibliographyFilePath resolves a .bib attachment. Relative paths are
+// attachments of the page at docPath; absolute ones, such as
+// /research/library.bib, are attachments of another page.
+func bibliographyFilePath(file, docPath string) (fsPath, page string, ok bool) {
+	clean := path.Clean(strings.ReplaceAll(file, "\\", "/"))
+	if clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+		return "", "", false
+	}
+	if !path.IsAbs(clean) {
+		return getAttachmentPath(clean, docPath), docPath, true
+	}
+	dir, name := path.Split(clean)
+	dir = path.Clean(dir)
+	return getAttachmentPath(name, dir), dir, true
+}
+
+// loadBibliography returns the references available to the page: those in
+// the .bib file named in its frontmatter, or else in every .bib file
+// attached to it, followed by the wiki-wide bibliography
+func (ctx *RenderContext) loadBibliography() map[string]*BibEntry {
+	var files []string
+	if ctx.Bibliography != "" {
+		fsPath, page, ok := bibliographyFilePath(ctx.Bibliography, ctx.DocPath)
+		if ok && (page == ctx.DocPath || ctx.CheckAccess(page)) {
+			files = append(files, fsPath)
+		}
+	} else {
+		// The directory changes when a .bib file is attached or removed
+		dir := filepath.Clean(getAttachmentPath("", ctx.DocPath))
+		ctx.RecordFile(dir)
+		attached, _ := filepath.Glob(filepath.Join(dir, "*.bib"))
+		files = append(files, attached...)
+	}
+
+	citationMu.RLock()
+	wikiBibliography := defaultBibliography
+	citationMu.RUnlock()
+	if wikiBibliography != "" {
+		if fsPath, _, ok := bibliographyFilePath(wikiBibliography, "/"); ok {
+			files = append(files, fsPath)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+	entries := map[string]*BibEntry{}
+	for _, fsPath := range files {
+		ctx.RecordFile(fsPath)
+		for _, entry := range loadBibliographyFile(fsPath) {
+			if _, ok := entries[entry.Key]; !ok {
+				entries[entry.Key] = entry
+			}
+		}
+	}
+	return entries
+}
+
+// KindCitation is the node kind of citations
+var KindCitation = ast.NewNodeKind("Citation")
+
+// CitationRef is one reference cited by a citation, with an optional
+// locator such as "p. 33"
+type CitationRef struct {
+	Key     string
+	Locator string
+}
+
+// Citation is a citation such as [@knuth1984] or [@knuth1984, p. 33;
+// @lamport1994]. Its HTML is filled in once the bibliography is known.
+type Citation struct {
+	ast.BaseInline
+	Refs    []CitationRef
+	Literal string // The citation as written
+	HTML    string
+}
+
+// Kind implements ast.Node.Kind
+func (n *Citation) Kind() ast.NodeKind { return KindCitation }
+
+// Dump implements ast.Node.Dump
+func (n *Citation) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Literal": n.Literal}, nil)
+}
+
+// citationRegex matches a citation, [@key] with further references
+// separated by semicolons and optional locators after a comma
+var citationRegex = regexp.MustCompile(`^\[(@[\w:.#$%&+?<>~/-]+(?:,[^;\]]*)?(?:;\s*@[\w:.#$%&+?<>~/-]+(?:,[^;\]]*)?)*)\]`)
+
+// citationParser parses citations, and cross-references to headings such as
+// [@sec:install]. It runs before the link parser, and leaves [@key](url)
+// and [@key][ref] to it.
+type citationParser struct{}
+
+func (p *citationParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+func (p *citationParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	matches := citationRegex.FindSubmatch(line)
+	if matches == nil {
+		return nil
+	}
+	if rest := line[len(matches[0]):]; len(rest) > 0 && (rest[0] == '(' || rest[0] == '[') {
+		return nil
+	}
+
+	if label, ok := strings.CutPrefix(string(matches[1]), "@"+sectionRefPrefix); ok && !strings.ContainsAny(label, ",;") {
+		block.Advance(len(matches[0]))
+		return &SectionRef{Label: label}
+	}
+
+	node := &Citation{Literal: string(matches[0])}
+	for _, item := range strings.Split(string(matches[1]), ";") {
+		key, locator, _ := strings.Cut(strings.TrimSpace(item), ",")
+		node.Refs = append(node.Refs, CitationRef{
+			Key:     strings.TrimPrefix(key, "@"),
+			Locator: strings.TrimSpace(locator),
+		})
+	}
+	block.Advance(len(matches[0]))
+	return node
+}
+
+func (r *nodeRenderer) renderCitation(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(node.(*Citation).HTML)
+	}
+	return ast.WalkContinue, nil
+}
+
+// renderBibliographyShortcode renders :::bibliography:::, which places the
+// list of references. The list is filled in by citationTransformer.
+func renderBibliographyShortcode(args string, _ *RenderContext) (string, bool) {
+	return "", strings.TrimSpace(args) == ""
+}
+
+// citationTransformer resolves citations against the page's bibliography
+// and adds the list of cited references, at :::bibliography::: or at the
+// end of the page
+type citationTransformer struct {
+	ctx *RenderContext
+}
+
+// Transform implements parser.ASTTransformer
+func (t *citationTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var citations []*Citation
+	var placeholder *Shortcode
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *Citation:
+			citations = append(citations, node)
+		case *Shortcode:
+			if node.Name == "bibliography" && placeholder == nil {
+				placeholder = node
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	if len(citations) == 0 && placeholder == nil {
+		return
+	}
+
+	entries := t.ctx.loadBibliography()
+	if entries == nil {
+		for _, c := range citations {
+			c.HTML = html.EscapeString(c.Literal)
+		}
+		if placeholder != nil {
+			placeholder.HTML = "<div class=\"wiki-bibliography-error\">No .bib file is attached to this page</div>\n"
+		}
+		return
+	}
+
+	// References are listed in order of first citation
+	var cited []*BibEntry
+	seen := map[string]bool{}
+	for _, c := range citations {
+		for _, ref := range c.Refs {
+			if entry, ok := entries[ref.Key]; ok && !seen[ref.Key] {
+				seen[ref.Key] = true
+				cited = append(cited, entry)
+			}
+		}
+	}
+
+	style := t.ctx.CitationStyle
+	if style == "" {
+		citationMu.RLock()
+		style = defaultCitationStyle
+		citationMu.RUnlock()
+	}
+	style = citationStyle(style)
+	labels := newCitationLabels(cited, style)
+
+	for _, c := range citations {
+		c.HTML = labels.citation(c.Refs)
+	}
+
+	if len(cited) == 0 && placeholder == nil {
+		return
+	}
+	list := labels.bibliography(placeholder == nil)
+	if placeholder != nil {
+		placeholder.HTML = list
+	} else {
+		doc.AppendChild(doc, &Shortcode{Name: "bibliography", HTML: list})
+	}
+}
+
+// citationLabels formats citations and the list of cited references
+type citationLabels struct {
+	style   string
+	entries []*BibEntry       // Cited references in the order they are listed
+	numbers map[string]int    // Numbers of references in the numeric style
+	years   map[string]string // Years of references, with a letter for same author, same year
+}
+
+// newCitationLabels numbers the cited references, or sorts them by author
+// and tells apart works by the same authors in the same year
+func newCitationLabels(cited []*BibEntry, style string) *citationLabels {
+	l := &citationLabels{style: style, numbers: map[string]int{}, years: map[string]string{}}
+	l.entries = append(l.entries, cited...)
+	for i, entry := range l.entries {
+		l.numbers[entry.Key] = i + 1
+		l.years[entry.Key] = bibYear(entry)
+	}
+	if style != CitationAuthorYear {
+		return l
+	}
+
+	sort.SliceStable(l.entries, func(i, j int) bool {
+		a, b := l.entries[i], l.entries[j]
+		if sa, sb := bibSortName(a), bibSortName(b); sa != sb {
+			return sa < sb
+		}
+		if ya, yb := bibYear(a), bibYear(b); ya != yb {
+			return ya < yb
+		}
+		return strings.ToLower(a.Field("title")) < strings.ToLower(b.Field("title"))
+	})
+	for i := 0; i < len(l.entries); {
+		j := i + 1
+		for j < len(l.entries) && bibAuthorLabel(l.entries[j]) == bibAuthorLabel(l.entries[i]) && bibYear(l.entries[j]) == bibYear(l.entries[i]) {
+			j++
+		}
+		if j-i > 1 {
+			for k := i; k < j; k++ {
+				l.years[l.entries[k].Key] += string(rune('a' + k - i))
+			}
+		}
+		i = j
+	}
+	return l
+}
+
+// citation returns the HTML of a citation of refs
+func (l *citationLabels) citation(refs []CitationRef) string {
+	var items []string
+	hasLocator := false
+	for _, ref := range refs {
+		if _, ok := l.numbers[ref.Key]; !ok {
+			items = append(items, fmt.Sprintf("<span class=\"citation-missing\" title=\"Not in the bibliography\">@%s</span>", html.EscapeString(ref.Key)))
+			continue
+		}
+
+		label := fmt.Sprint(l.numbers[ref.Key])
+		if l.style == CitationAuthorYear {
+			entry := l.entryByKey(ref.Key)
+			label = html.EscapeString(bibAuthorLabel(entry) + ", " + l.years[ref.Key])
+		}
+		item := fmt.Sprintf("<a href=\"#ref-%s\">%s</a>", html.EscapeString(ref.Key), label)
+		if ref.Locator != "" {
+			item += ", " + html.EscapeString(ref.Locator)
+			hasLocator = true
+		}
+		items = append(items, item)
+	}
+
+	separator := ", "
+	if hasLocator || l.style == CitationAuthorYear {
+		separator = "; "
+	}
+	open, closing := "[", "]"
+	if l.style == CitationAuthorYear {
+		open, closing = "(", ")"
+	}
+	return "<span class=\"citation\">" + open + strings.Join(items, separator) + closing + "</span>"
+}
+
+// entryByKey returns the cited reference with key
+func (l *citationLabels) entryByKey(key string) *BibEntry {
+	for _, entry := range l.entries {
+		if entry.Key == key {
+			return entry
+		}
+	}
+	return nil
+}
+
+// bibliography returns the HTML of the list of cited references, with a
+// heading when it is added at the end of the page
+func (l *citationLabels) bibliography(withHeading bool) string {
+	var buf strings.Builder
+	buf.WriteString("<section class=\"bibliography\">\n")
+	if withHeading {
+		buf.WriteString("<h2 id=\"references\">References</h2>\n")
+	}
+	list := "ol"
+	if l.style == CitationAuthorYear {
+		list = "ul"
+	}
+	fmt.Fprintf(&buf, "<%s class=\"references\">\n", list)
+	for _, entry := range l.entries {
+		fmt.Fprintf(&buf, "<li id=\"ref-%s\">%s</li>\n", html.EscapeString(entry.Key), formatReference(entry, l.years[entry.Key]))
+	}
+	fmt.Fprintf(&buf, "</%s>\n</section>\n", list)
+	return buf.String()
+}
+
+// bibYear returns the year of a reference, or "n.d." if it has none
+func bibYear(entry *BibEntry) string {
+	if year := entry.Field("year"); year != "" {
+		return year
+	}
+	if date := entry.Field("date"); len(date) >= 4 {
+		return date[:4]
+	}
+	return "n.d."
+}
+
+// bibAuthorLabel returns the authors as they appear in an author-year
+// citation: Knuth, Knuth & Lamport or Knuth et al.
+func bibAuthorLabel(entry *BibEntry) string {
+	names := entry.Authors()
+	switch {
+	case len(names) == 0:
+		return entry.Field("title")
+	case len(names) == 1:
+		return names[0].Last
+	case len(names) == 2 && names[1].Last != "others":
+		return names[0].Last + " & " + names[1].Last
+	}
+	return names[0].Last + " et al."
+}
+
+// bibSortName returns the key references are sorted by in the author-year
+// style
+func bibSortName(entry *BibEntry) string {
+	var parts []string
+	for _, name := range entry.Authors() {
+		parts = append(parts, name.Last, name.First)
+	}
+	if len(parts) == 0 {
+		parts = append(parts, entry.Field("title"))
+	}
+	return strings.ToLower(strings.Join(parts, " "))
+}
+
+// formatReference returns the HTML of a reference in the list of
+// references, after APA: authors, year, title, where it was published and
+// its DOI or URL
+func formatReference(entry *BibEntry, year string) string {
+	var buf strings.Builder
+	title := html.EscapeString(entry.Field("title"))
+
+	names := entry.Authors()
+	if len(names) > 0 {
+		buf.WriteString(html.EscapeString(formatBibNames(names)))
+		if strings.TrimSpace(entry.Fields["author"]) == "" {
+			if len(names) == 1 {
+				buf.WriteString(" (Ed.)")
+			} else {
+				buf.WriteString(" (Eds.)")
+			}
+		}
+		buf.WriteString(" ")
+	}
+	fmt.Fprintf(&buf, "(%s). ", html.EscapeString(year))
+
+	volume := html.EscapeString(entry.Field("volume"))
+	if number := entry.Field("number"); number != "" {
+		volume += "(" + html.EscapeString(number) + ")"
+	}
+	pages := html.EscapeString(strings.ReplaceAll(entry.Field("pages"), "-", "–"))
+	publisher := html.EscapeString(firstNonEmpty(entry.Field("publisher"), entry.Field("institution"),
+		entry.Field("school"), entry.Field("organization"), entry.Field("howpublished")))
+
+	switch entry.Type {
+	case "article":
+		buf.WriteString(sentence(title))
+		parts := []string{"<em>" + html.EscapeString(entry.Field("journal")) + "</em>"}
+		if volume != "" {
+			parts = append(parts, volume)
+		}
+		if pages != "" {
+			parts = append(parts, pages)
+		}
+		buf.WriteString(sentence(strings.Join(parts, ", ")))
+	case "inproceedings", "incollection", "conference", "inbook":
+		buf.WriteString(sentence(title))
+		container := "In <em>" + html.EscapeString(entry.Field("booktitle")) + "</em>"
+		if pages != "" {
+			container += " (pp. " + pages + ")"
+		}
+		buf.WriteString(sentence(container))
+		if publisher != "" {
+			buf.WriteString(sentence(publisher))
+		}
+	default:
+		buf.WriteString(sentence("<em>" + title + "</em>"))
+		if publisher != "" {
+			buf.WriteString(sentence(publisher))
+		}
+	}
+
+	if doi := entry.Field("doi"); doi != "" {
+		link := "https://doi.org/" + strings.TrimPrefix(strings.TrimPrefix(doi, "https://doi.org/"), "doi:")
+		fmt.Fprintf(&buf, "<a href=\"%s\">%s</a>", html.EscapeString(link), html.EscapeString(link))
+	} else if link := entry.Field("url"); strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://") {
+		fmt.Fprintf(&buf, "<a href=\"%s\">%s</a>", html.EscapeString(link), html.EscapeString(link))
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// formatBibNames returns names in the form Knuth, D. E., & Lamport, L.
+func formatBibNames(names []BibName) string {
+	var formatted []string
+	etAl := false
+	for _, name := range names {
+		if name.Last == "others" {
+			etAl = true
+			continue
+		}
+		if initials := bibInitials(name.First); initials != "" {
+			formatted = append(formatted, name.Last+", "+initials)
+		} else {
+			formatted = append(formatted, name.Last)
+		}
+	}
+
+	switch {
+	case etAl:
+		return strings.Join(formatted, ", ") + ", et al."
+	case len(formatted) == 1:
+		return formatted[0]
+	case len(formatted) == 2:
+		return formatted[0] + ", & " + formatted[1]
+	}
+	return strings.Join(formatted[:len(formatted)-1], ", ") + ", & " + formatted[len(formatted)-1]
+}
+
+// bibInitials returns the initials of first names: Donald Ervin becomes
+// D. E. and Jean-Paul becomes J.-P.
+func bibInitials(first string) string {
+	var initials []string
+	for _, word := range strings.Fields(first) {
+		var parts []string
+		for _, part := range strings.Split(word, "-") {
+			if r, _ := utf8.DecodeRuneInString(part); unicode.IsLetter(r) {
+				parts = append(parts, string(r)+".")
+			}
+		}
+		if len(parts) > 0 {
+			initials = append(initials, strings.Join(parts, "-"))
+		}
+	}
+	return strings.Join(initials, " ")
+}
+
+// sentence ends s with a period and a space, unless it already ends a
+// sentence
+func sentence(s string) string {
+	if s == "" || s == "<em></em>" {
+		return ""
+	}
+	plain := strings.TrimSuffix(s, "</em>")
+	if strings.HasSuffix(plain, ".") || strings.HasSuffix(plain, "?") || strings.HasSuffix(plain, "!") {
+		return s + " "
+	}
+	return s + ". "
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package goldext
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+)
+
+const testBibliography = `@comment{Written by hand}
+@string{tcj = "The Computer Journal"}
+
+@article{knuth1984,
+  author  = {Knuth, Donald E.},
+  title   = {Literate Programming},
+  journal = tcj,
+  year    = 1984,
+  volume  = {27},
+  number  = {2},
+  pages   = {97--111},
+  doi     = {10.1093/comjnl/27.2.97}
+}
+
+@book{lamport1994a,
+  author    = "Leslie Lamport",
+  title     = "{\LaTeX}: A Document Preparation System",
+  publisher = {Addison-Wesley},
+  year      = {1994},
+}
+
+@inproceedings{lamport1994b,
+  author    = {Lamport, Leslie},
+  title     = {The Temporal Logic of Actions},
+  booktitle = {Proceedings},
+  year      = {1994},
+}
+
+@misc{group,
+  author = {{World Health Organization} and G{\"o}del, Kurt and Erd\H{o}s, Paul},
+  title  = {Report},
+  year   = {2020},
+}
+`
+
+func TestParseBibTeX(t *testing.T) {
+	entries, errs := ParseBibTeX(testBibliography + "@article{broken, title = {unterminated}\n")
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(entries))
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken") {
+		t.Errorf("expected an error for the broken entry, got %v", errs)
+	}
+
+	// A value cut off after a backslash is an error, not a panic
+	if entries, errs := ParseBibTeX(`@misc{k, title={C:\`); len(entries) != 0 || len(errs) != 1 {
+		t.Errorf("expected an error for the truncated entry, got %v and %v", entries, errs)
+	}
+
+	knuth := entries[0]
+	if knuth.Type != "article" || knuth.Key != "knuth1984" || knuth.Field("journal") != "The Computer Journal" || knuth.Field("pages") != "97–111" {
+		t.Errorf("unexpected entry %+v", knuth)
+	}
+	if got := entries[1].Field("title"); got != "LaTeX: A Document Preparation System" {
+		t.Errorf("unexpected title %q", got)
+	}
+
+	names := entries[3].Authors()
+	want := []BibName{{Last: "World Health Organization"}, {First: "Kurt", Last: "Gödel"}, {First: "Paul", Last: "Erdős"}}
+	if len(names) != len(want) {
+		t.Fatalf("expected %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("expected %v, got %v", want[i], names[i])
+		}
+	}
+}
+
+func TestCitations(t *testing.T) {
+	t.Chdir(t.TempDir())
+	dir := filepath.Join("data", "documents", "notes")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "refs.bib"), []byte(testBibliography), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join("data", "documents", "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	render := func(ctx *RenderContext, markdown string) string {
+		var buf bytes.Buffer
+		if err := goldmark.New(goldmark.WithExtensions(NewExtension(ctx))).Convert([]byte(markdown), &buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	got := render(&RenderContext{DocPath: "notes"}, "See [@lamport1994a; @knuth1984, p. 99] and [@knuth1984] but not [@nope].\n\n[@knuth1984](https://example.com)")
+	want := "<p>See <span class=\"citation\">[<a href=\"#ref-lamport1994a\">1</a>; <a href=\"#ref-knuth1984\">2</a>, p. 99]</span> and " +
+		"<span class=\"citation\">[<a href=\"#ref-knuth1984\">2</a>]</span> but not " +
+		"<span class=\"citation\">[<span class=\"citation-missing\" title=\"Not in the bibliography\">@nope</span>]</span>.</p>\n" +
+		"<p><a href=\"https://example.com\">@knuth1984</a></p>\n" +
+		"<section class=\"bibliography\">\n<h2 id=\"references\">References</h2>\n<ol class=\"references\">\n" +
+		"<li id=\"ref-lamport1994a\">Lamport, L. (1994). <em>LaTeX: A Document Preparation System</em>. Addison-Wesley.</li>\n" +
+		"<li id=\"ref-knuth1984\">Knuth, D. E. (1984). Literate Programming. <em>The Computer Journal</em>, 27(2), 97–111. " +
+		"<a href=\"https://doi.org/10.1093/comjnl/27.2.97\">https://doi.org/10.1093/comjnl/27.2.97</a></li>\n</ol>\n</section>\n"
+	if got != want {
+		t.Errorf("numeric:\nexpected %q\ngot      %q", want, got)
+	}
+
+	got = render(&RenderContext{DocPath: "notes", CitationStyle: "author-year"}, "[@lamport1994b] [@lamport1994a] [@group]\n\n## Sources\n\n:::bibliography:::\n\nThe end.")
+	for _, want := range []string{
+		"<span class=\"citation\">(<a href=\"#ref-lamport1994b\">Lamport, 1994b</a>)</span>",
+		"<span class=\"citation\">(<a href=\"#ref-group\">World Health Organization et al., 2020</a>)</span>",
+		"<section class=\"bibliography\">\n<ul class=\"references\">\n<li id=\"ref-lamport1994a\">Lamport, L. (1994a).",
+		"<li id=\"ref-lamport1994b\">Lamport, L. (1994b). The Temporal Logic of Actions. In <em>Proceedings</em>.</li>\n" +
+			"<li id=\"ref-group\">World Health Organization, Gödel, K., &amp; Erdős, P. (2020). <em>Report</em>.</li>\n</ul>\n</section>\n<p>The end.</p>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("author-year: expected %q in %q", want, got)
+		}
+	}
+
+	got = render(&RenderContext{DocPath: "empty"}, "Not a citation [@knuth1984]")
+	if want := "<p>Not a citation [@knuth1984]</p>\n"; got != want {
+		t.Errorf("without a bibliography: expected %q, got %q", want, got)
+	}
+
+	SetCitationDefaults("/notes/refs.bib", "")
+	defer SetCitationDefaults("", "")
+	got = render(&RenderContext{DocPath: "empty"}, "[@knuth1984]")
+	if !strings.Contains(got, "<a href=\"#ref-knuth1984\">1</a>") {
+		t.Errorf("with the wiki bibliography: expected a citation, got %q", got)
+	}
+}
@@ -1,110 +1,138 @@
 package goldext
 
 import (
-	"strings"
+	"bytes"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
-// DetailsPreprocessor adds support for ```details and ~~~details blocks
-func DetailsPreprocessor(markdown string, _ string) string {
-	lines := strings.Split(markdown, "\n")
-	var result []string
-	
-	var inCodeBlock bool
-	var codeBlockMarker string
-	
-	for i := 0; i < len(lines); i++ {
-		line := lines[i]
-		trimmedLine := strings.TrimSpace(line)
-
-		// Strip blockquote prefix(es) to detect code blocks inside blockquotes
-		// e.g., "> ```" or "> > ```" should be detected as code block markers
-		contentLine := trimmedLine
-		for strings.HasPrefix(contentLine, ">") {
-			contentLine = strings.TrimSpace(strings.TrimPrefix(contentLine, ">"))
-		}
-		
-		// Track regular code blocks (not details blocks)
-		if (strings.HasPrefix(contentLine, "```") || strings.HasPrefix(contentLine, "~~~")) &&
-			!strings.HasPrefix(contentLine, "```details") && !strings.HasPrefix(contentLine, "~~~details") {
-			
-			if !inCodeBlock {
-				// Starting a code block
-				inCodeBlock = true
-				if strings.HasPrefix(contentLine, "```") {
-					codeBlockMarker = "```"
-				} else {
-					codeBlockMarker = "~~~"
-				}
-			} else if contentLine == codeBlockMarker {
-				// Ending a code block
-				inCodeBlock = false
-				codeBlockMarker = ""
-			}
-			result = append(result, line)
-			continue
-		}
-		
-		// If we're inside a regular code block, don't process details
-		if inCodeBlock {
-			result = append(result, line)
-			continue
-		}
-		
-		// Check for details block start
-		if strings.HasPrefix(trimmedLine, "```details") || strings.HasPrefix(trimmedLine, "~~~details") {
-			var detailsMarker string
-			if strings.HasPrefix(trimmedLine, "```details") {
-				detailsMarker = "```"
-			} else {
-				detailsMarker = "~~~"
-			}
-			
-			// Extract the title (everything after "```details" or "~~~details")
-			detailsTitle := strings.TrimSpace(strings.TrimPrefix(
-				strings.TrimPrefix(trimmedLine, "```details"), "~~~details"))
-			
-			// Find the end of the details block
-			var detailsContent []string
-			j := i + 1
-			
-			for ; j < len(lines); j++ {
-				if strings.TrimSpace(lines[j]) == detailsMarker {
-					break
-				}
-				detailsContent = append(detailsContent, lines[j])
-			}
-			
-			// Generate the details HTML with proper markdown content
-			detailsHTML := "<details class=\"markdown-details\">"
-			if detailsTitle != "" {
-				detailsHTML += "<summary>" + detailsTitle + "</summary>"
-			} else {
-				detailsHTML += "<summary>Details</summary>"
-			}
-			detailsHTML += "<div class=\"details-content\">"
-			
-			// Add the content as-is so it can be processed by markdown renderer
-			if len(detailsContent) > 0 {
-				detailsHTML += "\n\n" + strings.Join(detailsContent, "\n") + "\n\n"
-			}
-			
-			detailsHTML += "</div></details>"
-			
-			result = append(result, detailsHTML)
-			
-			// Skip to the end of the details block
-			if j < len(lines) {
-				i = j
-			}
-			continue
-		}
-		
-		// Regular line
-		result = append(result, line)
+// KindDetails is the node kind of collapsible details blocks
+var KindDetails = ast.NewNodeKind("Details")
+
+// KindDetailsSummary is the node kind of the title of a details block
+var KindDetailsSummary = ast.NewNodeKind("DetailsSummary")
+
+// Details is a collapsible ```details block. Its first child is the
+// DetailsSummary, the others are the blocks inside it.
+type Details struct {
+	ast.BaseBlock
+	marker   string // Fence that closes the block, ``` or ~~~
+	fences   fenceTracker
+	hasLines bool // Whether any lines follow the opening fence
+}
+
+// Kind implements ast.Node.Kind
+func (n *Details) Kind() ast.NodeKind { return KindDetails }
+
+// Dump implements ast.Node.Dump
+func (n *Details) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// DetailsSummary holds the title of a details block as inline content
+type DetailsSummary struct {
+	ast.BaseBlock
+}
+
+// Kind implements ast.Node.Kind
+func (n *DetailsSummary) Kind() ast.NodeKind { return KindDetailsSummary }
+
+// Dump implements ast.Node.Dump
+func (n *DetailsSummary) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// detailsParser adds support for ```details and ~~~details blocks
+// Syntax:
+// ```details Optional title
+// Content
+// ```
+type detailsParser struct{}
+
+func (p *detailsParser) Trigger() []byte {
+	return []byte{'`', '~'}
+}
+
+func (p *detailsParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 {
+		return nil, parser.NoChildren
+	}
+	rest := line[pos:]
+
+	var marker string
+	switch {
+	case bytes.HasPrefix(rest, []byte("```details")):
+		marker = "```"
+	case bytes.HasPrefix(rest, []byte("~~~details")):
+		marker = "~~~"
+	default:
+		return nil, parser.NoChildren
+	}
+
+	node := &Details{marker: marker}
+	summary := &DetailsSummary{}
+
+	// Everything after "```details" is the title
+	start := segment.Start + pos + len(marker) + len("details")
+	title := text.NewSegment(start, segment.Stop)
+	title = title.TrimLeftSpace(reader.Source())
+	title = title.TrimRightSpace(reader.Source())
+	if title.Len() > 0 {
+		summary.Lines().Append(title)
 	}
-	
-	return strings.Join(result, "\n")
+	node.AppendChild(node, summary)
+
+	reader.AdvanceToEOL()
+	return node, parser.HasChildren
+}
+
+func (p *detailsParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	n := node.(*Details)
+	line, _ := reader.PeekLine()
+	if n.fences.closes(line, n.marker) {
+		reader.AdvanceToEOL()
+		return parser.Close
+	}
+	n.hasLines = true
+	return parser.Continue | parser.HasChildren
+}
+
+func (p *detailsParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *detailsParser) CanInterruptParagraph() bool {
+	return true
 }
 
-// Register Details preprocessor in the list of known processors
-var _ = DetailsPreprocessor
+func (p *detailsParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+func (r *nodeRenderer) renderDetails(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(`<details class="markdown-details">`)
+	} else {
+		_, _ = w.WriteString("</div></details>\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (r *nodeRenderer) renderDetailsSummary(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString("<summary>")
+		if !node.HasChildren() {
+			_, _ = w.WriteString("Details")
+		}
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = w.WriteString(`</summary><div class="details-content">`)
+	if node.Parent().(*Details).hasLines {
+		_ = w.WriteByte('\n')
+	}
+	return ast.WalkContinue, nil
+}
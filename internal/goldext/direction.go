@@ -1,201 +1,78 @@
 package goldext
 
 import (
-	"bytes"
-	"fmt"
-	"strings"
-	"sync"
-
-	"github.com/yuin/goldmark"
-	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/parser"
-	html "github.com/yuin/goldmark/renderer/html"
-)
-
-// Store extracted direction blocks until restored after Goldmark processing
-var (
-	directionBlocks     = make(map[string]string)
-	directionBlockCount = 0
-	directionMutex      sync.Mutex
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
-// DirectionPreprocessor extracts rtl/ltr blocks and replaces them with placeholders
-// The actual HTML generation will happen after Goldmark processes everything else
-func DirectionPreprocessor(markdown string, _ string) string {
-	directionMutex.Lock()
-	defer directionMutex.Unlock()
-
-	// Reset the storage on each new document
-	directionBlocks = make(map[string]string)
-	directionBlockCount = 0
-
-	// Process line by line to safely extract RTL/LTR blocks
-	lines := strings.Split(markdown, "\n")
-	var result []string
-
-	// State tracking
-	inCodeBlock := false  // Are we inside a non-RTL/LTR code block?
-	inRtlLtrBlock := false // Are we inside an RTL/LTR block?
-	blockType := "" // rtl or ltr
-	blockContent := []string{}
-	codeBlockDepth := 0
-
-	for i := 0; i < len(lines); i++ {
-		line := lines[i]
-		trimmed := strings.TrimSpace(line)
-
-		// Handle code block markers
-		if strings.HasPrefix(trimmed, "```") {
-			if trimmed == "```rtl" || trimmed == "```ltr" {
-				// Only process as RTL/LTR block if we're not already in a code block
-				if !inCodeBlock && !inRtlLtrBlock {
-					inRtlLtrBlock = true
-					blockType = strings.TrimPrefix(trimmed, "```")
-					blockContent = []string{}
-					continue
-				}
-			}
-
-			// Toggle code block state if not an RTL/LTR block or already in a code block
-			if !inRtlLtrBlock || inCodeBlock {
-				if codeBlockDepth == 0 {
-					codeBlockDepth = 1
-				} else {
-					codeBlockDepth = 0
-				}
-				inCodeBlock = codeBlockDepth > 0
-			}
-
-			// If this is the closing marker for an RTL/LTR block
-			if inRtlLtrBlock && trimmed == "```" && !inCodeBlock {
-				// Create a placeholder for this block
-				blockID := fmt.Sprintf("DIRECTION_BLOCK_%d", directionBlockCount)
-				directionBlockCount++
-
-				// Store the direction type and content for later processing
-				directionBlocks[blockID] = blockType + "|" + strings.Join(blockContent, "\n")
-
-				// Add the placeholder to the output
-				result = append(result, "<!-- "+blockID+" -->")
+// KindDirection is the node kind of RTL and LTR blocks
+var KindDirection = ast.NewNodeKind("Direction")
 
-				// Reset state
-				inRtlLtrBlock = false
-				blockType = ""
-				blockContent = []string{}
-				continue
-			}
-		} else if strings.HasPrefix(trimmed, "~~~") {
-			if trimmed == "~~~rtl" || trimmed == "~~~ltr" {
-				// Only process as RTL/LTR block if we're not already in a code block
-				if !inCodeBlock && !inRtlLtrBlock {
-					inRtlLtrBlock = true
-					blockType = strings.TrimPrefix(trimmed, "~~~")
-					blockContent = []string{}
-					continue
-				}
-			}
-
-			// Toggle code block state if not an RTL/LTR block or already in a code block
-			if !inRtlLtrBlock || inCodeBlock {
-				if codeBlockDepth == 0 {
-					codeBlockDepth = 1
-				} else {
-					codeBlockDepth = 0
-				}
-				inCodeBlock = codeBlockDepth > 0
-			}
+// Direction is a ```rtl or ```ltr block whose content is rendered as
+// markdown inside a div setting the text direction
+type Direction struct {
+	ast.BaseBlock
+	Dir    string // rtl or ltr
+	marker string // Fence that closes the block, ``` or ~~~
+	fences fenceTracker
+}
 
-			// If this is the closing marker for an RTL/LTR block
-			if inRtlLtrBlock && trimmed == "~~~" && !inCodeBlock {
-				// Create a placeholder for this block
-				blockID := fmt.Sprintf("DIRECTION_BLOCK_%d", directionBlockCount)
-				directionBlockCount++
+// Kind implements ast.Node.Kind
+func (n *Direction) Kind() ast.NodeKind { return KindDirection }
 
-				// Store the direction type and content for later processing
-				directionBlocks[blockID] = blockType + "|" + strings.Join(blockContent, "\n")
+// Dump implements ast.Node.Dump
+func (n *Direction) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Dir": n.Dir}, nil)
+}
 
-				// Add the placeholder to the output
-				result = append(result, "<!-- "+blockID+" -->")
+// directionParser adds support for ```rtl and ```ltr blocks
+type directionParser struct{}
 
-				// Reset state
-				inRtlLtrBlock = false
-				blockType = ""
-				blockContent = []string{}
-				continue
-			}
-		}
+func (p *directionParser) Trigger() []byte {
+	return []byte{'`', '~'}
+}
 
-		// Collect content or pass line through
-		if inRtlLtrBlock && !inCodeBlock {
-			blockContent = append(blockContent, line)
-		} else {
-			result = append(result, line)
-		}
+func (p *directionParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line := string(lineText(reader))
+	if len(line) != 6 {
+		return nil, parser.NoChildren
 	}
-
-	// Handle any unclosed blocks at EOF (rare case)
-	if inRtlLtrBlock && !inCodeBlock && blockType != "" {
-		blockID := fmt.Sprintf("DIRECTION_BLOCK_%d", directionBlockCount)
-		directionBlockCount++
-		directionBlocks[blockID] = blockType + "|" + strings.Join(blockContent, "\n")
-		result = append(result, "<!-- "+blockID+" -->")
+	marker, dir := line[:3], line[3:]
+	if (marker != "```" && marker != "~~~") || (dir != "rtl" && dir != "ltr") {
+		return nil, parser.NoChildren
 	}
 
-	return strings.Join(result, "\n")
+	reader.AdvanceToEOL()
+	return &Direction{Dir: dir, marker: marker}, parser.HasChildren
 }
 
-// RestoreDirectionBlocks replaces direction block placeholders with HTML
-// This must be called after Goldmark rendering
-func RestoreDirectionBlocks(htmlContent string) string {
-	directionMutex.Lock()
-	defer directionMutex.Unlock()
-
-	// Create our own Goldmark instance for RTL/LTR content processing
-	// This won't be recursive because we're only processing the content inside the blocks
-	md := goldmark.New(
-		goldmark.WithExtensions(
-			extension.Table,
-			extension.Strikethrough,
-			extension.Linkify,
-			extension.Footnote,
-			extension.DefinitionList,
-			extension.GFM,
-		),
-		goldmark.WithParserOptions(
-			parser.WithAutoHeadingID(),
-			parser.WithAttribute(),
-		),
-		goldmark.WithRendererOptions(
-			html.WithUnsafe(),
-			html.WithHardWraps(),
-		),
-	)
-
-	result := htmlContent
+func (p *directionParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	n := node.(*Direction)
+	line, _ := reader.PeekLine()
+	if n.fences.closes(line, n.marker) {
+		reader.AdvanceToEOL()
+		return parser.Close
+	}
+	return parser.Continue | parser.HasChildren
+}
 
-	// Replace each placeholder with processed HTML
-	for id, block := range directionBlocks {
-		placeholder := fmt.Sprintf("<!-- %s -->", id)
+func (p *directionParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
 
-		// Split the stored data into type and content
-		parts := strings.SplitN(block, "|", 2)
-		if len(parts) != 2 {
-			continue
-		}
+func (p *directionParser) CanInterruptParagraph() bool {
+	return true
+}
 
-		dirType := parts[0]
-		content := parts[1]
+func (p *directionParser) CanAcceptIndentedLine() bool {
+	return false
+}
 
-		// Render the content with Goldmark
-		var buf bytes.Buffer
-		if err := md.Convert([]byte(content), &buf); err != nil {
-			// If error, just use unprocessed content
-			result = strings.Replace(result, placeholder, fmt.Sprintf("<div class=\"%s\">%s</div>", dirType, content), 1)
-		} else {
-			// Use the rendered HTML inside the direction div
-			result = strings.Replace(result, placeholder, fmt.Sprintf("<div class=\"%s\">%s</div>", dirType, buf.String()), 1)
-		}
+func (r *nodeRenderer) renderDirection(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(`<div class="` + node.(*Direction).Dir + `">`)
+	} else {
+		_, _ = w.WriteString("</div>\n")
 	}
-
-	return result
+	return ast.WalkContinue, nil
 }
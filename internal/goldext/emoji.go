@@ -1,12 +1,17 @@
 package goldext
 
 import (
+	"bytes"
 	"encoding/json"
 	"io/fs"
 	"log"
 	"strings"
 
 	"wiki-go/internal/resources"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
 )
 
 // EmojiData represents an emoji entry in the JSON file
@@ -59,58 +64,61 @@ func init() {
 	log.Printf("Loaded %d emojis from emojis.json", len(emojis))
 }
 
-// EmojiPreprocessor replaces emoji shortcodes with Unicode emoji characters
-// but avoids processing text inside code blocks
-func EmojiPreprocessor(markdown string, _ string) string {
-	// Process line by line instead of relying on regex which might fail on large documents
-	lines := strings.Split(markdown, "\n")
-	var result []string
-
-	inCodeBlock := false
-
-	for _, line := range lines {
-		// Check if this line starts or ends a code block
-		trimmedLine := strings.TrimSpace(line)
+// matchEmoji returns the emoji for a :shortcode: at the start of text and
+// the length of the shortcode, or 0 if text doesn't start with one
+func matchEmoji(text []byte) (string, int) {
+	if len(text) < 3 || text[0] != ':' {
+		return "", 0
+	}
+	end := bytes.IndexByte(text[1:], ':')
+	if end < 1 {
+		return "", 0
+	}
+	code := text[:end+2]
+	if bytes.ContainsAny(code, " \t\n") {
+		return "", 0
+	}
+	emoji, ok := emojis[string(code)]
+	if !ok {
+		return "", 0
+	}
+	return emoji, len(code)
+}
 
-		// Strip blockquote prefix(es) to detect code blocks inside blockquotes
-		// e.g., "> ```" or "> > ```" should be detected as code block markers
-		contentLine := trimmedLine
-		for strings.HasPrefix(contentLine, ">") {
-			contentLine = strings.TrimSpace(strings.TrimPrefix(contentLine, ">"))
-		}
+// ReplaceEmoji replaces emoji shortcodes in plain text, such as document
+// titles, with Unicode emoji characters
+func ReplaceEmoji(text string) string {
+	if !strings.Contains(text, ":") {
+		return text
+	}
 
-		if strings.HasPrefix(contentLine, "```") || strings.HasPrefix(contentLine, "~~~") {
-			inCodeBlock = !inCodeBlock
-			result = append(result, line)
+	var result strings.Builder
+	for i := 0; i < len(text); {
+		if emoji, n := matchEmoji([]byte(text[i:])); n > 0 {
+			result.WriteString(emoji)
+			i += n
 			continue
 		}
+		result.WriteByte(text[i])
+		i++
+	}
+	return result.String()
+}
 
-		// If we're in a code block, don't process
-		if inCodeBlock {
-			result = append(result, line)
-			continue
-		}
+// emojiParser replaces emoji shortcodes in text with Unicode emoji
+// characters
+type emojiParser struct{}
 
-		// Process each segment of the line, preserving inline code
-		var processedLine string
-		segments := strings.Split(line, "`")
-
-		for i, segment := range segments {
-			// Even segments (0, 2, 4...) are outside inline code
-			if i%2 == 0 {
-				// Apply all replacements to non-code segments
-				for shortcode, emoji := range emojis {
-					segment = strings.ReplaceAll(segment, shortcode, emoji)
-				}
-				processedLine += segment
-			} else {
-				// Odd segments (1, 3, 5...) are inside inline code - preserve them
-				processedLine += "`" + segment + "`"
-			}
-		}
+func (p *emojiParser) Trigger() []byte {
+	return []byte{':'}
+}
 
-		result = append(result, processedLine)
+func (p *emojiParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	emoji, n := matchEmoji(line)
+	if n == 0 {
+		return nil
 	}
-
-	return strings.Join(result, "\n")
-}
\ No newline at end of file
+	block.Advance(n)
+	return ast.NewString([]byte(emoji))
+}
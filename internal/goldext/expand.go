@@ -0,0 +1,48 @@
+package goldext
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// shortcodeRegex matches a shortcode anywhere in text, :::name args:::
+var shortcodeRegex = regexp.MustCompile(`:::([a-z][a-z0-9-]*)(?:\s+([^:]*?))?\s*:::`)
+
+// ExpandShortcodes replaces the shortcodes in markdown outside code with
+// what they stand for, so the markdown reads the same outside the wiki.
+// :::var name::: and :::year::: become their text and :::include::: the
+// markdown of the included page or section, expanded in turn. Every other
+// shortcode renders HTML and is replaced by what render returns for it.
+func ExpandShortcodes(markdown string, ctx *RenderContext, render func(shortcode string) string) string {
+	return replaceOutsideCode(markdown, "", func(segment string) string {
+		return shortcodeRegex.ReplaceAllStringFunc(segment, func(match string) string {
+			parts := shortcodeRegex.FindStringSubmatch(match)
+			switch parts[1] {
+			case "year":
+				return strconv.Itoa(time.Now().Year())
+			case "var":
+				if value, ok := ctx.LookupVariable(strings.TrimSpace(parts[2])); ok {
+					return value
+				}
+				return match
+			case "include":
+				inc := includeRegex.FindStringSubmatch(match)
+				if inc == nil {
+					return match
+				}
+				path := normalizeIncludePath(inc[1])
+				if !ctx.CheckAccess(path) || ctx.IsIncluding(path) || len(ctx.IncludeStack) >= MaxIncludeDepth {
+					return ""
+				}
+				source, err := ReadIncludeSource(path, inc[2])
+				if err != nil {
+					return match
+				}
+				return ExpandShortcodes(strings.TrimSpace(source), ctx.Child(path), render)
+			}
+			return render(match)
+		})
+	})
+}
@@ -0,0 +1,40 @@
+package goldext
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandShortcodes(t *testing.T) {
+	ctx := &RenderContext{Variables: map[string]string{"product": "Acme"}}
+	source := "# :::var product:::\n\n© :::year::: :::var nope:::\n\n:::children:::\n\n`:::var product:::`\n\n```\n:::year:::\n```\n"
+
+	got := ExpandShortcodes(source, ctx, func(shortcode string) string {
+		return "<ul>" + shortcode + "</ul>"
+	})
+	want := "# Acme\n\n© " + strconv.Itoa(time.Now().Year()) + " :::var nope:::\n\n<ul>:::children:::</ul>\n\n`:::var product:::`\n\n```\n:::year:::\n```\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRewriteLinks(t *testing.T) {
+	source := "See [Install](/guide/install#linux \"Install\") and ![Logo](logo.png).\n\n```\n[code](/guide)\n```\n"
+
+	var images []string
+	got := RewriteLinks(source, func(destination string, image bool) string {
+		if image {
+			images = append(images, destination)
+		}
+		return strings.ToUpper(destination)
+	})
+	want := "See [Install](/GUIDE/INSTALL#LINUX \"Install\") and ![Logo](LOGO.PNG).\n\n```\n[code](/guide)\n```\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if len(images) != 1 || images[0] != "logo.png" {
+		t.Errorf("expected the image logo.png, got %v", images)
+	}
+}
@@ -0,0 +1,185 @@
+package goldext
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Extension adds the wiki's markdown syntax to goldmark: alerts, details and
+// direction blocks, video embeds, charts, shortcodes, tables of contents
+// with heading anchors, abbreviations and glossary terms, citations with a
+// list of references, and ==highlight==, ^superscript^, ~subscript~,
+// typography and emoji in inline text. Unlike preprocessors, it works on the
+// parsed document, so code spans, code blocks and nesting are handled by
+// goldmark.
+type Extension struct {
+	ctx *RenderContext
+}
+
+// NewExtension returns the extension for rendering the document described by
+// ctx. A nil ctx renders as a page without a path, such as the homepage.
+func NewExtension(ctx *RenderContext) goldmark.Extender {
+	if ctx == nil {
+		ctx = &RenderContext{}
+	}
+	return &Extension{ctx: ctx}
+}
+
+// Extend implements goldmark.Extender
+func (e *Extension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(
+			// Before the fenced code block parser (700), which would
+			// otherwise take ```details, ```rtl and ```ltr
+			util.Prioritized(&detailsParser{}, 650),
+			util.Prioritized(&directionParser{}, 660),
+			// Before the blockquote parser (800)
+			util.Prioritized(&alertParser{}, 790),
+			// Before the paragraph parser (1000)
+			util.Prioritized(&abbreviationParser{}, 940),
+			util.Prioritized(&tocParser{}, 950),
+			util.Prioritized(&shortcodeBlockParser{ctx: e.ctx}, 960),
+		),
+		parser.WithInlineParsers(
+			// Before the link parser (200), which would otherwise take
+			// [@key] as link text
+			util.Prioritized(&citationParser{}, 190),
+			// Before the strikethrough parser (500), so single and double
+			// tildes are told apart in one place
+			util.Prioritized(&tildeParser{}, 450),
+			util.Prioritized(&highlightParser{}, 460),
+			util.Prioritized(&superscriptParser{}, 470),
+			util.Prioritized(&shortcodeInlineParser{ctx: e.ctx}, 480),
+			util.Prioritized(&emojiParser{}, 490),
+			util.Prioritized(&typographyParser{}, 490),
+		),
+		parser.WithASTTransformers(
+			util.Prioritized(&videoTransformer{ctx: e.ctx}, 100),
+			util.Prioritized(&chartTransformer{ctx: e.ctx}, 110),
+			util.Prioritized(&headingTransformer{ctx: e.ctx}, 200),
+			util.Prioritized(&glossaryTransformer{ctx: e.ctx}, 300),
+			util.Prioritized(&citationTransformer{ctx: e.ctx}, 400),
+		),
+	)
+	m.Renderer().AddOptions(
+		renderer.WithNodeRenderers(
+			util.Prioritized(&nodeRenderer{}, 500),
+		),
+	)
+}
+
+// nodeRenderer renders the nodes added by Extension
+type nodeRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs
+func (r *nodeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindAlert, r.renderAlert)
+	reg.Register(KindDetails, r.renderDetails)
+	reg.Register(KindDetailsSummary, r.renderDetailsSummary)
+	reg.Register(KindDirection, r.renderDirection)
+	reg.Register(KindVideo, r.renderVideo)
+	reg.Register(KindChart, r.renderChart)
+	reg.Register(KindTOC, r.renderTOC)
+	reg.Register(KindHeadingAnchor, r.renderHeadingAnchor)
+	reg.Register(KindHeadingNumber, r.renderHeadingNumber)
+	reg.Register(KindSectionRef, r.renderSectionRef)
+	reg.Register(KindShortcode, r.renderShortcode)
+	reg.Register(KindInlineShortcode, r.renderInlineShortcode)
+	reg.Register(KindAbbr, r.renderAbbr)
+	reg.Register(KindCitation, r.renderCitation)
+	reg.Register(KindHighlight, r.renderHighlight)
+	reg.Register(KindSuperscript, r.renderSuperscript)
+	reg.Register(KindSubscript, r.renderSubscript)
+}
+
+// fenceTracker follows fenced code blocks nested in a fenced container such
+// as ```details, so that their closing fence doesn't close the container
+type fenceTracker struct {
+	open string // Fence of the nested code block, empty outside one
+}
+
+// closes reports whether the trimmed line closes the container opened with
+// marker, updating the nested fence state otherwise
+func (f *fenceTracker) closes(line []byte, marker string) bool {
+	trimmed := string(util.TrimRightSpace(util.TrimLeftSpace(line)))
+
+	if f.open != "" {
+		if isFence(trimmed) && trimmed[0] == f.open[0] && len(trimmed) >= len(f.open) {
+			f.open = ""
+		}
+		return false
+	}
+	if trimmed == marker {
+		return true
+	}
+	if fence := fencePrefix(trimmed); fence != "" {
+		f.open = fence
+	}
+	return false
+}
+
+// fencePrefix returns the run of backticks or tildes a fence line starts
+// with, or an empty string if the line is not a fence
+func fencePrefix(line string) string {
+	if len(line) < 3 || (line[0] != '`' && line[0] != '~') {
+		return ""
+	}
+	i := 0
+	for i < len(line) && line[i] == line[0] {
+		i++
+	}
+	if i < 3 {
+		return ""
+	}
+	return line[:i]
+}
+
+// isFence reports whether a line is only a closing fence
+func isFence(line string) bool {
+	fence := fencePrefix(line)
+	return fence != "" && len(fence) == len(line)
+}
+
+// lineText returns a reader's current line without surrounding whitespace
+func lineText(reader text.Reader) []byte {
+	line, _ := reader.PeekLine()
+	return util.TrimRightSpace(util.TrimLeftSpace(line))
+}
+
+// inMath reports whether the reader's position is inside $inline$ or
+// $$display$$ math in the current block, where ^ and ~ belong to MathJax
+func inMath(parent ast.Node, block text.Reader) bool {
+	lines := parent.Lines()
+	if lines.Len() == 0 {
+		return false
+	}
+	_, pos := block.Position()
+	source := block.Source()
+
+	inside, inCode := false, false
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		for j := seg.Start; j < seg.Stop && j < pos.Start; j++ {
+			switch source[j] {
+			case '`':
+				inCode = !inCode
+			case '$':
+				if inCode {
+					continue
+				}
+				inside = !inside
+				if j+1 < seg.Stop && j+1 < pos.Start && source[j+1] == '$' {
+					j++
+				}
+			}
+		}
+		if seg.Stop >= pos.Start {
+			break
+		}
+	}
+	return inside
+}
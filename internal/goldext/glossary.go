@@ -0,0 +1,507 @@
+package goldext
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"wiki-go/internal/frontmatter"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// GlossaryLayout is the frontmatter layout of glossary pages
+const GlossaryLayout = "glossary"
+
+// maxDefinitionLength limits the length of definitions shown as tooltips
+const maxDefinitionLength = 300
+
+// GlossaryEntry is a term defined on a glossary page
+type GlossaryEntry struct {
+	Term       string `json:"term"`
+	Definition string `json:"definition"` // Plain text of the first definition
+	Page       string `json:"page"`       // Path of the glossary page, such as /glossary
+	Anchor     string `json:"anchor"`     // ID of the term on the glossary page
+}
+
+// glossary holds the terms of all glossary pages, loaded by RefreshGlossary
+var (
+	glossaryMu      sync.RWMutex
+	glossaryEntries []GlossaryEntry
+	glossaryVersion string
+)
+
+// RefreshGlossary reloads the terms of every page with layout: glossary and
+// reports whether they changed
+func RefreshGlossary() bool {
+	entries := loadGlossaryEntries(filepath.Join("data", "documents"))
+
+	h := sha256.New()
+	for _, e := range entries {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\n", e.Page, e.Anchor, e.Term, e.Definition)
+	}
+	version := hex.EncodeToString(h.Sum(nil))[:16]
+	if len(entries) == 0 {
+		version = ""
+	}
+
+	glossaryMu.Lock()
+	defer glossaryMu.Unlock()
+	changed := version != glossaryVersion
+	glossaryEntries = entries
+	glossaryVersion = version
+	return changed
+}
+
+// GlossaryVersion identifies the loaded glossary terms, so it can be part of
+// cache keys. It is empty when there are none.
+func GlossaryVersion() string {
+	glossaryMu.RLock()
+	defer glossaryMu.RUnlock()
+	return glossaryVersion
+}
+
+// GlossaryEntries returns the loaded glossary terms
+func GlossaryEntries() []GlossaryEntry {
+	glossaryMu.RLock()
+	defer glossaryMu.RUnlock()
+	return glossaryEntries
+}
+
+// loadGlossaryEntries reads the terms of the glossary pages below
+// docsDir. When a term is defined more than once, the first page by path
+// wins.
+func loadGlossaryEntries(docsDir string) []GlossaryEntry {
+	var entries []GlossaryEntry
+	seen := map[string]bool{}
+
+	filepath.Walk(docsDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Name() != "document.md" {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		metadata, body, ok := frontmatter.Parse(string(content))
+		if !ok || metadata.Layout != GlossaryLayout {
+			return nil
+		}
+
+		rel, err := filepath.Rel(docsDir, filepath.Dir(path))
+		if err != nil {
+			return nil
+		}
+		page := "/" + filepath.ToSlash(rel)
+		for _, entry := range parseGlossary(body, page) {
+			key := strings.ToLower(entry.Term)
+			if !seen[key] {
+				seen[key] = true
+				entries = append(entries, entry)
+			}
+		}
+		return nil
+	})
+	return entries
+}
+
+// parseGlossary returns the terms of the definition lists in the markdown
+// of a glossary page
+func parseGlossary(markdown, page string) []GlossaryEntry {
+	source := []byte(markdown)
+	md := goldmark.New(goldmark.WithExtensions(extension.DefinitionList))
+	doc := md.Parser().Parse(text.NewReader(source))
+
+	var entries []GlossaryEntry
+	anchors := map[string]int{}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		term, ok := n.(*extast.DefinitionTerm)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		name := strings.TrimSpace(plainText(term, source))
+		if name == "" {
+			return ast.WalkSkipChildren, nil
+		}
+
+		definition := ""
+		if desc, ok := term.NextSibling().(*extast.DefinitionDescription); ok {
+			definition = strings.Join(strings.Fields(plainText(desc, source)), " ")
+		}
+		if utf8.RuneCountInString(definition) > maxDefinitionLength {
+			definition = string([]rune(definition)[:maxDefinitionLength-1]) + "…"
+		}
+
+		entries = append(entries, GlossaryEntry{
+			Term:       name,
+			Definition: definition,
+			Page:       page,
+			Anchor:     uniqueAnchor(glossaryAnchor(name), anchors),
+		})
+		return ast.WalkSkipChildren, nil
+	})
+	return entries
+}
+
+// glossaryAnchor returns the ID of a term on its glossary page
+func glossaryAnchor(term string) string {
+	return "term-" + makeSlug(term)
+}
+
+// uniqueAnchor makes an anchor unique among those seen on a page
+func uniqueAnchor(anchor string, seen map[string]int) string {
+	count := seen[anchor]
+	seen[anchor]++
+	if count > 0 {
+		return fmt.Sprintf("%s-%d", anchor, count)
+	}
+	return anchor
+}
+
+// plainText returns the text inside a node without markup
+func plainText(node ast.Node, source []byte) string {
+	var buf strings.Builder
+	ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := n.(type) {
+		case *ast.Text:
+			buf.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(t.Value)
+		case *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		default:
+			if n.Type() == ast.TypeBlock && n != node && buf.Len() > 0 {
+				buf.WriteByte(' ')
+			}
+			if n.Type() == ast.TypeBlock && n.IsRaw() {
+				lines := n.Lines()
+				for i := 0; i < lines.Len(); i++ {
+					line := lines.At(i)
+					buf.Write(line.Value(source))
+				}
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return buf.String()
+}
+
+// abbreviationRegex matches a PHP Markdown Extra abbreviation definition,
+// *[HTML]: Hyper Text Markup Language
+var abbreviationRegex = regexp.MustCompile(`^\*\[([^\]]+)\]:\s*(.*)$`)
+
+// abbreviationsKey stores the abbreviations defined in a document in the
+// parser context
+var abbreviationsKey = parser.NewContextKey()
+
+// abbreviation is an abbreviation defined in a page
+type abbreviation struct {
+	Abbr, Title string
+}
+
+// abbreviationParser takes *[ABBR]: Title lines out of the document and
+// collects them for the glossary transformer
+type abbreviationParser struct{}
+
+func (p *abbreviationParser) Trigger() []byte {
+	return []byte{'*'}
+}
+
+func (p *abbreviationParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	matches := abbreviationRegex.FindSubmatch(lineText(reader))
+	if matches == nil || strings.TrimSpace(string(matches[1])) == "" {
+		return nil, parser.NoChildren
+	}
+
+	var abbrs []abbreviation
+	if v := pc.Get(abbreviationsKey); v != nil {
+		abbrs = v.([]abbreviation)
+	}
+	pc.Set(abbreviationsKey, append(abbrs, abbreviation{
+		Abbr:  strings.TrimSpace(string(matches[1])),
+		Title: strings.TrimSpace(string(matches[2])),
+	}))
+
+	reader.AdvanceToEOL()
+	return ast.NewTextBlock(), parser.NoChildren
+}
+
+func (p *abbreviationParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	return parser.Close
+}
+
+func (p *abbreviationParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+	// The definition itself isn't shown
+	node.Parent().RemoveChild(node.Parent(), node)
+}
+
+func (p *abbreviationParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *abbreviationParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// KindAbbr is the node kind of abbreviations and glossary terms in text
+var KindAbbr = ast.NewNodeKind("Abbr")
+
+// Abbr is an abbreviation or glossary term in text. Glossary terms link to
+// their definition.
+type Abbr struct {
+	ast.BaseInline
+	Title string // Expansion or definition, shown as a tooltip
+	Href  string // Link to the glossary entry, empty for abbreviations
+}
+
+// Kind implements ast.Node.Kind
+func (n *Abbr) Kind() ast.NodeKind { return KindAbbr }
+
+// Dump implements ast.Node.Dump
+func (n *Abbr) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Title": n.Title, "Href": n.Href}, nil)
+}
+
+func (r *nodeRenderer) renderAbbr(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*Abbr)
+	if entering {
+		if n.Href != "" {
+			_, _ = w.WriteString(`<a class="glossary-term" href="`)
+			_, _ = w.Write(util.EscapeHTML(util.URLEscape([]byte(n.Href), true)))
+			_, _ = w.WriteString(`">`)
+		}
+		_, _ = w.WriteString(`<abbr title="`)
+		_, _ = w.Write(util.EscapeHTML([]byte(n.Title)))
+		_, _ = w.WriteString(`">`)
+	} else {
+		_, _ = w.WriteString("</abbr>")
+		if n.Href != "" {
+			_, _ = w.WriteString("</a>")
+		}
+	}
+	return ast.WalkContinue, nil
+}
+
+// abbrTarget is a term to look for in text
+type abbrTarget struct {
+	Text      string
+	Title     string
+	Href      string
+	FirstOnly bool // Glossary terms are marked once per page, abbreviations everywhere
+}
+
+// glossaryTransformer marks abbreviations defined in the page, and glossary
+// terms when ctx.LinkGlossary is set, in the text of the page. Code, links
+// and headings are left alone. On glossary pages it gives each term an ID
+// for the links to point to.
+type glossaryTransformer struct {
+	ctx *RenderContext
+}
+
+// Transform implements parser.ASTTransformer
+func (t *glossaryTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	if t.ctx.Layout == GlossaryLayout {
+		anchorGlossaryTerms(doc, source)
+	}
+
+	var targets []abbrTarget
+	if v := pc.Get(abbreviationsKey); v != nil {
+		for _, a := range v.([]abbreviation) {
+			targets = append(targets, abbrTarget{Text: a.Abbr, Title: a.Title})
+		}
+	}
+	if t.ctx.LinkGlossary {
+		for _, entry := range GlossaryEntries() {
+			if !t.ctx.CheckAccess(entry.Page) {
+				continue
+			}
+			targets = append(targets, abbrTarget{
+				Text:      entry.Term,
+				Title:     entry.Definition,
+				Href:      entry.Page + "#" + entry.Anchor,
+				FirstOnly: true,
+			})
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	// Longer terms first, so "render cache" wins over "cache"
+	sort.SliceStable(targets, func(i, j int) bool { return len(targets[i].Text) > len(targets[j].Text) })
+	matcher := newAbbrMatcher(targets)
+
+	var texts []*ast.Text
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.(type) {
+		case *ast.CodeSpan, *ast.Link, *ast.AutoLink, *ast.Image, *ast.RawHTML, *ast.Heading, *Abbr, *HeadingAnchor:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			texts = append(texts, n.(*ast.Text))
+		}
+		return ast.WalkContinue, nil
+	})
+
+	used := map[int]bool{}
+	for _, node := range texts {
+		for node != nil {
+			node = matcher.markNext(node, source, used)
+		}
+	}
+}
+
+// abbrMatcher finds terms in text: case-sensitively for abbreviations and
+// all-caps terms, ignoring case for other glossary terms
+type abbrMatcher struct {
+	targets   []abbrTarget
+	exact     *regexp.Regexp
+	exactIdx  map[string]int
+	folded    *regexp.Regexp
+	foldedIdx map[string]int
+}
+
+func newAbbrMatcher(targets []abbrTarget) *abbrMatcher {
+	m := &abbrMatcher{targets: targets, exactIdx: map[string]int{}, foldedIdx: map[string]int{}}
+	var exact, folded []string
+	for i, target := range targets {
+		if target.Href == "" || strings.ToUpper(target.Text) == target.Text {
+			if _, ok := m.exactIdx[target.Text]; !ok {
+				m.exactIdx[target.Text] = i
+				exact = append(exact, regexp.QuoteMeta(target.Text))
+			}
+		} else {
+			key := strings.ToLower(target.Text)
+			if _, ok := m.foldedIdx[key]; !ok {
+				m.foldedIdx[key] = i
+				folded = append(folded, regexp.QuoteMeta(target.Text))
+			}
+		}
+	}
+	if len(exact) > 0 {
+		m.exact = regexp.MustCompile(strings.Join(exact, "|"))
+	}
+	if len(folded) > 0 {
+		m.folded = regexp.MustCompile("(?i)" + strings.Join(folded, "|"))
+	}
+	return m
+}
+
+// markNext wraps the first term in a text node that is not yet used and
+// stands on word boundaries in an Abbr node. It returns the text node after
+// the term to continue with, or nil if there was no term.
+func (m *abbrMatcher) markNext(node *ast.Text, source []byte, used map[int]bool) *ast.Text {
+	value := node.Segment.Value(source)
+	start, end, idx := -1, -1, -1
+
+	find := func(re *regexp.Regexp, index map[string]int, fold bool) {
+		if re == nil {
+			return
+		}
+		for _, loc := range re.FindAllIndex(value, -1) {
+			if start >= 0 && loc[0] >= start {
+				return
+			}
+			key := string(value[loc[0]:loc[1]])
+			if fold {
+				key = strings.ToLower(key)
+			}
+			i := index[key]
+			if (m.targets[i].FirstOnly && used[i]) || !onWordBoundaries(value, loc[0], loc[1]) {
+				continue
+			}
+			start, end, idx = loc[0], loc[1], i
+			return
+		}
+	}
+	find(m.exact, m.exactIdx, false)
+	find(m.folded, m.foldedIdx, true)
+	if idx < 0 {
+		return nil
+	}
+	used[idx] = true
+
+	parent := node.Parent()
+	seg := node.Segment
+	target := m.targets[idx]
+
+	if start > 0 {
+		before := ast.NewTextSegment(seg.WithStop(seg.Start + start))
+		parent.InsertBefore(parent, node, before)
+	}
+	abbr := &Abbr{Title: target.Title, Href: target.Href}
+	abbr.AppendChild(abbr, ast.NewTextSegment(text.NewSegment(seg.Start+start, seg.Start+end)))
+	parent.InsertBefore(parent, node, abbr)
+
+	if seg.Start+end == seg.Stop {
+		// The term ends the text, so the line break moves to the term
+		last := abbr.FirstChild().(*ast.Text)
+		last.SetSoftLineBreak(node.SoftLineBreak())
+		last.SetHardLineBreak(node.HardLineBreak())
+		parent.RemoveChild(parent, node)
+		return nil
+	}
+	node.Segment = seg.WithStart(seg.Start + end)
+	return node
+}
+
+// onWordBoundaries reports whether value[start:end] is not part of a
+// longer word
+func onWordBoundaries(value []byte, start, end int) bool {
+	if r, _ := utf8.DecodeLastRune(value[:start]); start > 0 && isWordRune(r) {
+		return false
+	}
+	if r, _ := utf8.DecodeRune(value[end:]); end < len(value) && isWordRune(r) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// anchorGlossaryTerms gives every definition list term of a glossary page
+// an ID and marks the lists as a glossary
+func anchorGlossaryTerms(doc *ast.Document, source []byte) {
+	anchors := map[string]int{}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *extast.DefinitionList:
+			node.SetAttributeString("class", []byte("glossary"))
+		case *extast.DefinitionTerm:
+			name := strings.TrimSpace(plainText(node, source))
+			if name != "" {
+				node.SetAttributeString("id", []byte(uniqueAnchor(glossaryAnchor(name), anchors)))
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+}
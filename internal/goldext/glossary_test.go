@@ -0,0 +1,94 @@
+package goldext
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+func TestGlossary(t *testing.T) {
+	t.Chdir(t.TempDir())
+	glossary := "---\nlayout: glossary\n---\n# Glossary\n\nRender cache\n: Stores rendered *HTML* of pages.\n\nAPI\n: Application programming interface.\n\nSecret term\n: Hidden.\n"
+	restricted := "---\nlayout: glossary\n---\nHidden word\n: Only for admins.\n"
+	for path, content := range map[string]string{"glossary": glossary, "admin/terms": restricted} {
+		file := filepath.Join("data", "documents", path, "document.md")
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !RefreshGlossary() {
+		t.Fatal("expected the glossary to change after loading")
+	}
+	defer func() {
+		os.RemoveAll("data")
+		RefreshGlossary()
+	}()
+	if RefreshGlossary() {
+		t.Error("expected no change when reloading the same glossary")
+	}
+
+	render := func(ctx *RenderContext, markdown string) string {
+		md := goldmark.New(goldmark.WithExtensions(extension.GFM, extension.DefinitionList, NewExtension(ctx)))
+		var buf bytes.Buffer
+		if err := md.Convert([]byte(markdown), &buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	viewer := func(path string) bool { return path != "/admin/terms" }
+
+	tests := []struct {
+		name     string
+		ctx      *RenderContext
+		input    string
+		expected string
+	}{
+		{
+			name:  "First occurrence only, longest term first",
+			ctx:   &RenderContext{LinkGlossary: true, CanAccess: viewer},
+			input: "The render cache is a cache. The Render Cache again, via the API and `API`.",
+			expected: "<p>The <a class=\"glossary-term\" href=\"/glossary#term-render-cache\"><abbr title=\"Stores rendered HTML of pages.\">render cache</abbr></a> is a cache. " +
+				"The Render Cache again, via the <a class=\"glossary-term\" href=\"/glossary#term-api\"><abbr title=\"Application programming interface.\">API</abbr></a> and <code>API</code>.</p>\n",
+		},
+		{
+			name:     "Links, headings and partial words are skipped",
+			ctx:      &RenderContext{LinkGlossary: true, CanAccess: viewer},
+			input:    "# API\n\n[the API](/x) RAPID APIs\n\nHidden word",
+			expected: "<h1 id=\"api\">API <a class=\"heading-anchor\" href=\"#api\" aria-label=\"Permalink\">¶</a></h1>\n<p><a href=\"/x\">the API</a> RAPID APIs</p>\n<p>Hidden word</p>\n",
+		},
+		{
+			name:     "Opted out",
+			ctx:      &RenderContext{CanAccess: viewer},
+			input:    "The API",
+			expected: "<p>The API</p>\n",
+		},
+		{
+			name:     "Abbreviations",
+			ctx:      &RenderContext{},
+			input:    "*[HTML]: Hyper Text Markup Language\nHTML and HTML5 and HTML.",
+			expected: "<p><abbr title=\"Hyper Text Markup Language\">HTML</abbr> and HTML5 and <abbr title=\"Hyper Text Markup Language\">HTML</abbr>.</p>\n",
+		},
+		{
+			name:     "Glossary page anchors",
+			ctx:      &RenderContext{Layout: GlossaryLayout},
+			input:    "API\n: One\n\nAPI\n: Two\n",
+			expected: "<dl class=\"glossary\">\n<dt id=\"term-api\">API</dt>\n<dd>One</dd>\n<dt id=\"term-api-1\">API</dt>\n<dd>Two</dd>\n</dl>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := render(tt.ctx, tt.input)
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+}
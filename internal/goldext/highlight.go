@@ -1,67 +1,70 @@
 package goldext
 
 import (
-	"regexp"
-	"strings"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
-// HighlightPreprocessor adds support for ==highlighted text==
-// It correctly handles code blocks, math blocks, and other special sections
-func HighlightPreprocessor(markdown string, _ string) string {
-	// Process line by line instead of relying on regex which might fail on large documents
-	lines := strings.Split(markdown, "\n")
-	var result []string
+// KindHighlight is the node kind of ==highlighted text==
+var KindHighlight = ast.NewNodeKind("Highlight")
 
-	inCodeBlock := false
-	highlightRegex := regexp.MustCompile(`([^=]|^)==([^=\n]+?)==([^=]|$)`)
+// Highlight is ==highlighted text==, rendered as <mark>
+type Highlight struct {
+	ast.BaseInline
+}
+
+// Kind implements ast.Node.Kind
+func (n *Highlight) Kind() ast.NodeKind { return KindHighlight }
 
-	for _, line := range lines {
-		// Check if this line starts or ends a code block
-		trimmedLine := strings.TrimSpace(line)
+// Dump implements ast.Node.Dump
+func (n *Highlight) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
 
-		// Strip blockquote prefix(es) to detect code blocks inside blockquotes
-		// e.g., "> ```" or "> > ```" should be detected as code block markers
-		contentLine := trimmedLine
-		for strings.HasPrefix(contentLine, ">") {
-			contentLine = strings.TrimSpace(strings.TrimPrefix(contentLine, ">"))
-		}
+// highlightDelimiter pairs == delimiters
+type highlightDelimiter struct{}
 
-		if strings.HasPrefix(contentLine, "```") || strings.HasPrefix(contentLine, "~~~") {
-			inCodeBlock = !inCodeBlock
-			result = append(result, line)
-			continue
-		}
+func (d *highlightDelimiter) IsDelimiter(b byte) bool {
+	return b == '='
+}
 
-		// If we're in a code block, don't process
-		if inCodeBlock {
-			result = append(result, line)
-			continue
-		}
+func (d *highlightDelimiter) CanOpenCloser(opener, closer *parser.Delimiter) bool {
+	return opener.Char == closer.Char
+}
 
-		// Process each segment of the line, preserving inline code
-		var processedLine string
-		segments := strings.Split(line, "`")
+func (d *highlightDelimiter) OnMatch(consumes int) ast.Node {
+	return &Highlight{}
+}
 
-		for i, segment := range segments {
-			// Even segments (0, 2, 4...) are outside inline code
-			if i%2 == 0 {
-				// Apply highlight replacement to non-code segments
-				for {
-					replaced := highlightRegex.ReplaceAllString(segment, "$1<mark>$2</mark>$3")
-					if replaced == segment {
-						break // No more matches
-					}
-					segment = replaced
-				}
-				processedLine += segment
-			} else {
-				// Odd segments (1, 3, 5...) are inside inline code - preserve them
-				processedLine += "`" + segment + "`"
-			}
-		}
+// highlightParser adds support for ==highlighted text==. Only runs of
+// exactly two = are delimiters.
+type highlightParser struct{}
 
-		result = append(result, processedLine)
+func (p *highlightParser) Trigger() []byte {
+	return []byte{'='}
+}
+
+func (p *highlightParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	before := block.PrecendingCharacter()
+	line, segment := block.PeekLine()
+	node := parser.ScanDelimiter(line, before, 2, &highlightDelimiter{})
+	if node == nil || node.OriginalLength != 2 || before == '=' {
+		return nil
 	}
 
-	return strings.Join(result, "\n")
+	node.Segment = segment.WithStop(segment.Start + node.OriginalLength)
+	block.Advance(node.OriginalLength)
+	pc.PushDelimiter(node)
+	return node
+}
+
+func (r *nodeRenderer) renderHighlight(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString("<mark>")
+	} else {
+		_, _ = w.WriteString("</mark>")
+	}
+	return ast.WalkContinue, nil
 }
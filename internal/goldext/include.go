@@ -0,0 +1,342 @@
+package goldext
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"wiki-go/internal/frontmatter"
+)
+
+// MaxIncludeDepth limits how deeply :::include::: shortcodes may nest
+const MaxIncludeDepth = 8
+
+// includeRegex matches :::include /path::: and :::include /path#section:::
+var includeRegex = regexp.MustCompile(`:::include\s+([^:#\s]+)(?:#([^:\s]+))?\s*:::`)
+
+// sectionHeadingRegex matches an ATX heading and its optional {#id}
+var sectionHeadingRegex = regexp.MustCompile(`^(#{1,6})\s+(.+?)(?:\s+\{#([a-zA-Z0-9-]+)\})?\s*$`)
+
+// Include represents a single :::include::: shortcode found in a document
+type Include struct {
+	ID      string // Placeholder ID used until the included HTML is restored
+	Path    string // Document path of the included page, always starting with /
+	Section string // Heading anchor to extract, empty for the whole page
+}
+
+// RenderContext carries per-render state that does not fit the Preprocessor
+// signature, such as who is viewing the page and which pages are being included
+type RenderContext struct {
+	DocPath        string                 // Path of the document being rendered
+	CanAccess      func(path string) bool // Access check for the viewer, nil denies includes
+	IncludeStack   []string               // Pages currently being included, used for cycle detection
+	Deps           *RenderDeps            // Records what the output depends on, nil if not needed
+	Layout         string                 // Frontmatter layout of the page
+	LinkGlossary   bool                   // Link glossary terms in the page's text
+	Bibliography   string                 // .bib attachment named in the frontmatter, instead of all attached ones
+	CitationStyle  string                 // Citation style from the frontmatter, empty for the wiki's default
+	Variables      map[string]string      // Variables set in the frontmatter, overriding wiki-wide ones
+	NumberHeadings bool                   // Number the page's headings 1, 1.1, 1.1.1
+}
+
+// RenderDeps records what a rendered page depends on besides its own
+// content, so that a cached copy can be checked before it is reused
+type RenderDeps struct {
+	Access     map[string]bool  // Pages the viewer's access was checked for, and the result
+	Includes   map[string]int64 // Included pages and the modification time of their source
+	Files      map[string]int64 // Attachments read while rendering and their modification time
+	Tree       bool             // Lists documents across the wiki, e.g. :::stats::: or :::children:::
+	Dated      bool             // Changes with the current date, e.g. :::year::: or kanban due dates
+	LinkStatus bool             // Shows results of the link checker
+}
+
+// CheckAccess runs the viewer's access check for path and records the result
+func (ctx *RenderContext) CheckAccess(path string) bool {
+	allowed := ctx.CanAccess != nil && ctx.CanAccess(path)
+	if ctx.Deps != nil {
+		if ctx.Deps.Access == nil {
+			ctx.Deps.Access = make(map[string]bool)
+		}
+		ctx.Deps.Access[path] = allowed
+	}
+	return allowed
+}
+
+// RecordInclude records that the source of the page at path is part of the output
+func (ctx *RenderContext) RecordInclude(path string) {
+	if ctx.Deps == nil {
+		return
+	}
+	if ctx.Deps.Includes == nil {
+		ctx.Deps.Includes = make(map[string]int64)
+	}
+	ctx.Deps.Includes[normalizeIncludePath(path)] = PageModTime(path)
+}
+
+// RecordFile records that the attachment at fsPath is part of the output
+func (ctx *RenderContext) RecordFile(fsPath string) {
+	if ctx.Deps == nil {
+		return
+	}
+	if ctx.Deps.Files == nil {
+		ctx.Deps.Files = make(map[string]int64)
+	}
+	ctx.Deps.Files[fsPath] = FileModTime(fsPath)
+}
+
+// FileModTime returns the modification time of a file in nanoseconds, or 0
+// if it doesn't exist
+func FileModTime(fsPath string) int64 {
+	info, err := os.Stat(fsPath)
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
+
+// RecordContent records the dependencies implied by the markdown of a page
+// or include and its layout
+func (ctx *RenderContext) RecordContent(markdown, layout string) {
+	if ctx.Deps == nil {
+		return
+	}
+	if strings.Contains(markdown, ":::stats") || strings.Contains(markdown, ":::children") ||
+		strings.Contains(markdown, ":::siblings") || strings.Contains(markdown, ":::recent") {
+		ctx.Deps.Tree = true
+	}
+	if strings.Contains(markdown, ":::year:::") || layout == "kanban" {
+		ctx.Deps.Dated = true
+	}
+	if layout == "links" {
+		ctx.Deps.LinkStatus = true
+	}
+}
+
+// PageModTime returns the modification time of a page's source in
+// nanoseconds, or 0 if it doesn't exist
+func PageModTime(path string) int64 {
+	info, err := os.Stat(includeFilePath(path))
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
+
+// Child returns a context for rendering the included page at path, with the
+// current page pushed onto the include stack
+func (ctx *RenderContext) Child(path string) *RenderContext {
+	stack := make([]string, 0, len(ctx.IncludeStack)+1)
+	stack = append(stack, ctx.IncludeStack...)
+	stack = append(stack, normalizeIncludePath(ctx.DocPath))
+	return &RenderContext{
+		DocPath:      strings.TrimPrefix(path, "/"),
+		CanAccess:    ctx.CanAccess,
+		IncludeStack: stack,
+		Deps:         ctx.Deps,
+	}
+}
+
+// IsIncluding reports whether path is the current page or one of the pages
+// including it, which means including it again would create a cycle
+func (ctx *RenderContext) IsIncluding(path string) bool {
+	path = normalizeIncludePath(path)
+	if path == normalizeIncludePath(ctx.DocPath) {
+		return true
+	}
+	for _, p := range ctx.IncludeStack {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractIncludes replaces :::include::: shortcodes outside code blocks with
+// placeholders that Goldmark won't touch and returns the includes found.
+// The caller renders each include and restores it with RestoreIncludes.
+func ExtractIncludes(markdown string) (string, []Include) {
+	var includes []Include
+
+	result := replaceOutsideCode(markdown, "include", func(segment string) string {
+		return includeRegex.ReplaceAllStringFunc(segment, func(match string) string {
+			parts := includeRegex.FindStringSubmatch(match)
+			if len(parts) < 2 {
+				return match
+			}
+
+			id := fmt.Sprintf("INCLUDE_BLOCK_%d", len(includes))
+			includes = append(includes, Include{
+				ID:      id,
+				Path:    normalizeIncludePath(parts[1]),
+				Section: parts[2],
+			})
+
+			// Surround with blank lines so the placeholder becomes its own HTML block
+			return "\n<!-- " + id + " -->\n"
+		})
+	})
+
+	return result, includes
+}
+
+// FindIncludes returns the pages included by markdown without modifying it.
+// It is used by search and backlinks to discover transclusion dependencies.
+func FindIncludes(markdown string) []Include {
+	_, includes := ExtractIncludes(markdown)
+	return includes
+}
+
+// RestoreIncludes replaces include placeholders with the rendered HTML
+func RestoreIncludes(htmlContent string, rendered map[string]string) string {
+	result := htmlContent
+	for id, block := range rendered {
+		placeholder := fmt.Sprintf("<!-- %s -->", id)
+		result = strings.Replace(result, placeholder, block, 1)
+	}
+	return result
+}
+
+// ReadIncludeSource loads the markdown of an included page without its
+// frontmatter, optionally narrowed down to a single section
+func ReadIncludeSource(path, section string) (string, error) {
+	content, err := os.ReadFile(includeFilePath(path))
+	if err != nil {
+		return "", err
+	}
+
+	_, body, hasFrontmatter := frontmatter.Parse(string(content))
+	if !hasFrontmatter {
+		body = string(content)
+	}
+
+	if section == "" {
+		return body, nil
+	}
+
+	sectionContent, ok := extractSection(body, section)
+	if !ok {
+		return "", fmt.Errorf("section #%s not found in %s", section, path)
+	}
+	return sectionContent, nil
+}
+
+// WrapInclude wraps rendered include HTML in a container identifying its source
+func WrapInclude(inc Include, renderedHTML string) string {
+	source := inc.Path
+	if inc.Section != "" {
+		source += "#" + inc.Section
+	}
+	return fmt.Sprintf("<div class=\"wiki-include\" data-include-path=\"%s\">\n%s</div>", html.EscapeString(source), renderedHTML)
+}
+
+// RenderIncludeError renders a visible notice in place of an include that could not be resolved
+func RenderIncludeError(inc Include, message string) string {
+	return fmt.Sprintf("<div class=\"wiki-include include-error\" data-include-path=\"%s\">%s</div>",
+		html.EscapeString(inc.Path), html.EscapeString(message))
+}
+
+// normalizeIncludePath cleans an include path and makes sure it starts with /
+func normalizeIncludePath(path string) string {
+	path = strings.TrimSpace(path)
+	path = filepath.ToSlash(filepath.Clean("/" + strings.TrimLeft(path, "/")))
+	return path
+}
+
+// includeFilePath returns the document.md path for an included page
+func includeFilePath(path string) string {
+	path = strings.Trim(normalizeIncludePath(path), "/")
+	if path == "" {
+		return filepath.Join("data", "pages", "home", "document.md")
+	}
+	return filepath.Join("data", "documents", filepath.FromSlash(path), "document.md")
+}
+
+// extractSection returns the heading matching section and everything below it
+// up to the next heading of the same or a higher level
+func extractSection(markdown, section string) (string, bool) {
+	lines := strings.Split(markdown, "\n")
+
+	inCodeBlock := false
+	start, level := -1, 0
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock {
+			continue
+		}
+
+		m := sectionHeadingRegex.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+
+		if start >= 0 {
+			if len(m[1]) <= level {
+				return strings.Join(lines[start:i], "\n"), true
+			}
+			continue
+		}
+
+		id := m[3]
+		if id == "" {
+			id = makeSlug(m[2])
+		}
+		if id == section {
+			start, level = i, len(m[1])
+		}
+	}
+
+	if start >= 0 {
+		return strings.Join(lines[start:], "\n"), true
+	}
+	return "", false
+}
+
+// replaceOutsideCode applies fn to the parts of markdown that are outside
+// fenced code blocks and inline code. Lines not containing the ":::"+keyword
+// marker are passed through untouched.
+func replaceOutsideCode(markdown, keyword string, fn func(segment string) string) string {
+	lines := strings.Split(markdown, "\n")
+	marker := ":::" + keyword
+
+	inBacktickBlock := false
+	inTildeBlock := false
+
+	for i, line := range lines {
+		// Strip blockquote prefixes to detect fences inside blockquotes
+		contentLine := strings.TrimSpace(line)
+		for strings.HasPrefix(contentLine, ">") {
+			contentLine = strings.TrimSpace(strings.TrimPrefix(contentLine, ">"))
+		}
+
+		if strings.HasPrefix(contentLine, "```") && !inTildeBlock {
+			inBacktickBlock = !inBacktickBlock
+			continue
+		}
+		if strings.HasPrefix(contentLine, "~~~") && !inBacktickBlock {
+			inTildeBlock = !inTildeBlock
+			continue
+		}
+		if inBacktickBlock || inTildeBlock || !strings.Contains(line, marker) {
+			continue
+		}
+
+		// Even segments are outside inline code
+		segments := strings.Split(line, "`")
+		for j := range segments {
+			if j%2 == 0 {
+				segments[j] = fn(segments[j])
+			}
+		}
+		lines[i] = strings.Join(segments, "`")
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,75 @@
+package goldext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractIncludes(t *testing.T) {
+	input := strings.Join([]string{
+		"Intro",
+		":::include /ops/escalation#contacts:::",
+		"```",
+		":::include /ignored:::",
+		"```",
+		"Inline `:::include /also-ignored:::` code",
+		":::include team/runbook:::",
+	}, "\n")
+
+	result, includes := ExtractIncludes(input)
+
+	if len(includes) != 2 {
+		t.Fatalf("expected 2 includes, got %d: %+v", len(includes), includes)
+	}
+	if includes[0].Path != "/ops/escalation" || includes[0].Section != "contacts" {
+		t.Errorf("unexpected first include: %+v", includes[0])
+	}
+	if includes[1].Path != "/team/runbook" || includes[1].Section != "" {
+		t.Errorf("unexpected second include: %+v", includes[1])
+	}
+	if !strings.Contains(result, "<!-- INCLUDE_BLOCK_0 -->") || !strings.Contains(result, "<!-- INCLUDE_BLOCK_1 -->") {
+		t.Errorf("placeholders missing from result: %q", result)
+	}
+	if !strings.Contains(result, ":::include /ignored:::") || !strings.Contains(result, "`:::include /also-ignored:::`") {
+		t.Errorf("includes inside code should be preserved: %q", result)
+	}
+}
+
+func TestExtractSection(t *testing.T) {
+	doc := strings.Join([]string{
+		"# Title",
+		"## Contacts",
+		"| Name | Phone |",
+		"### On call",
+		"Pager",
+		"## Other",
+		"Not included",
+	}, "\n")
+
+	section, ok := extractSection(doc, "contacts")
+	if !ok {
+		t.Fatal("expected section to be found")
+	}
+	if !strings.Contains(section, "Pager") || strings.Contains(section, "Not included") {
+		t.Errorf("unexpected section content: %q", section)
+	}
+
+	if _, ok := extractSection(doc, "missing"); ok {
+		t.Error("expected missing section to not be found")
+	}
+}
+
+func TestRenderContextCycleDetection(t *testing.T) {
+	ctx := &RenderContext{DocPath: "/a"}
+	child := ctx.Child("/b")
+
+	if !ctx.IsIncluding("/a") {
+		t.Error("a page including itself should be detected")
+	}
+	if !child.IsIncluding("/a") {
+		t.Error("/a -> /b -> /a should be detected as a cycle")
+	}
+	if child.IsIncluding("/c") {
+		t.Error("/c is not on the include stack")
+	}
+}
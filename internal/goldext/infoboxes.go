@@ -3,152 +3,112 @@ package goldext
 import (
 	"fmt"
 	"regexp"
-	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
-// InfoBoxPreprocessor adds support for GitHub-flavored alerts
+// alertStartRegex matches the first line of a GitHub-flavored alert, "> [!TYPE]"
+var alertStartRegex = regexp.MustCompile(`^>\s*\[!(NOTE|TIP|IMPORTANT|WARNING|CAUTION)\]$`)
+
+// alertStyles maps alert types to their title, CSS class and icon
+var alertStyles = map[string]struct {
+	Title, Class, Icon string
+}{
+	"NOTE":      {"Note", "note", `<i class="fa fa-info-circle" aria-hidden="true"></i>`},
+	"TIP":       {"Tip", "tip", `<i class="fa fa-lightbulb-o" aria-hidden="true"></i>`},
+	"IMPORTANT": {"Important", "important", `<i class="fa fa-exclamation-circle" aria-hidden="true"></i>`},
+	"WARNING":   {"Warning", "warning", `<i class="fa fa-exclamation-triangle" aria-hidden="true"></i>`},
+	"CAUTION":   {"Caution", "caution", `<i class="fa fa-ban" aria-hidden="true"></i>`},
+}
+
+// KindAlert is the node kind of GitHub-flavored alerts
+var KindAlert = ast.NewNodeKind("Alert")
+
+// Alert is a GitHub-flavored alert, a blockquote starting with [!TYPE]
+type Alert struct {
+	ast.BaseBlock
+	AlertType string // NOTE, TIP, IMPORTANT, WARNING or CAUTION
+}
+
+// Kind implements ast.Node.Kind
+func (n *Alert) Kind() ast.NodeKind { return KindAlert }
+
+// Dump implements ast.Node.Dump
+func (n *Alert) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"AlertType": n.AlertType}, nil)
+}
+
+// alertParser adds support for GitHub-flavored alerts
 // Syntax:
 // > [!NOTE]
 // > Content
-func InfoBoxPreprocessor(markdown string, _ string) string {
-	lines := strings.Split(markdown, "\n")
-	var result []string
-
-	var inCodeBlock bool
-	var codeBlockMarker string
-
-	// Regex to detect the start of an alert
-	// Matches "> [!TYPE]" where TYPE is one of the supported types
-	alertStartRegex := regexp.MustCompile(`^\s*>\s*\[!(NOTE|TIP|IMPORTANT|WARNING|CAUTION)\]\s*$`)
-
-	for i := 0; i < len(lines); i++ {
-		line := lines[i]
-		trimmedLine := strings.TrimSpace(line)
-
-		// Track code blocks
-		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
-			if !inCodeBlock {
-				inCodeBlock = true
-				if strings.HasPrefix(trimmedLine, "```") {
-					codeBlockMarker = "```"
-				} else {
-					codeBlockMarker = "~~~"
-				}
-			} else {
-				// Check if it's the closing marker
-				// We need to be careful about nested blocks or length of markers, 
-				// but for this simple preprocessor, checking prefix is usually enough
-				if strings.HasPrefix(trimmedLine, codeBlockMarker) {
-					inCodeBlock = false
-					codeBlockMarker = ""
-				}
-			}
-			result = append(result, line)
-			continue
-		}
+//
+// The alert continues while lines are quoted, like a blockquote.
+type alertParser struct{}
 
-		if inCodeBlock {
-			result = append(result, line)
-			continue
-		}
+func (p *alertParser) Trigger() []byte {
+	return []byte{'>'}
+}
 
-		// Check for alert start
-		matches := alertStartRegex.FindStringSubmatch(trimmedLine)
-		if len(matches) > 1 {
-			alertType := strings.ToUpper(matches[1])
-
-			// Collect content
-			var content []string
-			j := i + 1
-			for ; j < len(lines); j++ {
-				nextLine := lines[j]
-				nextTrimmed := strings.TrimSpace(nextLine)
-
-				// Check if line starts with >
-				if strings.HasPrefix(nextTrimmed, ">") {
-					// Remove the > and optional space
-					// We want to preserve indentation relative to the >
-					// Find where the > is in the original line (to handle indentation before >)
-					idx := strings.Index(nextLine, ">")
-					if idx != -1 {
-						contentLine := nextLine[idx+1:]
-						if strings.HasPrefix(contentLine, " ") {
-							contentLine = contentLine[1:]
-						}
-						content = append(content, contentLine)
-					} else {
-						// Should not happen given HasPrefix check on trimmed, but fallback
-						content = append(content, strings.TrimPrefix(nextTrimmed, ">"))
-					}
-				} else if nextTrimmed == "" {
-					// Empty line. In GitHub alerts, empty lines are usually quoted too ">"
-					// But if the user leaves a blank line, we can treat it as part of the block 
-					// if the next line is also part of the block.
-					// However, standard markdown breaks blockquote on empty line unless lazy.
-					// For this preprocessor, we will stop at empty line to be safe and simple,
-					// unless the user explicitly quoted it with ">".
-					// Actually, let's stop at non-quoted line.
-					break
-				} else {
-					// Non-empty line not starting with >. End of block.
-					break
-				}
-			}
-
-			// Generate HTML
-			html := generateAlertHTML(alertType, content)
-			result = append(result, html)
-
-			// Skip processed lines
-			i = j - 1
-			continue
-		}
+func (p *alertParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	matches := alertStartRegex.FindSubmatch(lineText(reader))
+	if matches == nil {
+		return nil, parser.NoChildren
+	}
+	reader.AdvanceToEOL()
+	return &Alert{AlertType: string(matches[1])}, parser.HasChildren
+}
 
-		result = append(result, line)
+func (p *alertParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, _ := reader.PeekLine()
+	w, pos := util.IndentWidth(line, reader.LineOffset())
+	if w > 3 || pos >= len(line) || line[pos] != '>' {
+		return parser.Close
 	}
 
-	return strings.Join(result, "\n")
+	// Strip the > and one optional space, as for blockquotes
+	pos++
+	if pos >= len(line) || line[pos] == '\n' {
+		reader.Advance(pos)
+		return parser.Continue | parser.HasChildren
+	}
+	reader.Advance(pos)
+	if line[pos] == ' ' || line[pos] == '\t' {
+		padding := 0
+		if line[pos] == '\t' {
+			padding = util.TabWidth(reader.LineOffset()) - 1
+		}
+		reader.AdvanceAndSetPadding(1, padding)
+	}
+	return parser.Continue | parser.HasChildren
 }
 
-func generateAlertHTML(alertType string, content []string) string {
-	var iconHTML string
-	var title string
-	var classSuffix string
-
-	switch alertType {
-	case "NOTE":
-		title = "Note"
-		classSuffix = "note"
-		iconHTML = `<i class="fa fa-info-circle" aria-hidden="true"></i>`
-	case "TIP":
-		title = "Tip"
-		classSuffix = "tip"
-		iconHTML = `<i class="fa fa-lightbulb-o" aria-hidden="true"></i>`
-	case "IMPORTANT":
-		title = "Important"
-		classSuffix = "important"
-		iconHTML = `<i class="fa fa-exclamation-circle" aria-hidden="true"></i>`
-	case "WARNING":
-		title = "Warning"
-		classSuffix = "warning"
-		iconHTML = `<i class="fa fa-exclamation-triangle" aria-hidden="true"></i>`
-	case "CAUTION":
-		title = "Caution"
-		classSuffix = "caution"
-		iconHTML = `<i class="fa fa-ban" aria-hidden="true"></i>`
+func (p *alertParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *alertParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *alertParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+func (r *nodeRenderer) renderAlert(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("</div>\n</div>\n")
+		return ast.WalkContinue, nil
 	}
 
-	html := fmt.Sprintf(`<div class="markdown-alert markdown-alert-%s">
+	style := alertStyles[node.(*Alert).AlertType]
+	fmt.Fprintf(w, `<div class="markdown-alert markdown-alert-%s">
 <p class="markdown-alert-title">
   %s
   %s
 </p>
 <div class="markdown-alert-content">
-
-%s
-
-</div>
-</div>`, classSuffix, iconHTML, title, strings.Join(content, "\n"))
-
-	return html
+`, style.Class, style.Icon, style.Title)
+	return ast.WalkContinue, nil
 }
@@ -198,6 +198,92 @@ func LinkPreprocessor(markdown string, docPath string) string {
 	return joinSections(sections)
 }
 
+// documentLinkRegex matches inline links, [text](url "title")
+var documentLinkRegex = regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+
+// FindDocumentLinks returns the wiki documents linked from markdown, as
+// absolute document paths without anchors. Links inside code are ignored.
+func FindDocumentLinks(markdown string) []string {
+	var links []string
+	seen := make(map[string]bool)
+
+	for _, section := range splitCodeSections(markdown) {
+		if section.isCode {
+			continue
+		}
+		for _, match := range documentLinkRegex.FindAllStringSubmatch(section.content, -1) {
+			path := match[2]
+			if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/static/") || strings.HasPrefix(path, "//") {
+				continue
+			}
+			if idx := strings.IndexAny(path, "#?"); idx != -1 {
+				path = path[:idx]
+			}
+			if unescaped, err := url.PathUnescape(path); err == nil {
+				path = unescaped
+			}
+			path = strings.TrimSuffix(path, "/")
+			if path == "" {
+				path = "/"
+			}
+			if !seen[path] {
+				seen[path] = true
+				links = append(links, path)
+			}
+		}
+	}
+
+	return links
+}
+
+// markdownLinkRegex matches inline links and images, [text](url "title")
+var markdownLinkRegex = regexp.MustCompile(`(!?)\[([^\]]*)\]\(([^)\s]+)((?:\s+"[^"]*")?)\)`)
+
+// RewriteLinks replaces the destination of every inline link and image in
+// markdown outside code with what rewrite returns for it
+func RewriteLinks(markdown string, rewrite func(destination string, image bool) string) string {
+	sections := splitCodeSections(markdown)
+	for i := range sections {
+		if sections[i].isCode {
+			continue
+		}
+		sections[i].content = markdownLinkRegex.ReplaceAllStringFunc(sections[i].content, func(match string) string {
+			parts := markdownLinkRegex.FindStringSubmatch(match)
+			return parts[1] + "[" + parts[2] + "](" + rewrite(parts[3], parts[1] == "!") + parts[4] + ")"
+		})
+	}
+	return joinSections(sections)
+}
+
+// externalLinkRegex matches http(s) links, [text](url "title"), and
+// autolinks, <url>
+var externalLinkRegex = regexp.MustCompile(`\]\((https?://[^)\s]+)(?:\s+"[^"]*")?\)|<(https?://[^>\s]+)>`)
+
+// FindExternalLinks returns the http(s) URLs linked from markdown, both as
+// [text](url) links and <url> autolinks. Links inside code are ignored.
+func FindExternalLinks(markdown string) []string {
+	var links []string
+	seen := make(map[string]bool)
+
+	for _, section := range splitCodeSections(markdown) {
+		if section.isCode {
+			continue
+		}
+		for _, match := range externalLinkRegex.FindAllStringSubmatch(section.content, -1) {
+			link := match[1]
+			if link == "" {
+				link = match[2]
+			}
+			if !seen[link] {
+				seen[link] = true
+				links = append(links, link)
+			}
+		}
+	}
+
+	return links
+}
+
 // isLocalPath returns true if the path is a local file reference
 func isLocalPath(path string) bool {
 	// Skip URLs with schemes (http://, https://, ftp://, etc)
@@ -0,0 +1,268 @@
+package goldext
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"wiki-go/internal/frontmatter"
+	"wiki-go/internal/types"
+)
+
+// navigationProvider returns the wiki's page tree for the page listing
+// shortcodes, set with SetNavigationProvider
+var (
+	navigationMu       sync.RWMutex
+	navigationProvider func() (*types.NavItem, error)
+)
+
+// SetNavigationProvider registers the page tree used by :::children:::,
+// :::siblings::: and :::recent:::. It should return the same tree as the
+// sidebar, before it is filtered for the viewer.
+func SetNavigationProvider(provider func() (*types.NavItem, error)) {
+	navigationMu.Lock()
+	defer navigationMu.Unlock()
+	navigationProvider = provider
+}
+
+// maxListingDepth limits how many levels :::children depth=N::: can show
+const maxListingDepth = 6
+
+// listedPage is a page shown by a listing shortcode
+type listedPage struct {
+	Title       string
+	Description string
+	Path        string
+	ModTime     time.Time
+	Children    []*listedPage
+}
+
+// loadNavigation returns the page tree, or nil if none is available
+func loadNavigation() *types.NavItem {
+	navigationMu.RLock()
+	provider := navigationProvider
+	navigationMu.RUnlock()
+	if provider == nil {
+		return nil
+	}
+	root, err := provider()
+	if err != nil {
+		return nil
+	}
+	return root
+}
+
+// findNavItem returns the item at path in the tree below node
+func findNavItem(node *types.NavItem, path string) *types.NavItem {
+	if node == nil {
+		return nil
+	}
+	if node.Path == path {
+		return node
+	}
+	for _, child := range node.Children {
+		if path == child.Path || strings.HasPrefix(path, child.Path+"/") {
+			return findNavItem(child, path)
+		}
+	}
+	return nil
+}
+
+// listingPath returns the navigation path of a page path, "/" for the root
+func listingPath(path string) string {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	return "/" + strings.ReplaceAll(path, " ", "-")
+}
+
+// listPages returns the children of item the viewer can access, down to
+// depth levels, with titles and descriptions from their frontmatter
+func listPages(item *types.NavItem, depth int, sortBy string, ctx *RenderContext) []*listedPage {
+	var pages []*listedPage
+	for _, child := range item.Children {
+		if !ctx.CheckAccess(child.Path) {
+			continue
+		}
+		page := describePage(child)
+		if depth > 1 {
+			page.Children = listPages(child, depth-1, sortBy, ctx)
+		}
+		pages = append(pages, page)
+	}
+	sortPages(pages, sortBy)
+	return pages
+}
+
+// describePage reads the title, description and modification time of the
+// page behind a navigation item
+func describePage(item *types.NavItem) *listedPage {
+	page := &listedPage{Title: item.Title, Path: item.Path}
+
+	file := includeFilePath(item.Path)
+	if info, err := os.Stat(file); err == nil {
+		page.ModTime = info.ModTime()
+	}
+	if content, err := os.ReadFile(file); err == nil {
+		if metadata, _, ok := frontmatter.Parse(string(content)); ok {
+			if metadata.Title != "" {
+				page.Title = metadata.Title
+			}
+			page.Description = metadata.Description
+		}
+	}
+	return page
+}
+
+// sortPages sorts pages by title, path or modification time (newest
+// first). Any other value keeps the navigation order.
+func sortPages(pages []*listedPage, sortBy string) {
+	switch sortBy {
+	case "title":
+		sort.SliceStable(pages, func(i, j int) bool {
+			return strings.ToLower(pages[i].Title) < strings.ToLower(pages[j].Title)
+		})
+	case "path":
+		sort.SliceStable(pages, func(i, j int) bool { return pages[i].Path < pages[j].Path })
+	case "modified":
+		sort.SliceStable(pages, func(i, j int) bool { return pages[i].ModTime.After(pages[j].ModTime) })
+	}
+}
+
+// renderChildrenShortcode renders :::children::: with optional depth=N,
+// sort=title|path|modified and path=/x, listing the pages below the current
+// page or path
+func renderChildrenShortcode(args string, ctx *RenderContext) (string, bool) {
+	_, params := ParseShortcodeArgs(args)
+
+	path := listingPath(ctx.DocPath)
+	if p, ok := params["path"]; ok {
+		path = listingPath(p)
+	}
+	depth, err := strconv.Atoi(params["depth"])
+	if err != nil || depth < 1 {
+		depth = 1
+	}
+	if depth > maxListingDepth {
+		depth = maxListingDepth
+	}
+
+	var pages []*listedPage
+	if item := findNavItem(loadNavigation(), path); item != nil {
+		pages = listPages(item, depth, params["sort"], ctx)
+	}
+
+	var buf strings.Builder
+	renderPageList(&buf, "wiki-pages children", pages, "No subpages found.")
+	return buf.String(), true
+}
+
+// renderSiblingsShortcode renders :::siblings::: with optional
+// sort=title|path|modified, listing the other pages under the current
+// page's parent
+func renderSiblingsShortcode(args string, ctx *RenderContext) (string, bool) {
+	_, params := ParseShortcodeArgs(args)
+
+	path := listingPath(ctx.DocPath)
+	parent := "/"
+	if i := strings.LastIndex(path, "/"); i > 0 {
+		parent = path[:i]
+	}
+
+	var pages []*listedPage
+	if path != "/" {
+		if item := findNavItem(loadNavigation(), parent); item != nil {
+			for _, page := range listPages(item, 1, params["sort"], ctx) {
+				if page.Path != path {
+					pages = append(pages, page)
+				}
+			}
+		}
+	}
+
+	var buf strings.Builder
+	renderPageList(&buf, "wiki-pages siblings", pages, "No sibling pages found.")
+	return buf.String(), true
+}
+
+// renderRecentShortcode renders :::recent::: with optional path=/x and
+// count=N, listing the most recently modified pages below path
+func renderRecentShortcode(args string, ctx *RenderContext) (string, bool) {
+	_, params := ParseShortcodeArgs(args)
+
+	count, err := strconv.Atoi(params["count"])
+	if err != nil || count <= 0 {
+		count = 10
+	}
+
+	var pages []*listedPage
+	if item := findNavItem(loadNavigation(), listingPath(params["path"])); item != nil {
+		pages = flattenPages(listPages(item, maxListingDepth, "", ctx))
+	}
+	sortPages(pages, "modified")
+	if len(pages) > count {
+		pages = pages[:count]
+	}
+
+	var buf strings.Builder
+	buf.WriteString("<div class=\"wiki-stats recent-edits\">\n")
+	buf.WriteString("<h4>Recently Edited Documents</h4>\n")
+	if len(pages) == 0 {
+		buf.WriteString("<p>No recently edited documents found.</p>\n")
+	} else {
+		buf.WriteString("<ul>\n")
+		for _, page := range pages {
+			buf.WriteString("<li>\n")
+			buf.WriteString("  <div class=\"doc-info\">\n")
+			buf.WriteString(fmt.Sprintf("    <a href=\"%s\">%s</a>\n", html.EscapeString(page.Path), html.EscapeString(page.Title)))
+			buf.WriteString(fmt.Sprintf("    <span class=\"doc-path\">%s</span>\n", html.EscapeString(page.Path)))
+			buf.WriteString("  </div>\n")
+			buf.WriteString(fmt.Sprintf("  <span class=\"edit-date\">%s</span>\n", formatModTime(page.ModTime, "2006-01-02 15:04")))
+			buf.WriteString("</li>\n")
+		}
+		buf.WriteString("</ul>\n")
+	}
+	buf.WriteString("</div>\n")
+	return buf.String(), true
+}
+
+// flattenPages returns pages and all their descendants in one list
+func flattenPages(pages []*listedPage) []*listedPage {
+	var all []*listedPage
+	for _, page := range pages {
+		all = append(all, page)
+		all = append(all, flattenPages(page.Children)...)
+	}
+	return all
+}
+
+// renderPageList renders pages as nested lists inside a div with class
+func renderPageList(w *strings.Builder, class string, pages []*listedPage, empty string) {
+	w.WriteString("<div class=\"" + class + "\">\n")
+	if len(pages) == 0 {
+		w.WriteString("<p>" + empty + "</p>\n")
+	} else {
+		writePageItems(w, pages)
+	}
+	w.WriteString("</div>\n")
+}
+
+// writePageItems writes one level of a page list
+func writePageItems(w *strings.Builder, pages []*listedPage) {
+	w.WriteString("<ul>\n")
+	for _, page := range pages {
+		w.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a>", html.EscapeString(page.Path), html.EscapeString(page.Title)))
+		if page.Description != "" {
+			w.WriteString(" <span class=\"page-description\">" + html.EscapeString(page.Description) + "</span>")
+		}
+		if len(page.Children) > 0 {
+			w.WriteString("\n")
+			writePageItems(w, page.Children)
+		}
+		w.WriteString("</li>\n")
+	}
+	w.WriteString("</ul>\n")
+}
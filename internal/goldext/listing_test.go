@@ -0,0 +1,82 @@
+package goldext
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"wiki-go/internal/types"
+)
+
+func TestListingShortcodes(t *testing.T) {
+	t.Chdir(t.TempDir())
+	pages := map[string]string{
+		"guide/zeta":         "---\ntitle: Alpha Guide\ndescription: Start here\n---\n# Zeta\n",
+		"guide/beta":         "# Beta\n",
+		"guide/beta/nested":  "# Nested\n",
+		"guide/secret":       "# Secret\n",
+		"guide/zeta/deep":    "# Deep\n",
+		"guide/zeta/deep/er": "# Deeper\n",
+	}
+	modTime := time.Now().Add(-time.Hour)
+	for path, content := range pages {
+		file := filepath.Join("data", "documents", path, "document.md")
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(file, modTime, modTime)
+	}
+	// beta was edited last
+	os.Chtimes(filepath.Join("data", "documents", "guide", "beta", "document.md"), time.Now(), time.Now())
+
+	item := func(path, title string, children ...*types.NavItem) *types.NavItem {
+		return &types.NavItem{Title: title, Path: path, IsDir: true, Children: children}
+	}
+	tree := item("/", "Wiki-Go",
+		item("/guide", "Guide",
+			item("/guide/beta", "Beta", item("/guide/beta/nested", "Nested")),
+			item("/guide/secret", "Secret"),
+			item("/guide/zeta", "Zeta", item("/guide/zeta/deep", "Deep", item("/guide/zeta/deep/er", "Deeper"))),
+		),
+	)
+	SetNavigationProvider(func() (*types.NavItem, error) { return tree, nil })
+	defer SetNavigationProvider(nil)
+
+	ctx := &RenderContext{
+		DocPath:   "guide/beta",
+		CanAccess: func(path string) bool { return !strings.HasPrefix(path, "/guide/secret") },
+	}
+
+	children, _ := renderChildrenShortcode("path=/guide depth=2 sort=title", ctx)
+	want := "<div class=\"wiki-pages children\">\n<ul>\n" +
+		"<li><a href=\"/guide/zeta\">Alpha Guide</a> <span class=\"page-description\">Start here</span>\n<ul>\n<li><a href=\"/guide/zeta/deep\">Deep</a></li>\n</ul>\n</li>\n" +
+		"<li><a href=\"/guide/beta\">Beta</a>\n<ul>\n<li><a href=\"/guide/beta/nested\">Nested</a></li>\n</ul>\n</li>\n" +
+		"</ul>\n</div>\n"
+	if children != want {
+		t.Errorf("children: expected %q, got %q", want, children)
+	}
+
+	siblings, _ := renderSiblingsShortcode("", ctx)
+	want = "<div class=\"wiki-pages siblings\">\n<ul>\n<li><a href=\"/guide/zeta\">Alpha Guide</a> <span class=\"page-description\">Start here</span></li>\n</ul>\n</div>\n"
+	if siblings != want {
+		t.Errorf("siblings: expected %q, got %q", want, siblings)
+	}
+
+	recent, _ := renderRecentShortcode("path=/guide count=2", ctx)
+	if strings.Count(recent, "<li>") != 2 || !strings.Contains(recent, "<a href=\"/guide/beta\">Beta</a>") {
+		t.Errorf("recent: expected beta among two pages, got %q", recent)
+	}
+	if strings.Contains(recent, "secret") {
+		t.Errorf("recent: listed a page the viewer can't access: %q", recent)
+	}
+
+	none, _ := renderChildrenShortcode("", &RenderContext{DocPath: "guide/beta/nested"})
+	if !strings.Contains(none, "No subpages found.") {
+		t.Errorf("children: expected no subpages, got %q", none)
+	}
+}
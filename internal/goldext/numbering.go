@@ -0,0 +1,146 @@
+package goldext
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// sectionRefPrefix starts the key of a cross-reference to a heading,
+// [@sec:label]
+const sectionRefPrefix = "sec:"
+
+// KindHeadingNumber is the node kind of section numbers
+var KindHeadingNumber = ast.NewNodeKind("HeadingNumber")
+
+// HeadingNumber is the section number at the start of a numbered heading,
+// such as 3.2.1
+type HeadingNumber struct {
+	ast.BaseInline
+	Number string
+}
+
+// Kind implements ast.Node.Kind
+func (n *HeadingNumber) Kind() ast.NodeKind { return KindHeadingNumber }
+
+// Dump implements ast.Node.Dump
+func (n *HeadingNumber) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Number": n.Number}, nil)
+}
+
+func (r *nodeRenderer) renderHeadingNumber(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(headingNumberHTML(node.(*HeadingNumber).Number))
+	}
+	return ast.WalkContinue, nil
+}
+
+// headingNumberHTML returns the HTML of a section number before a heading's
+// text
+func headingNumberHTML(number string) string {
+	return `<span class="heading-number">` + number + `</span> `
+}
+
+// KindSectionRef is the node kind of cross-references to headings
+var KindSectionRef = ast.NewNodeKind("SectionRef")
+
+// SectionRef is a cross-reference to a heading, [@sec:label], rendered as
+// a link with the heading's number and title once the headings are known
+type SectionRef struct {
+	ast.BaseInline
+	Label string
+	HTML  string
+}
+
+// Kind implements ast.Node.Kind
+func (n *SectionRef) Kind() ast.NodeKind { return KindSectionRef }
+
+// Dump implements ast.Node.Dump
+func (n *SectionRef) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Label": n.Label}, nil)
+}
+
+func (r *nodeRenderer) renderSectionRef(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(node.(*SectionRef).HTML)
+	}
+	return ast.WalkContinue, nil
+}
+
+// numberHeadings numbers the outline headings 1, 1.1, 1.1.1 and so on,
+// starting at the highest level used. A lone level 1 heading at the top is
+// the page title and is not numbered.
+func numberHeadings(headings []*ast.Heading, entries []tocEntry) {
+	if len(headings) == 0 {
+		return
+	}
+	first := 0
+	if headings[0].Level == 1 {
+		titles := 0
+		for _, h := range headings {
+			if h.Level == 1 {
+				titles++
+			}
+		}
+		if titles == 1 {
+			first = 1
+		}
+	}
+	if first >= len(headings) {
+		return
+	}
+
+	base := 6
+	for _, h := range headings[first:] {
+		base = min(base, h.Level)
+	}
+
+	var counters [6]int
+	for i := first; i < len(headings); i++ {
+		depth := headings[i].Level - base
+		counters[depth]++
+		for j := depth + 1; j < len(counters); j++ {
+			counters[j] = 0
+		}
+		parts := make([]string, depth+1)
+		for j := range parts {
+			parts[j] = strconv.Itoa(counters[j])
+		}
+
+		number := strings.Join(parts, ".")
+		headings[i].InsertBefore(headings[i], headings[i].FirstChild(), &HeadingNumber{Number: number})
+		entries[i].Number = number
+		entries[i].Text = headingNumberHTML(number) + entries[i].Text
+	}
+}
+
+// resolveSectionRefs renders cross-references as links to the headings
+// they name, showing the section number and title: §3.2 Installation
+func resolveSectionRefs(refs []*SectionRef, entries []tocEntry) {
+	byID := make(map[string]tocEntry, len(entries))
+	for _, entry := range entries {
+		byID[entry.ID] = entry
+	}
+
+	for _, ref := range refs {
+		entry, ok := byID[sectionRefPrefix+ref.Label]
+		if !ok {
+			entry, ok = byID[ref.Label]
+		}
+		if !ok {
+			ref.HTML = fmt.Sprintf(`<span class="citation-missing" title="No heading with this label">@%s%s</span>`,
+				sectionRefPrefix, html.EscapeString(ref.Label))
+			continue
+		}
+
+		label := entry.Title
+		if entry.Number != "" {
+			label = "§" + entry.Number + " " + label
+		}
+		ref.HTML = fmt.Sprintf(`<a class="section-ref" href="#%s">%s</a>`, html.EscapeString(entry.ID), label)
+	}
+}
@@ -0,0 +1,58 @@
+package goldext
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+)
+
+func TestHeadingNumbering(t *testing.T) {
+	render := func(ctx *RenderContext, markdown string) string {
+		md := goldmark.New(
+			goldmark.WithExtensions(NewExtension(ctx)),
+			goldmark.WithParserOptions(parser.WithAutoHeadingID(), parser.WithAttribute()),
+		)
+		var buf bytes.Buffer
+		if err := md.Convert([]byte(markdown), &buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	source := "# Specification\n\n[toc]\n\n## Scope\n\n## Install {#sec:install}\n\n### On Linux {#linux}\n\nSee [@sec:install], [@sec:linux] and [@sec:nope].\n\n## Usage\n"
+
+	got := render(&RenderContext{NumberHeadings: true}, source)
+	for _, want := range []string{
+		"<h1 id=\"specification\">Specification <a class=\"heading-anchor\"",
+		"<h2 id=\"scope\"><span class=\"heading-number\">1</span> Scope <a",
+		"<h2 id=\"sec:install\"><span class=\"heading-number\">2</span> Install <a",
+		"<h3 id=\"linux\"><span class=\"heading-number\">2.1</span> On Linux <a",
+		"<h2 id=\"usage\"><span class=\"heading-number\">3</span> Usage <a",
+		"<li><a href=\"#linux\"><span class=\"heading-number\">2.1</span> On Linux</a>",
+		"See <a class=\"section-ref\" href=\"#sec:install\">§2 Install</a>, <a class=\"section-ref\" href=\"#linux\">§2.1 On Linux</a> and " +
+			"<span class=\"citation-missing\" title=\"No heading with this label\">@sec:nope</span>.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+
+	got = render(&RenderContext{}, source)
+	if strings.Contains(got, "heading-number") {
+		t.Errorf("expected no numbers without number_headings, got %q", got)
+	}
+	if want := "See <a class=\"section-ref\" href=\"#sec:install\">Install</a>"; !strings.Contains(got, want) {
+		t.Errorf("expected %q in %q", want, got)
+	}
+
+	// Several level 1 headings are numbered themselves
+	got = render(&RenderContext{NumberHeadings: true}, "# One\n\n## Sub\n\n# Two\n")
+	for _, want := range []string{"<span class=\"heading-number\">1</span> One", "<span class=\"heading-number\">1.1</span> Sub", "<span class=\"heading-number\">2</span> Two"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package goldext
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"log"
 	"os"
@@ -12,6 +13,11 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
 // wikiLocation is the resolved *time.Location for the configured wiki
@@ -57,106 +63,158 @@ func formatModTime(t time.Time, format string) string {
 	return t.In(loc).Format(format)
 }
 
-// ShortcodesPreprocessor processes shortcodes in markdown text
-// Supports: :::year:::, :::stats count=*:::, :::stats recent=N:::
-// Avoids processing shortcodes inside code blocks
-func ShortcodesPreprocessor(markdown string, _ string) string {
-	// Split markdown into lines for processing
-	lines := strings.Split(markdown, "\n")
-	processedLines := make([]string, 0, len(lines))
-
-	// State tracking for code blocks
-	inBacktickBlock := false
-	inTildeBlock := false
-
-	// Process each line
-	for _, line := range lines {
-		// Check for code block markers
-		trimmedLine := strings.TrimSpace(line)
-
-		// Strip blockquote prefix(es) to detect code blocks inside blockquotes
-		// e.g., "> ```" or "> > ```" should be detected as code block markers
-		contentLine := trimmedLine
-		for strings.HasPrefix(contentLine, ">") {
-			contentLine = strings.TrimSpace(strings.TrimPrefix(contentLine, ">"))
-		}
+// shortcodeLineRegex matches a shortcode on a line of its own, :::name args:::
+var shortcodeLineRegex = regexp.MustCompile(`^:::([a-z][a-z0-9-]*)(?:\s+([^:]*?))?\s*:::$`)
 
-		if strings.HasPrefix(contentLine, "```") {
-			inBacktickBlock = !inBacktickBlock
-			processedLines = append(processedLines, line)
-			continue
-		}
+// shortcodeInlineRegex matches a shortcode at the start of text
+var shortcodeInlineRegex = regexp.MustCompile(`^:::([a-z][a-z0-9-]*)(?:\s+([^:]*?))?\s*:::`)
 
-		if strings.HasPrefix(contentLine, "~~~") {
-			inTildeBlock = !inTildeBlock
-			processedLines = append(processedLines, line)
-			continue
-		}
+// statsArgsRegex matches the arguments of :::stats count=X::: and :::stats recent=N:::
+var statsArgsRegex = regexp.MustCompile(`^(recent|count)=([^:]+)$`)
 
-		// Skip processing if in a code block
-		if inBacktickBlock || inTildeBlock {
-			processedLines = append(processedLines, line)
-			continue
+// BlockShortcode renders a shortcode that stands on a line of its own from
+// its arguments. It returns false if the arguments are invalid, in which case
+// the line is rendered as text.
+type BlockShortcode func(args string, ctx *RenderContext) (string, bool)
+
+// blockShortcodes holds the block shortcodes by name
+var blockShortcodes = map[string]BlockShortcode{
+	"stats":    renderStatsShortcode,
+	"children": renderChildrenShortcode,
+	"siblings": renderSiblingsShortcode,
+	"recent":   renderRecentShortcode,
+	"table":    renderTableShortcode,
+
+	"bibliography": renderBibliographyShortcode,
+}
+
+// KindShortcode is the node kind of block shortcodes
+var KindShortcode = ast.NewNodeKind("Shortcode")
+
+// Shortcode is a block shortcode such as :::stats count=*::: and the HTML it
+// rendered to
+type Shortcode struct {
+	ast.BaseBlock
+	Name string
+	HTML string
+}
+
+// Kind implements ast.Node.Kind
+func (n *Shortcode) Kind() ast.NodeKind { return KindShortcode }
+
+// Dump implements ast.Node.Dump
+func (n *Shortcode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Name": n.Name}, nil)
+}
+
+// shortcodeBlockParser processes shortcodes that stand on a line of their
+// own, such as :::stats count=*::: and :::stats recent=N:::, and user
+// shortcodes on a line of their own
+type shortcodeBlockParser struct {
+	ctx *RenderContext
+}
+
+func (p *shortcodeBlockParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+func (p *shortcodeBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	matches := shortcodeLineRegex.FindSubmatch(lineText(reader))
+	if matches == nil {
+		return nil, parser.NoChildren
+	}
+	render, ok := blockShortcodes[string(matches[1])]
+	if !ok {
+		render = func(args string, ctx *RenderContext) (string, bool) {
+			html, ok := renderUserShortcode(string(matches[1]), args, ctx)
+			return html + "\n", ok
 		}
+	}
+	html, ok := render(string(matches[2]), p.ctx)
+	if !ok {
+		return nil, parser.NoChildren
+	}
 
-		// Process shortcodes with respect to inline code blocks
-		if strings.Contains(line, ":::year:::") || strings.Contains(line, ":::stats") {
-			// Process each segment of the line, preserving inline code
-			var processedLine string
-			segments := strings.Split(line, "`")
-
-			for i, segment := range segments {
-				// Even segments (0, 2, 4...) are outside inline code
-				if i%2 == 0 {
-					// Process :::year::: shortcode
-					if strings.Contains(segment, ":::year:::") {
-						currentYear := strconv.Itoa(time.Now().Year())
-						segment = strings.ReplaceAll(segment, ":::year:::", currentYear)
-					}
-
-					// Match stats shortcode pattern
-					if strings.Contains(segment, ":::stats") {
-						statsRegex := regexp.MustCompile(`:::stats\s+(recent|count)=([^:]+):::`)
-						segment = statsRegex.ReplaceAllStringFunc(segment, func(match string) string {
-							params := statsRegex.FindStringSubmatch(match)
-							if len(params) < 3 {
-								return match
-							}
-
-							shortcodeType := params[1]
-							shortcodeValue := params[2]
-
-							if shortcodeType == "count" {
-								var buf strings.Builder
-								renderDocumentCount(&buf, shortcodeValue)
-								return buf.String()
-							} else if shortcodeType == "recent" {
-								count, err := strconv.Atoi(shortcodeValue)
-								if err != nil || count <= 0 {
-									count = 5 // Default to 5 if invalid
-								}
-								var buf strings.Builder
-								renderRecentEdits(&buf, count)
-								return buf.String()
-							}
-
-							return match
-						})
-					}
-					processedLine += segment
-				} else {
-					// Odd segments (1, 3, 5...) are inside inline code - preserve them
-					processedLine += "`" + segment + "`"
-				}
-			}
+	reader.AdvanceToEOL()
+	return &Shortcode{Name: string(matches[1]), HTML: html}, parser.NoChildren
+}
+
+func (p *shortcodeBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	return parser.Close
+}
 
-			processedLines = append(processedLines, processedLine)
-		} else {
-			processedLines = append(processedLines, line)
+func (p *shortcodeBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *shortcodeBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *shortcodeBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+func (r *nodeRenderer) renderShortcode(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(node.(*Shortcode).HTML)
+	}
+	return ast.WalkContinue, nil
+}
+
+// shortcodeInlineParser processes shortcodes within text: :::year:::,
+// :::var name::: and user shortcodes
+type shortcodeInlineParser struct {
+	ctx *RenderContext
+}
+
+func (p *shortcodeInlineParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+func (p *shortcodeInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if bytes.HasPrefix(line, []byte(":::year:::")) {
+		block.Advance(len(":::year:::"))
+		return ast.NewString([]byte(strconv.Itoa(time.Now().Year())))
+	}
+
+	matches := shortcodeInlineRegex.FindSubmatch(line)
+	if matches == nil {
+		return nil
+	}
+	if string(matches[1]) == "var" {
+		node, ok := variableNode(string(matches[2]), p.ctx)
+		if !ok {
+			return nil
 		}
+		block.Advance(len(matches[0]))
+		return node
+	}
+	html, ok := renderUserShortcode(string(matches[1]), string(matches[2]), p.ctx)
+	if !ok {
+		return nil
+	}
+	block.Advance(len(matches[0]))
+	return &InlineShortcode{Name: string(matches[1]), HTML: html}
+}
+
+// renderStatsShortcode renders :::stats count=X::: and :::stats recent=N:::
+func renderStatsShortcode(args string, _ *RenderContext) (string, bool) {
+	params := statsArgsRegex.FindStringSubmatch(args)
+	if params == nil {
+		return "", false
 	}
 
-	return strings.Join(processedLines, "\n")
+	var buf strings.Builder
+	if params[1] == "count" {
+		renderDocumentCount(&buf, params[2])
+	} else {
+		count, err := strconv.Atoi(params[2])
+		if err != nil || count <= 0 {
+			count = 5 // Default to 5 if invalid
+		}
+		renderRecentEdits(&buf, count)
+	}
+	return buf.String(), true
 }
 
 // Document represents a document in the wiki
@@ -1,118 +1,84 @@
 package goldext
 
 import (
-	"strings"
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
-// SubscriptPreprocessor adds support for ~subscript~ syntax
-func SubscriptPreprocessor(markdown string, _ string) string {
-	// Split markdown into lines for processing
-	lines := strings.Split(markdown, "\n")
-	processedLines := make([]string, 0, len(lines))
+// KindSubscript is the node kind of ~subscript~ text
+var KindSubscript = ast.NewNodeKind("Subscript")
 
-	// State tracking for code blocks and math blocks
-	inBacktickBlock := false
-	inTildeBlock := false
-	inMathBlock := false // For $$ math blocks
-
-	// Process each line
-	for _, line := range lines {
-		// Check for code block markers
-		trimmedLine := strings.TrimSpace(line)
+// Subscript is ~subscript~ text, rendered as <sub>
+type Subscript struct {
+	ast.BaseInline
+}
 
-		// Strip blockquote prefix(es) to detect code blocks inside blockquotes
-		// e.g., "> ```" or "> > ```" should be detected as code block markers
-		contentLine := trimmedLine
-		for strings.HasPrefix(contentLine, ">") {
-			contentLine = strings.TrimSpace(strings.TrimPrefix(contentLine, ">"))
-		}
+// Kind implements ast.Node.Kind
+func (n *Subscript) Kind() ast.NodeKind { return KindSubscript }
 
-		// Check for math block markers ($$)
-		if strings.Contains(contentLine, "$$") {
-			// Toggle math block state if we have an odd number of $$ markers
-			count := strings.Count(contentLine, "$$")
-			if count%2 != 0 {
-				inMathBlock = !inMathBlock
-			}
-			processedLines = append(processedLines, line)
-			continue
-		}
+// Dump implements ast.Node.Dump
+func (n *Subscript) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
 
-		if strings.HasPrefix(contentLine, "```") {
-			inBacktickBlock = !inBacktickBlock
-			processedLines = append(processedLines, line)
-			continue
-		}
+// tildeDelimiter pairs ~ delimiters with ~ and ~~ with ~~, so that
+// ~subscript~ and ~~strikethrough~~ can't be mixed up
+type tildeDelimiter struct{}
 
-		if strings.HasPrefix(contentLine, "~~~") {
-			inTildeBlock = !inTildeBlock
-			processedLines = append(processedLines, line)
-			continue
-		}
+func (d *tildeDelimiter) IsDelimiter(b byte) bool {
+	return b == '~'
+}
 
-		// Skip processing if in a code block or math block
-		if inBacktickBlock || inTildeBlock || inMathBlock {
-			processedLines = append(processedLines, line)
-			continue
-		}
+func (d *tildeDelimiter) CanOpenCloser(opener, closer *parser.Delimiter) bool {
+	return opener.Char == closer.Char && opener.OriginalLength == closer.OriginalLength
+}
 
-		// Process subscript, being careful to distinguish from strikethrough
-		if strings.Contains(line, "~") {
-			// Process the line character by character to handle single tildes vs double tildes
-			var inCode bool
-			var inInlineMath bool
-			var result strings.Builder
+func (d *tildeDelimiter) OnMatch(consumes int) ast.Node {
+	if consumes == 2 {
+		return extast.NewStrikethrough()
+	}
+	return &Subscript{}
+}
 
-			for i := 0; i < len(line); i++ {
-				// Handle inline code
-				if line[i] == '`' {
-					inCode = !inCode
-					result.WriteByte('`')
-					// Handle inline math ($)
-				} else if line[i] == '$' && !inCode {
-					inInlineMath = !inInlineMath
-					result.WriteByte('$')
-					// Process subscript only when not in code or math
-				} else if line[i] == '~' && !inCode && !inInlineMath {
-					// Check if it's a double tilde (~~) for strikethrough
-					if i+1 < len(line) && line[i+1] == '~' {
-						// It's strikethrough, write both tildes
-						result.WriteString("~~")
-						i++
-					} else {
-						// Check for a closing single tilde
-						end := -1
-						for j := i + 1; j < len(line); j++ {
-							if line[j] == '~' && (j+1 >= len(line) || line[j+1] != '~') {
-								end = j
-								break
-							}
-						}
+// tildeParser adds support for ~subscript~ syntax next to ~~strikethrough~~,
+// taking over the tildes from goldmark's strikethrough parser. Tildes
+// inside MathJax math are left alone.
+type tildeParser struct{}
 
-						if end != -1 {
-							// Extract the text and wrap in <sub> tags
-							subText := line[i+1 : end]
-							result.WriteString("<sub>")
-							result.WriteString(subText)
-							result.WriteString("</sub>")
+func (p *tildeParser) Trigger() []byte {
+	return []byte{'~'}
+}
 
-							// Skip past the processed text
-							i = end
-						} else {
-							// No closing tilde, write as-is
-							result.WriteByte('~')
-						}
-					}
-				} else {
-					result.WriteByte(line[i])
-				}
-			}
+func (p *tildeParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	before := block.PrecendingCharacter()
+	line, segment := block.PeekLine()
+	node := parser.ScanDelimiter(line, before, 1, &tildeDelimiter{})
+	if node == nil || node.OriginalLength > 2 || before == '~' {
+		return nil
+	}
 
-			processedLines = append(processedLines, result.String())
-		} else {
-			processedLines = append(processedLines, line)
-		}
+	// Keep math as plain text rather than letting the strikethrough
+	// parser have it
+	if inMath(parent, block) {
+		text := ast.NewTextSegment(segment.WithStop(segment.Start + node.OriginalLength))
+		block.Advance(node.OriginalLength)
+		return text
 	}
 
-	return strings.Join(processedLines, "\n")
+	node.Segment = segment.WithStop(segment.Start + node.OriginalLength)
+	block.Advance(node.OriginalLength)
+	pc.PushDelimiter(node)
+	return node
+}
+
+func (r *nodeRenderer) renderSubscript(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString("<sub>")
+	} else {
+		_, _ = w.WriteString("</sub>")
+	}
+	return ast.WalkContinue, nil
 }
@@ -1,117 +1,74 @@
 package goldext
 
 import (
-	"strings"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
-// SuperscriptPreprocessor adds support for ^superscript^ syntax
-func SuperscriptPreprocessor(markdown string, _ string) string {
-	// Split markdown into lines for processing
-	lines := strings.Split(markdown, "\n")
-	processedLines := make([]string, 0, len(lines))
+// KindSuperscript is the node kind of ^superscript^ text
+var KindSuperscript = ast.NewNodeKind("Superscript")
 
-	// State tracking for code blocks and math blocks
-	inBacktickBlock := false
-	inTildeBlock := false
-	inMathBlock := false // For $$ math blocks
+// Superscript is ^superscript^ text, rendered as <sup>
+type Superscript struct {
+	ast.BaseInline
+}
 
-	// Process each line
-	for _, line := range lines {
-		// Check for code block markers
-		trimmedLine := strings.TrimSpace(line)
+// Kind implements ast.Node.Kind
+func (n *Superscript) Kind() ast.NodeKind { return KindSuperscript }
 
-		// Strip blockquote prefix(es) to detect code blocks inside blockquotes
-		// e.g., "> ```" or "> > ```" should be detected as code block markers
-		contentLine := trimmedLine
-		for strings.HasPrefix(contentLine, ">") {
-			contentLine = strings.TrimSpace(strings.TrimPrefix(contentLine, ">"))
-		}
+// Dump implements ast.Node.Dump
+func (n *Superscript) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
 
-		// Check for math block markers ($$)
-		if strings.Contains(contentLine, "$$") {
-			// Toggle math block state if we have an odd number of $$ markers
-			count := strings.Count(contentLine, "$$")
-			if count%2 != 0 {
-				inMathBlock = !inMathBlock
-			}
-			processedLines = append(processedLines, line)
-			continue
-		}
+// superscriptDelimiter pairs ^ delimiters
+type superscriptDelimiter struct{}
 
-		if strings.HasPrefix(contentLine, "```") {
-			inBacktickBlock = !inBacktickBlock
-			processedLines = append(processedLines, line)
-			continue
-		}
+func (d *superscriptDelimiter) IsDelimiter(b byte) bool {
+	return b == '^'
+}
 
-		if strings.HasPrefix(contentLine, "~~~") {
-			inTildeBlock = !inTildeBlock
-			processedLines = append(processedLines, line)
-			continue
-		}
+func (d *superscriptDelimiter) CanOpenCloser(opener, closer *parser.Delimiter) bool {
+	return opener.Char == closer.Char
+}
 
-		// Skip processing if in a code block or math block
-		if inBacktickBlock || inTildeBlock || inMathBlock {
-			processedLines = append(processedLines, line)
-			continue
-		}
+func (d *superscriptDelimiter) OnMatch(consumes int) ast.Node {
+	return &Superscript{}
+}
 
-		// Process superscript
-		if strings.Contains(line, "^") {
-			// Match text between carets, avoiding inline code and inline math
-			var inCode bool
-			var inInlineMath bool
-			var result strings.Builder
+// superscriptParser adds support for ^superscript^ syntax. Footnote
+// references like [^1] and carets inside MathJax math are left alone.
+type superscriptParser struct{}
 
-			for i := 0; i < len(line); i++ {
-				// Handle inline code
-				if line[i] == '`' {
-					inCode = !inCode
-					result.WriteByte('`')
-					// Handle inline math ($)
-				} else if line[i] == '$' && !inCode {
-					inInlineMath = !inInlineMath
-					result.WriteByte('$')
-					// Process superscript only when not in code or math
-				} else if line[i] == '^' && !inCode && !inInlineMath {
-					// Skip if this is a footnote reference [^...]
-					if i > 0 && line[i-1] == '[' {
-						result.WriteByte('^')
-						continue
-					}
+func (p *superscriptParser) Trigger() []byte {
+	return []byte{'^'}
+}
 
-					// Find the closing caret
-					end := -1
-					for j := i + 1; j < len(line); j++ {
-						if line[j] == '^' && j > i+1 {
-							end = j
-							break
-						}
-					}
+func (p *superscriptParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	before := block.PrecendingCharacter()
+	if before == '[' || before == '^' || inMath(parent, block) {
+		return nil
+	}
 
-					if end != -1 {
-						// Extract the text and wrap in <sup> tags
-						superText := line[i+1 : end]
-						result.WriteString("<sup>")
-						result.WriteString(superText)
-						result.WriteString("</sup>")
+	line, segment := block.PeekLine()
+	node := parser.ScanDelimiter(line, before, 1, &superscriptDelimiter{})
+	if node == nil || node.OriginalLength != 1 {
+		return nil
+	}
 
-						// Skip past the processed text
-						i = end
-					} else {
-						// No closing caret, write as-is
-						result.WriteByte('^')
-					}
-				} else {
-					result.WriteByte(line[i])
-				}
-			}
+	node.Segment = segment.WithStop(segment.Start + node.OriginalLength)
+	block.Advance(node.OriginalLength)
+	pc.PushDelimiter(node)
+	return node
+}
 
-			processedLines = append(processedLines, result.String())
-		} else {
-			processedLines = append(processedLines, line)
-		}
+func (r *nodeRenderer) renderSuperscript(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString("<sup>")
+	} else {
+		_, _ = w.WriteString("</sup>")
 	}
-
-	return strings.Join(processedLines, "\n")
+	return ast.WalkContinue, nil
 }
@@ -1,10 +1,25 @@
 package goldext
 
 import (
+	"bytes"
 	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
 )
 
-func TestSuperscriptPreprocessor(t *testing.T) {
+// renderExtension renders markdown with the GFM extensions and Extension
+func renderExtension(t *testing.T, markdown string) string {
+	t.Helper()
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM, extension.Footnote, NewExtension(nil)))
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(markdown), &buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestSuperscript(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    string
@@ -13,31 +28,107 @@ func TestSuperscriptPreprocessor(t *testing.T) {
 		{
 			name:     "Basic superscript",
 			input:    "This is a ^test^ of superscript.",
-			expected: "This is a <sup>test</sup> of superscript.",
+			expected: "<p>This is a <sup>test</sup> of superscript.</p>\n",
 		},
 		{
 			name:     "Multiple superscripts",
 			input:    "H^2^O and E=mc^2^ are formulas.",
-			expected: "H<sup>2</sup>O and E=mc<sup>2</sup> are formulas.",
+			expected: "<p>H<sup>2</sup>O and E=mc<sup>2</sup> are formulas.</p>\n",
 		},
 		{
 			name:     "Footnote reference",
 			input:    "This is a footnote[^1] reference.",
-			expected: "This is a footnote[^1] reference.",
+			expected: "<p>This is a footnote[^1] reference.</p>\n",
 		},
 		{
 			name:     "Mixed with footnotes",
 			input:    "This has a superscript^2^ and a footnote[^1].",
-			expected: "This has a superscript<sup>2</sup> and a footnote[^1].",
+			expected: "<p>This has a superscript<sup>2</sup> and a footnote[^1].</p>\n",
+		},
+		{
+			name:     "Inline math",
+			input:    "Math $x^2^$ is left to MathJax.",
+			expected: "<p>Math $x^2^$ is left to MathJax.</p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := renderExtension(t, tt.input)
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSubscriptAndStrikethrough(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Subscript",
+			input:    "H~2~O",
+			expected: "<p>H<sub>2</sub>O</p>\n",
+		},
+		{
+			name:     "Strikethrough next to subscript",
+			input:    "~~gone~~ and CO~2~",
+			expected: "<p><del>gone</del> and CO<sub>2</sub></p>\n",
+		},
+		{
+			name:     "Unmatched lengths",
+			input:    "~~a~ b",
+			expected: "<p>~~a~ b</p>\n",
+		},
+		{
+			name:     "Inline math",
+			input:    "$a~b~$",
+			expected: "<p>$a~b~$</p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := renderExtension(t, tt.input)
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSyntaxInsideContainers(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Nested quotes and lists",
+			input:    "> - ==marked== and 2^10^",
+			expected: "<blockquote>\n<ul>\n<li><mark>marked</mark> and 2<sup>10</sup></li>\n</ul>\n</blockquote>\n",
+		},
+		{
+			name:     "Link targets are not text",
+			input:    "[docs](https://example.com/a...b) (c)",
+			expected: "<p><a href=\"https://example.com/a...b\">docs</a> ©</p>\n",
+		},
+		{
+			name:     "Code fence inside details",
+			input:    "```details Code\n```go\nx := 1\n```\n```",
+			expected: "<details class=\"markdown-details\"><summary>Code</summary><div class=\"details-content\">\n<pre><code class=\"language-go\">x := 1\n</code></pre>\n</div></details>\n",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := SuperscriptPreprocessor(tt.input, "")
+			result := renderExtension(t, tt.input)
 			if result != tt.expected {
 				t.Errorf("Expected: %q, got: %q", tt.expected, result)
 			}
 		})
 	}
-}
\ No newline at end of file
+}
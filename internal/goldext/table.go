@@ -0,0 +1,428 @@
+package goldext
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Size limits of :::table:::
+const (
+	defaultTableRows = 200
+	maxTableRows     = 5000
+	maxTableColumns  = 200
+)
+
+// renderTableShortcode renders :::table file=data.csv::: from an attached
+// CSV or XLSX file. Options:
+//
+//	sheet=Name        sheet of an XLSX file, the first sheet by default
+//	header=true|false whether the first row is a header, detected by default
+//	sortable=true     let readers sort by clicking column headers
+//	limit=N           show at most N rows
+//	delimiter=;       CSV field separator, detected by default
+func renderTableShortcode(args string, ctx *RenderContext) (string, bool) {
+	_, params := ParseShortcodeArgs(args)
+	file := params["file"]
+	if file == "" {
+		return "", false
+	}
+
+	fsPath, err := tableFilePath(file, ctx.DocPath)
+	if err != nil {
+		return renderTableError(file, err.Error()), true
+	}
+	ctx.RecordFile(fsPath)
+
+	var rows [][]string
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv", ".tsv", ".txt":
+		rows, err = readCSVTable(fsPath, params["delimiter"])
+	case ".xlsx":
+		rows, err = readXLSXTable(fsPath, params["sheet"])
+	default:
+		err = errors.New("only CSV and XLSX files can be shown as tables")
+	}
+	if err != nil {
+		return renderTableError(file, err.Error()), true
+	}
+
+	limit, err := strconv.Atoi(params["limit"])
+	if err != nil || limit <= 0 {
+		limit = defaultTableRows
+	}
+	if limit > maxTableRows {
+		limit = maxTableRows
+	}
+
+	header := hasHeaderRow(rows)
+	switch params["header"] {
+	case "true", "yes":
+		header = len(rows) > 0
+	case "false", "no":
+		header = false
+	}
+
+	var buf strings.Builder
+	writeTable(&buf, file, rows, header, params["sortable"] == "true", limit)
+	return buf.String(), true
+}
+
+// tableFilePath resolves an attachment of the page at docPath, refusing
+// paths that leave the page's directory
+func tableFilePath(file, docPath string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(file, "\\", "/"))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", errors.New("the file must be an attachment of this page")
+	}
+	fsPath := getAttachmentPath(clean, docPath)
+	if _, err := os.Stat(fsPath); err != nil {
+		return "", errors.New("attachment not found")
+	}
+	return fsPath, nil
+}
+
+// readCSVTable reads a CSV file. The delimiter is guessed from the first
+// line unless given.
+func readCSVTable(fsPath, delimiter string) ([][]string, error) {
+	data, err := os.ReadFile(fsPath)
+	if err != nil {
+		return nil, err
+	}
+	return readCSVRows(data, delimiter)
+}
+
+// readCSVRows parses CSV data, guessing the delimiter unless given
+func readCSVRows(data []byte, delimiter string) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, errors.New("the file is not UTF-8 text")
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.Comma = guessDelimiter(data, delimiter)
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	return rows, nil
+}
+
+// guessDelimiter returns the delimiter given, or the most common of comma,
+// semicolon and tab in the first line
+func guessDelimiter(data []byte, delimiter string) rune {
+	if delimiter == `\t` || delimiter == "tab" {
+		return '\t'
+	}
+	if r, size := utf8.DecodeRuneInString(delimiter); size > 0 && size == len(delimiter) {
+		return r
+	}
+
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	best, bestCount := ',', 0
+	for _, r := range []rune{',', ';', '\t'} {
+		if count := bytes.Count(firstLine, []byte(string(r))); count > bestCount {
+			best, bestCount = r, count
+		}
+	}
+	return best
+}
+
+// xlsxWorkbook is the list of sheets in xl/workbook.xml
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxRelationships maps relationship IDs to the files they point to
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a string item of shared strings or an inline string, either
+// plain or made of rich text runs
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var s strings.Builder
+	for _, run := range t.Runs {
+		s.WriteString(run.T)
+	}
+	return s.String()
+}
+
+// xlsxSharedStrings is xl/sharedStrings.xml
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+// xlsxSheet is the cell data of a worksheet
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSXTable reads the cell values of a sheet of an XLSX file, the
+// first sheet if sheetName is empty. Formulas show their cached results.
+func readXLSXTable(fsPath, sheetName string) ([][]string, error) {
+	archive, err := zip.OpenReader(fsPath)
+	if err != nil {
+		return nil, errors.New("not a valid XLSX file")
+	}
+	defer archive.Close()
+
+	var workbook xlsxWorkbook
+	if err := readZipXML(&archive.Reader, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, errors.New("the workbook has no sheets")
+	}
+	sheetID := workbook.Sheets[0].ID
+	if sheetName != "" {
+		sheetID = ""
+		for _, sheet := range workbook.Sheets {
+			if strings.EqualFold(sheet.Name, sheetName) {
+				sheetID = sheet.ID
+				break
+			}
+		}
+		if sheetID == "" {
+			return nil, fmt.Errorf("sheet %q not found", sheetName)
+		}
+	}
+
+	var rels xlsxRelationships
+	if err := readZipXML(&archive.Reader, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	sheetFile := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == sheetID {
+			sheetFile = rel.Target
+			break
+		}
+	}
+	if sheetFile == "" {
+		return nil, errors.New("sheet data not found")
+	}
+	if strings.HasPrefix(sheetFile, "/") {
+		sheetFile = strings.TrimPrefix(sheetFile, "/")
+	} else {
+		sheetFile = path.Join("xl", sheetFile)
+	}
+
+	// Workbooks without text cells have no shared strings
+	var shared xlsxSharedStrings
+	_ = readZipXML(&archive.Reader, "xl/sharedStrings.xml", &shared)
+
+	var sheet xlsxSheet
+	if err := readZipXML(&archive.Reader, sheetFile, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		var values []string
+		for i, cell := range row.Cells {
+			col := cellColumn(cell.Ref)
+			if col < 0 {
+				col = i
+			}
+			if col >= maxTableColumns {
+				continue
+			}
+
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				if idx, err := strconv.Atoi(cell.Value); err == nil && idx >= 0 && idx < len(shared.Items) {
+					value = shared.Items[idx].String()
+				}
+			case "inlineStr":
+				value = cell.Inline.String()
+			case "b":
+				if value == "1" {
+					value = "TRUE"
+				} else {
+					value = "FALSE"
+				}
+			}
+
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			values[col] = value
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// readZipXML decodes an XML file inside a zip archive
+func readZipXML(archive *zip.Reader, name string, v interface{}) error {
+	for _, f := range archive.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		if err := xml.NewDecoder(io.LimitReader(rc, 64<<20)).Decode(v); err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s is missing", name)
+}
+
+// cellColumn returns the zero-based column of a cell reference such as
+// "C7", or -1 if ref has no column
+func cellColumn(ref string) int {
+	col := 0
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return -1
+	}
+	return col - 1
+}
+
+// hasHeaderRow guesses whether the first row holds column names: its cells
+// are all filled in, distinct and not numbers
+func hasHeaderRow(rows [][]string) bool {
+	if len(rows) < 2 || len(rows[0]) == 0 {
+		return false
+	}
+	seen := map[string]bool{}
+	for _, cell := range rows[0] {
+		cell = strings.TrimSpace(cell)
+		if cell == "" || seen[cell] || isNumber(cell) {
+			return false
+		}
+		seen[cell] = true
+	}
+	return true
+}
+
+// isNumber reports whether a cell holds a number, allowing thousands
+// separators, currency and percent signs
+func isNumber(cell string) bool {
+	cell = strings.Trim(strings.TrimSpace(cell), "$€£%")
+	cell = strings.ReplaceAll(cell, ",", "")
+	_, err := strconv.ParseFloat(cell, 64)
+	return err == nil
+}
+
+// writeTable writes rows as an HTML table, with at most limit rows below
+// the header
+func writeTable(w *strings.Builder, file string, rows [][]string, header, sortable bool, limit int) {
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	if columns > maxTableColumns {
+		columns = maxTableColumns
+	}
+
+	class := "wiki-table"
+	if sortable {
+		class += " sortable"
+	}
+	w.WriteString("<div class=\"wiki-table-container\">\n")
+	w.WriteString("<table class=\"" + class + "\" data-source=\"" + html.EscapeString(file) + "\">\n")
+
+	body := rows
+	if header && len(rows) > 0 {
+		w.WriteString("<thead>\n<tr>")
+		for i := 0; i < columns; i++ {
+			w.WriteString("<th>" + html.EscapeString(tableCell(rows[0], i)) + "</th>")
+		}
+		w.WriteString("</tr>\n</thead>\n")
+		body = rows[1:]
+	}
+
+	total := len(body)
+	if len(body) > limit {
+		body = body[:limit]
+	}
+
+	w.WriteString("<tbody>\n")
+	for _, row := range body {
+		w.WriteString("<tr>")
+		for i := 0; i < columns; i++ {
+			cell := tableCell(row, i)
+			if isNumber(cell) {
+				w.WriteString("<td class=\"number\">" + html.EscapeString(cell) + "</td>")
+			} else {
+				w.WriteString("<td>" + html.EscapeString(cell) + "</td>")
+			}
+		}
+		w.WriteString("</tr>\n")
+	}
+	w.WriteString("</tbody>\n</table>\n")
+
+	if total > len(body) {
+		w.WriteString(fmt.Sprintf("<p class=\"wiki-table-note\">Showing %d of %d rows from %s</p>\n", len(body), total, html.EscapeString(file)))
+	}
+	w.WriteString("</div>\n")
+}
+
+// tableCell returns the cell at column i of row, or "" for short rows
+func tableCell(row []string, i int) string {
+	if i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+// renderTableError renders a notice in place of a table that can't be shown
+func renderTableError(file, message string) string {
+	return fmt.Sprintf("<div class=\"wiki-table-error\">Cannot show %s: %s</div>\n", html.EscapeString(file), html.EscapeString(message))
+}
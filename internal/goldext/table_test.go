@@ -0,0 +1,85 @@
+package goldext
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeXLSX writes a minimal workbook with two sheets to file
+func writeXLSX(t *testing.T, file string) {
+	t.Helper()
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Summary" sheetId="1" r:id="rId1"/><sheet name="Q1 Sales" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":     `<sst><si><t>Region</t></si><si><t>Revenue</t></si><si><r><t>No</t></r><r><t>rth</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row><c r="A1" t="inlineStr"><is><t>unused</t></is></c></row></sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="C2"><v>1200.5</v></c></row>
+</sheetData></worksheet>`,
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTableShortcode(t *testing.T) {
+	t.Chdir(t.TempDir())
+	dir := filepath.Join("data", "documents", "reports")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	csvData := "\xef\xbb\xbfName;Score\nAda;10\n\"Grace <H>\";9,5\nLinus;7\n"
+	if err := os.WriteFile(filepath.Join(dir, "scores.csv"), []byte(csvData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeXLSX(t, filepath.Join(dir, "sales.xlsx"))
+
+	ctx := &RenderContext{DocPath: "reports", Deps: &RenderDeps{}}
+
+	got, _ := renderTableShortcode("file=scores.csv sortable=true limit=2", ctx)
+	want := "<div class=\"wiki-table-container\">\n<table class=\"wiki-table sortable\" data-source=\"scores.csv\">\n" +
+		"<thead>\n<tr><th>Name</th><th>Score</th></tr>\n</thead>\n<tbody>\n" +
+		"<tr><td>Ada</td><td class=\"number\">10</td></tr>\n" +
+		"<tr><td>Grace &lt;H&gt;</td><td class=\"number\">9,5</td></tr>\n" +
+		"</tbody>\n</table>\n<p class=\"wiki-table-note\">Showing 2 of 3 rows from scores.csv</p>\n</div>\n"
+	if got != want {
+		t.Errorf("CSV: expected %q, got %q", want, got)
+	}
+	if _, ok := ctx.Deps.Files[filepath.Join(dir, "scores.csv")]; !ok {
+		t.Errorf("expected the attachment to be recorded as a dependency, got %v", ctx.Deps.Files)
+	}
+
+	got, _ = renderTableShortcode(`file=sales.xlsx sheet="q1 sales"`, ctx)
+	want = "<thead>\n<tr><th>Region</th><th>Revenue</th><th></th></tr>\n</thead>\n<tbody>\n" +
+		"<tr><td>North</td><td></td><td class=\"number\">1200.5</td></tr>\n</tbody>"
+	if !strings.Contains(got, want) {
+		t.Errorf("XLSX: expected %q in %q", want, got)
+	}
+
+	for _, args := range []string{"file=../secret.csv", "file=missing.csv", `file=sales.xlsx sheet=Nope`} {
+		got, _ = renderTableShortcode(args, ctx)
+		if !strings.HasPrefix(got, "<div class=\"wiki-table-error\">") {
+			t.Errorf("%s: expected an error notice, got %q", args, got)
+		}
+	}
+}
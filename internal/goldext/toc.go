@@ -4,147 +4,248 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	// explicitIDRegex matches an {#id} attribute at the end of a heading line
+	explicitIDRegex = regexp.MustCompile(`^\s*\{#([a-zA-Z0-9_:.-]+)\}\s*$`)
+	// inlineCodeRegex and linkRegex strip markup from heading text before slugging
+	inlineCodeRegex = regexp.MustCompile("`[^`]+`")
+	linkRegex       = regexp.MustCompile(`\[([^\]]+)\]\([^)]+\)`)
+
+	slugSeparatorRegex = regexp.MustCompile(`[&+_,.()\[\]{}'"!?;:~*]`)
+	slugSpaceRegex     = regexp.MustCompile(`\s+`)
+	slugInvalidRegex   = regexp.MustCompile(`[^a-z0-9-]`)
+	slugHyphenRegex    = regexp.MustCompile(`-+`)
 )
 
-// TocPreprocessor adds support for [toc] markers
-// This generates the complete table of contents during markdown processing
-// by scanning for headings in the document and building the TOC HTML structure
-func TocPreprocessor(markdown string, _ string) string {
-	// Process line by line to handle code blocks properly
-	lines := strings.Split(markdown, "\n")
-	var result []string
+// tocEntry is a heading listed in the table of contents
+type tocEntry struct {
+	Level  int
+	Text   string // Heading text as HTML, after its number
+	Title  string // Heading text as HTML
+	Number string // Section number when headings are numbered
+	ID     string
+}
+
+// KindTOC is the node kind of [toc] markers
+var KindTOC = ast.NewNodeKind("TOC")
+
+// TOC is a [toc] marker, rendered as the table of contents of the document
+type TOC struct {
+	ast.BaseBlock
+	entries []tocEntry
+}
+
+// Kind implements ast.Node.Kind
+func (n *TOC) Kind() ast.NodeKind { return KindTOC }
+
+// Dump implements ast.Node.Dump
+func (n *TOC) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// tocParser adds support for [toc] markers on a line of their own
+type tocParser struct{}
 
-	inCodeBlock := false
-	tocMarker := regexp.MustCompile(`^\s*\[toc\]\s*$`)
-	headingRegex := regexp.MustCompile(`^(#{1,6})\s+(.+?)(?:\s+\{#([a-zA-Z0-9-]+)\})?$`)
+func (p *tocParser) Trigger() []byte {
+	return []byte{'['}
+}
 
-	// First pass: collect all headings and their levels
-	var headings []struct {
-		Level int
-		Text  string
-		ID    string
-		Line  string // Store the original line
+func (p *tocParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	if string(lineText(reader)) != "[toc]" {
+		return nil, parser.NoChildren
 	}
+	reader.AdvanceToEOL()
+	return &TOC{}, parser.NoChildren
+}
 
-	// Track used IDs to avoid duplicates
-	usedIDs := make(map[string]bool)
+func (p *tocParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	return parser.Close
+}
 
-	for i, line := range lines {
-		// Check if this line starts or ends a code block
-		trimmedLine := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
-			inCodeBlock = !inCodeBlock
-			continue
-		}
+func (p *tocParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
 
-		// Skip processing inside code blocks
-		if inCodeBlock {
-			continue
-		}
+func (p *tocParser) CanInterruptParagraph() bool {
+	return true
+}
 
-		// Extract headings outside of code blocks
-		matches := headingRegex.FindStringSubmatch(trimmedLine)
-		if matches != nil {
-			level := len(matches[1]) // Count the number of # characters
-			text := strings.TrimSpace(matches[2])
-			existingID := ""
+func (p *tocParser) CanAcceptIndentedLine() bool {
+	return false
+}
 
-			// Check if the heading already has an ID
-			if len(matches) > 3 && matches[3] != "" {
-				existingID = matches[3]
-			}
+func (r *nodeRenderer) renderTOC(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(generateTOCHTML(node.(*TOC).entries))
+		_ = w.WriteByte('\n')
+	}
+	return ast.WalkContinue, nil
+}
 
-			// Remove any inline code or formatting from heading text for ID generation
-			idText := text
-			// Remove inline code
-			idText = regexp.MustCompile("`[^`]+`").ReplaceAllString(idText, "")
-			// Remove links
-			idText = regexp.MustCompile(`\[([^\]]+)\]\([^)]+\)`).ReplaceAllString(idText, "$1")
-
-			// Use existing ID or generate a new one
-			var id string
-			if existingID != "" {
-				id = existingID
-			} else {
-				id = makeSlug(idText)
-			}
+// KindHeadingAnchor is the node kind of heading permalinks
+var KindHeadingAnchor = ast.NewNodeKind("HeadingAnchor")
 
-			// Ensure unique IDs
-			baseID := id
-			counter := 1
-			for usedIDs[id] {
-				id = fmt.Sprintf("%s-%d", baseID, counter)
-				counter++
-			}
+// HeadingAnchor is the ¶ permalink at the end of a heading
+type HeadingAnchor struct {
+	ast.BaseInline
+	ID string
+}
 
-			// Mark this ID as used
-			usedIDs[id] = true
+// Kind implements ast.Node.Kind
+func (n *HeadingAnchor) Kind() ast.NodeKind { return KindHeadingAnchor }
 
-			headings = append(headings, struct {
-				Level int
-				Text  string
-				ID    string
-				Line  string
-			}{Level: level, Text: text, ID: id, Line: line})
+// Dump implements ast.Node.Dump
+func (n *HeadingAnchor) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"ID": n.ID}, nil)
+}
 
-			// If this heading doesn't already have an ID, we need to update it in the original lines
-			if existingID == "" {
-				lines[i] = fmt.Sprintf("%s %s {#%s}", strings.Repeat("#", level), text, id)
-			}
-		}
+func (r *nodeRenderer) renderHeadingAnchor(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		fmt.Fprintf(w, ` <a class="heading-anchor" href="#%s" aria-label="Permalink">¶</a>`, node.(*HeadingAnchor).ID)
 	}
+	return ast.WalkContinue, nil
+}
 
-	// Second pass: Replace [toc] markers with generated TOC, but use the updated lines
-	inCodeBlock = false
-	for _, line := range lines {
-		trimmedLine := strings.TrimSpace(line)
+// headingTransformer gives every ATX heading of the document outline an ID
+// slugged from its text, adds a ¶ anchor to it, numbers it if the page asks
+// for it, and fills in the [toc] markers and [@sec:label] cross-references.
+// Headings in quotes, lists and direction blocks are not part of the
+// outline.
+type headingTransformer struct {
+	ctx *RenderContext
+}
 
-		// Check if this line starts or ends a code block
-		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
-			inCodeBlock = !inCodeBlock
-			result = append(result, line)
-			continue
-		}
+// Transform implements parser.ASTTransformer
+func (t *headingTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
 
-		// If we're in a code block, don't process
-		if inCodeBlock {
-			result = append(result, line)
-			continue
+	var entries []tocEntry
+	var headings []*ast.Heading
+	var tocs []*TOC
+	usedIDs := make(map[string]bool)
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
 		}
 
-		// Process [toc] markers outside of code blocks
-		if tocMarker.MatchString(trimmedLine) {
-			// Generate TOC HTML
-			tocHTML := generateTOCHTML(headings)
-			result = append(result, tocHTML)
-		} else {
-			// Check for inline code sections and preserve them
-			var processedLine string
-			segments := strings.Split(line, "`")
-
-			for j, segment := range segments {
-				if j%2 == 0 {
-					// Outside inline code
-					if strings.Contains(segment, "[toc]") {
-						// Replace [toc] with generated TOC HTML
-						segment = strings.ReplaceAll(segment, "[toc]", generateTOCHTML(headings))
-					}
-					processedLine += segment
-				} else {
-					// Inside inline code - preserve it
-					processedLine += "`" + segment + "`"
-				}
+		switch node := n.(type) {
+		case *ast.Document, *Details, *Alert:
+			return ast.WalkContinue, nil
+		case *TOC:
+			tocs = append(tocs, node)
+		case *ast.Heading:
+			if entry, ok := outlineHeading(node, source, usedIDs); ok {
+				entries = append(entries, entry)
+				headings = append(headings, node)
 			}
+		}
+		return ast.WalkSkipChildren, nil
+	})
 
-			if processedLine != "" {
-				result = append(result, processedLine)
-			} else {
-				result = append(result, line)
-			}
+	if t.ctx.NumberHeadings {
+		numberHeadings(headings, entries)
+	}
+
+	var refs []*SectionRef
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if ref, ok := n.(*SectionRef); ok && entering {
+			refs = append(refs, ref)
 		}
+		return ast.WalkContinue, nil
+	})
+	resolveSectionRefs(refs, entries)
+
+	for _, toc := range tocs {
+		toc.entries = entries
+	}
+}
+
+// outlineHeading assigns an ID and anchor to an ATX heading and returns its
+// table of contents entry
+func outlineHeading(heading *ast.Heading, source []byte, usedIDs map[string]bool) (tocEntry, bool) {
+	if heading.Lines().Len() != 1 || !isATXHeading(heading, source) {
+		return tocEntry{}, false
+	}
+	segment := heading.Lines().At(0)
+	text := string(segment.Value(source))
+	if text == "" {
+		return tocEntry{}, false
 	}
 
-	return strings.Join(result, "\n")
+	// Use the {#id} given in the source, or slug the heading text without
+	// inline code and link targets
+	var id, existingID string
+	if m := explicitIDRegex.FindSubmatch(restOfLine(source, segment.Stop)); m != nil {
+		existingID = string(m[1])
+		id = existingID
+	} else {
+		idText := inlineCodeRegex.ReplaceAllString(text, "")
+		idText = linkRegex.ReplaceAllString(idText, "$1")
+		id = makeSlug(idText)
+	}
+
+	// Ensure unique IDs
+	baseID := id
+	for counter := 1; usedIDs[id]; counter++ {
+		id = fmt.Sprintf("%s-%d", baseID, counter)
+	}
+	usedIDs[id] = true
+
+	anchorID := existingID
+	if existingID == "" {
+		heading.SetAttributeString("id", []byte(id))
+		anchorID = id
+	}
+	heading.AppendChild(heading, &HeadingAnchor{ID: anchorID})
+
+	text = headingText(heading, source)
+	return tocEntry{Level: heading.Level, Text: text, Title: text, ID: id}, true
+}
+
+// isATXHeading reports whether a heading starts with #, rather than being
+// underlined
+func isATXHeading(heading *ast.Heading, source []byte) bool {
+	start := heading.Lines().At(0).Start
+	lineStart := start
+	for lineStart > 0 && source[lineStart-1] != '\n' {
+		lineStart--
+	}
+	return strings.Contains(string(source[lineStart:start]), "#")
+}
+
+// restOfLine returns the source from pos to the end of its line
+func restOfLine(source []byte, pos int) []byte {
+	end := pos
+	for end < len(source) && source[end] != '\n' {
+		end++
+	}
+	return source[pos:end]
+}
+
+// headingText returns the text of a heading as HTML, without its markup
+func headingText(node ast.Node, source []byte) string {
+	var buf strings.Builder
+	for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+		switch child := c.(type) {
+		case *ast.Text:
+			buf.Write(util.EscapeHTML(child.Segment.Value(source)))
+			if child.SoftLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(util.EscapeHTML(child.Value))
+		case *HeadingAnchor, *ast.RawHTML:
+		default:
+			buf.WriteString(headingText(child, source))
+		}
+	}
+	return buf.String()
 }
 
 // makeSlug creates a URL-friendly slug from text
@@ -153,10 +254,10 @@ func makeSlug(text string) string {
 	text = strings.ToLower(text)
 
 	// First, replace common special characters with spaces
-	text = regexp.MustCompile(`[&+_,.()\[\]{}'"!?;:~*]`).ReplaceAllString(text, " ")
+	text = slugSeparatorRegex.ReplaceAllString(text, " ")
 
 	// Normalize spaces (convert multiple spaces to single space)
-	text = regexp.MustCompile(`\s+`).ReplaceAllString(text, " ")
+	text = slugSpaceRegex.ReplaceAllString(text, " ")
 
 	// Trim spaces from beginning and end
 	text = strings.TrimSpace(text)
@@ -165,10 +266,10 @@ func makeSlug(text string) string {
 	text = strings.ReplaceAll(text, " ", "-")
 
 	// Remove any non-alphanumeric characters except hyphens
-	text = regexp.MustCompile(`[^a-z0-9-]`).ReplaceAllString(text, "")
+	text = slugInvalidRegex.ReplaceAllString(text, "")
 
 	// Remove consecutive hyphens
-	text = regexp.MustCompile(`-+`).ReplaceAllString(text, "-")
+	text = slugHyphenRegex.ReplaceAllString(text, "-")
 
 	// Trim hyphens from beginning and end
 	text = strings.Trim(text, "-")
@@ -181,13 +282,25 @@ func makeSlug(text string) string {
 	return text
 }
 
-// Generate the HTML for the table of contents
-func generateTOCHTML(headings []struct {
+// TOCHeading is a heading listed by RenderTOC
+type TOCHeading struct {
 	Level int
-	Text  string
+	Text  string // Heading text as HTML
 	ID    string
-	Line  string
-}) string {
+}
+
+// RenderTOC returns the HTML of a table of contents of headings, the way
+// [toc] renders one
+func RenderTOC(headings []TOCHeading) string {
+	entries := make([]tocEntry, len(headings))
+	for i, h := range headings {
+		entries[i] = tocEntry{Level: h.Level, Text: h.Text, Title: h.Text, ID: h.ID}
+	}
+	return generateTOCHTML(entries)
+}
+
+// Generate the HTML for the table of contents
+func generateTOCHTML(headings []tocEntry) string {
 	if len(headings) == 0 {
 		return `<div class="wiki-toc"><p class="toc-empty">No headings found in this document.</p></div>`
 	}
@@ -1,74 +1,44 @@
 package goldext
 
 import (
-	"strings"
-)
-
-// TypographyPreprocessor replaces common typography shortcuts with proper Unicode symbols
-// but avoids processing text inside code blocks
-func TypographyPreprocessor(markdown string, _ string) string {
-	// Process line by line instead of relying on regex which might fail on large documents
-	lines := strings.Split(markdown, "\n")
-	var result []string
+	"bytes"
 
-	inCodeBlock := false
-
-	// Define replacements mapping
-	replacements := map[string]string{
-		"(c)":  "©", // Copyright symbol
-		"(r)":  "®", // Registered trademark symbol
-		"(tm)": "™", // Trademark symbol
-		"(p)":  "¶", // Paragraph symbol
-		"+-":   "±", // Plus-minus symbol
-		"...":  "…", // Ellipsis
-		"(1/2)":  "½", // One-half
-		"(1/4)":  "¼", // One-quarter
-		"(3/4)":  "¾", // Three-quarters
-	}
-
-	for _, line := range lines {
-		// Check if this line starts or ends a code block
-		trimmedLine := strings.TrimSpace(line)
-
-		// Strip blockquote prefix(es) to detect code blocks inside blockquotes
-		// e.g., "> ```" or "> > ```" should be detected as code block markers
-		contentLine := trimmedLine
-		for strings.HasPrefix(contentLine, ">") {
-			contentLine = strings.TrimSpace(strings.TrimPrefix(contentLine, ">"))
-		}
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
 
-		if strings.HasPrefix(contentLine, "```") || strings.HasPrefix(contentLine, "~~~") {
-			inCodeBlock = !inCodeBlock
-			result = append(result, line)
-			continue
-		}
+// typographyReplacements maps typography shortcuts to Unicode symbols
+var typographyReplacements = []struct {
+	Shortcut, Symbol string
+}{
+	{"(c)", "©"},   // Copyright symbol
+	{"(r)", "®"},   // Registered trademark symbol
+	{"(tm)", "™"},  // Trademark symbol
+	{"(p)", "¶"},   // Paragraph symbol
+	{"(1/2)", "½"}, // One-half
+	{"(1/4)", "¼"}, // One-quarter
+	{"(3/4)", "¾"}, // Three-quarters
+	{"+-", "±"},    // Plus-minus symbol
+	{"...", "…"},   // Ellipsis
+}
 
-		// If we're in a code block, don't process
-		if inCodeBlock {
-			result = append(result, line)
-			continue
-		}
+// typographyParser replaces common typography shortcuts in text with proper
+// Unicode symbols. Code, URLs and raw HTML are not text, so they are left
+// alone.
+type typographyParser struct{}
 
-		// Process each segment of the line, preserving inline code
-		var processedLine string
-		segments := strings.Split(line, "`")
+func (p *typographyParser) Trigger() []byte {
+	return []byte{'(', '+', '.'}
+}
 
-		for i, segment := range segments {
-			// Even segments (0, 2, 4...) are outside inline code
-			if i%2 == 0 {
-				// Apply all replacements to non-code segments
-				for shortcut, symbol := range replacements {
-					segment = strings.ReplaceAll(segment, shortcut, symbol)
-				}
-				processedLine += segment
-			} else {
-				// Odd segments (1, 3, 5...) are inside inline code - preserve them
-				processedLine += "`" + segment + "`"
-			}
+func (p *typographyParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	for _, r := range typographyReplacements {
+		if bytes.HasPrefix(line, []byte(r.Shortcut)) {
+			block.Advance(len(r.Shortcut))
+			return ast.NewString([]byte(r.Symbol))
 		}
-
-		result = append(result, processedLine)
 	}
-
-	return strings.Join(result, "\n")
+	return nil
 }
@@ -0,0 +1,335 @@
+package goldext
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// UserShortcodeExt is the file extension of shortcode templates
+const UserShortcodeExt = ".html"
+
+// shortcodeNameRegex matches valid shortcode names
+var shortcodeNameRegex = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// shortcodeArgRegex matches one shortcode argument: key=value, key="quoted
+// value", a bare value or a "quoted value"
+var shortcodeArgRegex = regexp.MustCompile(`(?:([a-zA-Z][a-zA-Z0-9_-]*)=)?(?:"([^"]*)"|(\S+))`)
+
+// descriptionRegex matches a {{/* comment */}} at the start of a template,
+// which is used as the shortcode's description
+var descriptionRegex = regexp.MustCompile(`^\s*\{\{-?\s*/\*\s*([\s\S]*?)\s*\*/\s*-?\}\}`)
+
+// builtinShortcodes are names handled by the wiki itself, which templates
+// can't override
+var builtinShortcodes = []string{"include", "var", "year"}
+
+// UserShortcode is a shortcode defined by an html/template file in the
+// shortcodes directory
+type UserShortcode struct {
+	Name        string `json:"name"`
+	File        string `json:"file"`
+	Description string `json:"description,omitempty"`
+	Error       string `json:"error,omitempty"` // Why the template can't be used, empty if it is valid
+	tmpl        *template.Template
+}
+
+// ShortcodeData is the data passed to shortcode templates. For
+// :::badge status=green "All good":::, Args is ["All good"] and Params is
+// {"status": "green"}.
+type ShortcodeData struct {
+	Name   string            // Name of the shortcode
+	Args   []string          // Arguments without a key, in order
+	Params map[string]string // key=value arguments
+	Page   string            // Path of the page being rendered
+}
+
+// Arg returns the positional argument at index i, or "" if there is none
+func (d ShortcodeData) Arg(i int) string {
+	if i < 0 || i >= len(d.Args) {
+		return ""
+	}
+	return d.Args[i]
+}
+
+// Param returns the value of a key=value argument, or def if it is missing
+func (d ShortcodeData) Param(key, def string) string {
+	if v, ok := d.Params[key]; ok {
+		return v
+	}
+	return def
+}
+
+// userShortcodes holds the loaded templates and a stamp of the files they
+// were loaded from, so changes can be detected
+var (
+	userShortcodesMu    sync.RWMutex
+	userShortcodes      = map[string]*UserShortcode{}
+	userShortcodesStamp string
+)
+
+// LoadUserShortcodes loads every template in dir, replacing the shortcodes
+// loaded before. Templates that fail to parse are kept with their error so
+// admins can see it, but are not rendered. A missing directory means no
+// user shortcodes.
+func LoadUserShortcodes(dir string) error {
+	stamp, err := userShortcodesDirStamp(dir)
+	if err != nil {
+		return err
+	}
+
+	loaded := map[string]*UserShortcode{}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+UserShortcodeExt))
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), UserShortcodeExt)
+		source, err := os.ReadFile(file)
+		if err != nil {
+			loaded[name] = &UserShortcode{Name: name, File: filepath.Base(file), Error: err.Error()}
+			continue
+		}
+		sc := ParseUserShortcode(name, string(source))
+		sc.File = filepath.Base(file)
+		if sc.Error != "" {
+			log.Printf("goldext: shortcode %s: %s", file, sc.Error)
+		}
+		loaded[name] = sc
+	}
+
+	userShortcodesMu.Lock()
+	userShortcodes = loaded
+	userShortcodesStamp = stamp
+	userShortcodesMu.Unlock()
+	return nil
+}
+
+// ReloadUserShortcodes reloads the templates in dir if any of them was
+// added, removed or modified since they were last loaded. It reports
+// whether they were reloaded.
+func ReloadUserShortcodes(dir string) bool {
+	stamp, err := userShortcodesDirStamp(dir)
+	if err != nil {
+		log.Printf("goldext: failed to check shortcodes in %s: %v", dir, err)
+		return false
+	}
+
+	userShortcodesMu.RLock()
+	unchanged := stamp == userShortcodesStamp
+	userShortcodesMu.RUnlock()
+	if unchanged {
+		return false
+	}
+
+	if err := LoadUserShortcodes(dir); err != nil {
+		log.Printf("goldext: failed to load shortcodes from %s: %v", dir, err)
+		return false
+	}
+	return true
+}
+
+// WatchUserShortcodes checks dir for changed templates every interval and
+// reloads them, calling onChange after each reload
+func WatchUserShortcodes(dir string, interval time.Duration, onChange func()) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if ReloadUserShortcodes(dir) && onChange != nil {
+				onChange()
+			}
+		}
+	}()
+}
+
+// UserShortcodesVersion identifies the loaded templates. It changes whenever
+// they are reloaded with different content, so it can be part of cache keys.
+func UserShortcodesVersion() string {
+	userShortcodesMu.RLock()
+	defer userShortcodesMu.RUnlock()
+	return userShortcodesStamp
+}
+
+// userShortcodesDirStamp hashes the names, sizes and modification times of
+// the templates in dir
+func userShortcodesDirStamp(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	h := sha256.New()
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != UserShortcodeExt {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(h, "%s|%d|%d\n", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// ParseUserShortcode parses the template source of a shortcode. Problems
+// with the name or template are reported in the Error field.
+func ParseUserShortcode(name, source string) *UserShortcode {
+	sc := &UserShortcode{Name: name, File: name + UserShortcodeExt}
+	if m := descriptionRegex.FindStringSubmatch(source); m != nil {
+		sc.Description = m[1]
+	}
+
+	if !shortcodeNameRegex.MatchString(name) {
+		sc.Error = "invalid name: use lowercase letters, digits and dashes, starting with a letter"
+		return sc
+	}
+	if isBuiltinShortcode(name) {
+		sc.Error = "the name is used by a built-in shortcode"
+		return sc
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(source)
+	if err != nil {
+		sc.Error = err.Error()
+		return sc
+	}
+	sc.tmpl = tmpl
+	return sc
+}
+
+// isBuiltinShortcode reports whether name is a shortcode of the wiki itself
+func isBuiltinShortcode(name string) bool {
+	if _, ok := blockShortcodes[name]; ok {
+		return true
+	}
+	for _, builtin := range builtinShortcodes {
+		if name == builtin {
+			return true
+		}
+	}
+	return false
+}
+
+// BuiltinShortcodeNames returns the names of the wiki's own shortcodes
+func BuiltinShortcodeNames() []string {
+	names := append([]string{}, builtinShortcodes...)
+	for name := range blockShortcodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UserShortcodes returns the loaded user shortcodes sorted by name
+func UserShortcodes() []UserShortcode {
+	userShortcodesMu.RLock()
+	defer userShortcodesMu.RUnlock()
+
+	list := make([]UserShortcode, 0, len(userShortcodes))
+	for _, sc := range userShortcodes {
+		list = append(list, *sc)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// LookupUserShortcode returns the user shortcode called name, which may have
+// an Error if its template is invalid
+func LookupUserShortcode(name string) (*UserShortcode, bool) {
+	userShortcodesMu.RLock()
+	defer userShortcodesMu.RUnlock()
+
+	sc, ok := userShortcodes[name]
+	return sc, ok
+}
+
+// ParseShortcodeArgs splits shortcode arguments into positional arguments
+// and key=value parameters. Values may be double-quoted to contain spaces.
+func ParseShortcodeArgs(args string) ([]string, map[string]string) {
+	var positional []string
+	params := map[string]string{}
+	for _, m := range shortcodeArgRegex.FindAllStringSubmatch(args, -1) {
+		value := m[3]
+		if value == "" {
+			value = m[2]
+		}
+		if m[1] != "" {
+			params[m[1]] = value
+		} else {
+			positional = append(positional, value)
+		}
+	}
+	return positional, params
+}
+
+// Render executes the shortcode's template with args and sanitizes the
+// output with the script sanitizer
+func (sc *UserShortcode) Render(args, docPath string) (string, error) {
+	if sc.tmpl == nil {
+		return "", errors.New(sc.Error)
+	}
+
+	positional, params := ParseShortcodeArgs(args)
+	data := ShortcodeData{Name: sc.Name, Args: positional, Params: params, Page: "/" + strings.Trim(docPath, "/")}
+
+	var buf strings.Builder
+	if err := sc.tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(ScriptSanitizePreprocessor(buf.String(), "")), nil
+}
+
+// renderUserShortcode renders the user shortcode called name, returning
+// false if there is no such shortcode or it fails to render
+func renderUserShortcode(name, args string, ctx *RenderContext) (string, bool) {
+	sc, ok := LookupUserShortcode(name)
+	if !ok || sc.Error != "" {
+		return "", false
+	}
+	html, err := sc.Render(args, ctx.DocPath)
+	if err != nil {
+		log.Printf("goldext: shortcode %s failed on %s: %v", name, ctx.DocPath, err)
+		return "", false
+	}
+	return html, true
+}
+
+// KindInlineShortcode is the node kind of shortcodes within text
+var KindInlineShortcode = ast.NewNodeKind("InlineShortcode")
+
+// InlineShortcode is a user shortcode within text and the HTML it rendered to
+type InlineShortcode struct {
+	ast.BaseInline
+	Name string
+	HTML string
+}
+
+// Kind implements ast.Node.Kind
+func (n *InlineShortcode) Kind() ast.NodeKind { return KindInlineShortcode }
+
+// Dump implements ast.Node.Dump
+func (n *InlineShortcode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Name": n.Name}, nil)
+}
+
+func (r *nodeRenderer) renderInlineShortcode(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(node.(*InlineShortcode).HTML)
+	}
+	return ast.WalkSkipChildren, nil
+}
@@ -0,0 +1,89 @@
+package goldext
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseShortcodeArgs(t *testing.T) {
+	args, params := ParseShortcodeArgs(`KEY-123 status=green text="All good" "two words"`)
+	if want := []string{"KEY-123", "two words"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("Expected args %q, got %q", want, args)
+	}
+	if want := map[string]string{"status": "green", "text": "All good"}; !reflect.DeepEqual(params, want) {
+		t.Errorf("Expected params %q, got %q", want, params)
+	}
+}
+
+func TestUserShortcodes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"jira.html":   `<a href="https://jira.example.com/browse/{{.Arg 0}}">{{.Arg 0}}</a>`,
+		"badge.html":  `{{/* Coloured status badge */}}<span class="badge badge-{{.Param "status" "grey"}}">{{.Params.text}}</span><script>alert(1)</script>`,
+		"stats.html":  `overrides a built-in`,
+		"broken.html": `{{if}}`,
+	}
+	for name, source := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := LoadUserShortcodes(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer LoadUserShortcodes(filepath.Join(dir, "missing"))
+
+	errors := map[string]bool{}
+	for _, sc := range UserShortcodes() {
+		errors[sc.Name] = sc.Error != ""
+	}
+	if want := map[string]bool{"badge": false, "broken": true, "jira": false, "stats": true}; !reflect.DeepEqual(errors, want) {
+		t.Errorf("Expected template errors %v, got %v", want, errors)
+	}
+	if sc, _ := LookupUserShortcode("badge"); sc.Description != "Coloured status badge" {
+		t.Errorf("Expected the leading comment as description, got %q", sc.Description)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Inline",
+			input:    "See :::jira KEY-123::: for details.",
+			expected: "<p>See <a href=\"https://jira.example.com/browse/KEY-123\">KEY-123</a> for details.</p>\n",
+		},
+		{
+			name:     "Own line, sanitized",
+			input:    `:::badge status=green text="All good":::`,
+			expected: "<span class=\"badge badge-green\">All good</span>\n",
+		},
+		{
+			name:     "Escaped parameters",
+			input:    `:::badge text="<b>x</b>":::`,
+			expected: "<span class=\"badge badge-grey\">&lt;b&gt;x&lt;/b&gt;</span>\n",
+		},
+		{
+			name:     "Invalid template",
+			input:    ":::broken:::",
+			expected: "<p>:::broken:::</p>\n",
+		},
+		{
+			name:     "Code span",
+			input:    "`:::jira KEY-1:::`",
+			expected: "<p><code>:::jira KEY-1:::</code></p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := renderExtension(t, tt.input)
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package goldext
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"wiki-go/internal/frontmatter"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// VariablesPage is the page whose frontmatter defines wiki-wide variables
+const VariablesPage = "/_variables"
+
+// Sources of a variable's value
+const (
+	VariableSourceConfig = "config"
+	VariableSourcePage   = "page"
+)
+
+// Variable is a wiki-wide variable and where it is defined
+type Variable struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"` // VariableSourceConfig or VariableSourcePage
+}
+
+// variableNameRegex matches a valid variable name
+var variableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// variableUseRegex matches :::var name::: in markdown
+var variableUseRegex = regexp.MustCompile(`:::var\s+([A-Za-z_][A-Za-z0-9_.-]*)\s*:::`)
+
+// configVariables are the variables from config.yaml, set with
+// SetConfigVariables
+var (
+	variablesMu     sync.RWMutex
+	configVariables map[string]string
+)
+
+// pageVariables caches the variables of VariablesPage by modification time
+var (
+	pageVariablesMu      sync.Mutex
+	pageVariablesModTime int64
+	pageVariables        map[string]string
+)
+
+// SetConfigVariables sets the variables defined in config.yaml
+func SetConfigVariables(vars map[string]string) {
+	variablesMu.Lock()
+	defer variablesMu.Unlock()
+	configVariables = vars
+}
+
+// VariablesVersion identifies the variables from config.yaml, so it can be
+// part of cache keys. Changes to VariablesPage are tracked as an include.
+func VariablesVersion() string {
+	variablesMu.RLock()
+	defer variablesMu.RUnlock()
+	if len(configVariables) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(configVariables))
+	for name := range configVariables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%s\n", name, configVariables[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// loadPageVariables returns the variables in the frontmatter of
+// VariablesPage
+func loadPageVariables() map[string]string {
+	modTime := PageModTime(VariablesPage)
+
+	pageVariablesMu.Lock()
+	defer pageVariablesMu.Unlock()
+	if modTime == pageVariablesModTime {
+		return pageVariables
+	}
+	pageVariablesModTime = modTime
+	pageVariables = nil
+	if content, err := os.ReadFile(includeFilePath(VariablesPage)); err == nil {
+		metadata, _, _ := frontmatter.Parse(string(content))
+		pageVariables = metadata.Variables
+	}
+	return pageVariables
+}
+
+// WikiVariables returns the wiki-wide variables sorted by name. Variables
+// on VariablesPage override those in config.yaml.
+func WikiVariables() []Variable {
+	merged := map[string]Variable{}
+	variablesMu.RLock()
+	for name, value := range configVariables {
+		merged[name] = Variable{Name: name, Value: value, Source: VariableSourceConfig}
+	}
+	variablesMu.RUnlock()
+	for name, value := range loadPageVariables() {
+		merged[name] = Variable{Name: name, Value: value, Source: VariableSourcePage}
+	}
+
+	list := make([]Variable, 0, len(merged))
+	for _, v := range merged {
+		if variableNameRegex.MatchString(v.Name) {
+			list = append(list, v)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// LookupVariable returns the value of a variable for the page: from its
+// frontmatter, else from VariablesPage, else from config.yaml
+func (ctx *RenderContext) LookupVariable(name string) (string, bool) {
+	if value, ok := ctx.Variables[name]; ok {
+		return value, true
+	}
+	ctx.RecordInclude(VariablesPage)
+	if value, ok := loadPageVariables()[name]; ok {
+		return value, true
+	}
+	variablesMu.RLock()
+	defer variablesMu.RUnlock()
+	value, ok := configVariables[name]
+	return value, ok
+}
+
+// UsedVariables returns the names of the variables referenced in markdown
+// outside code, each once and in order of first use
+func UsedVariables(markdown string) []string {
+	var names []string
+	seen := map[string]bool{}
+	fence := ""
+	for _, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if isFence(trimmed) && trimmed[0] == fence[0] && len(trimmed) >= len(fence) {
+				fence = ""
+			}
+			continue
+		}
+		if fence = fencePrefix(trimmed); fence != "" {
+			continue
+		}
+		for _, m := range variableUseRegex.FindAllStringSubmatch(codeSpanRegex.ReplaceAllString(line, ""), -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				names = append(names, m[1])
+			}
+		}
+	}
+	return names
+}
+
+// codeSpanRegex matches inline code on a line
+var codeSpanRegex = regexp.MustCompile("`+[^`]*`+")
+
+// variableNode returns the node for :::var name:::, the variable's value as
+// text. An undefined variable is shown by name, so it is easy to spot.
+func variableNode(name string, ctx *RenderContext) (ast.Node, bool) {
+	name = strings.TrimSpace(name)
+	if !variableNameRegex.MatchString(name) {
+		return nil, false
+	}
+	value, ok := ctx.LookupVariable(name)
+	if !ok {
+		return &InlineShortcode{
+			Name: "var",
+			HTML: fmt.Sprintf("<span class=\"wiki-var-missing\" title=\"Undefined variable\">%s</span>", name),
+		}, true
+	}
+	return ast.NewString([]byte(value)), true
+}
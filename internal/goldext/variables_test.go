@@ -0,0 +1,56 @@
+package goldext
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/yuin/goldmark"
+)
+
+func TestVariables(t *testing.T) {
+	t.Chdir(t.TempDir())
+	file := filepath.Join("data", "documents", "_variables", "document.md")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	page := "---\nvariables:\n  release_version: 2.5.0\n  vpn_host: vpn.example.com\n---\n"
+	if err := os.WriteFile(file, []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+	SetConfigVariables(map[string]string{"release_version": "2.4.1", "support_email": "help@example.com <Help>"})
+	defer SetConfigVariables(nil)
+
+	ctx := &RenderContext{DocPath: "guide", Variables: map[string]string{"vpn_host": "vpn.eu.example.com"}, Deps: &RenderDeps{}}
+	md := goldmark.New(goldmark.WithExtensions(NewExtension(ctx)))
+	var buf bytes.Buffer
+	source := ":::var release_version:::\n\nMail :::var support_email:::, connect to `:::var vpn_host:::` :::var vpn_host::: :::var nope:::\n\n```\n:::var release_version:::\n```\n"
+	if err := md.Convert([]byte(source), &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "<p>2.5.0</p>\n<p>Mail help@example.com &lt;Help&gt;, connect to <code>:::var vpn_host:::</code> vpn.eu.example.com " +
+		"<span class=\"wiki-var-missing\" title=\"Undefined variable\">nope</span></p>\n<pre><code>:::var release_version:::\n</code></pre>\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+	if _, ok := ctx.Deps.Includes[VariablesPage]; !ok {
+		t.Errorf("expected %s to be recorded as a dependency, got %v", VariablesPage, ctx.Deps.Includes)
+	}
+
+	vars := WikiVariables()
+	wantVars := []Variable{
+		{Name: "release_version", Value: "2.5.0", Source: VariableSourcePage},
+		{Name: "support_email", Value: "help@example.com <Help>", Source: VariableSourceConfig},
+		{Name: "vpn_host", Value: "vpn.example.com", Source: VariableSourcePage},
+	}
+	if !reflect.DeepEqual(vars, wantVars) {
+		t.Errorf("expected %v, got %v", wantVars, vars)
+	}
+
+	used := UsedVariables(source + "~~~\n:::var fenced:::\n~~~\n:::var vpn_host::: again")
+	if want := []string{"release_version", "support_email", "vpn_host", "nope"}; !reflect.DeepEqual(used, want) {
+		t.Errorf("expected %v, got %v", want, used)
+	}
+}
@@ -0,0 +1,104 @@
+package goldext
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindVideo is the node kind of embedded videos
+var KindVideo = ast.NewNodeKind("Video")
+
+// Video is a ```youtube, ```vimeo or ```mp4 block, rendered as an embedded
+// player with a placeholder for print
+type Video struct {
+	ast.BaseBlock
+	Provider string // youtube, vimeo or mp4
+	Source   string // Video ID, or the video URL for mp4
+}
+
+// Kind implements ast.Node.Kind
+func (n *Video) Kind() ast.NodeKind { return KindVideo }
+
+// Dump implements ast.Node.Dump
+func (n *Video) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Provider": n.Provider, "Source": n.Source}, nil)
+}
+
+// videoProviders lists the code block languages that embed videos, in the
+// order they are checked against the info string
+var videoProviders = []string{"mp4", "youtube", "vimeo"}
+
+// videoTransformer replaces fenced code blocks whose info string names a
+// video provider with Video nodes
+type videoTransformer struct {
+	ctx *RenderContext
+}
+
+// Transform implements parser.ASTTransformer
+func (t *videoTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+
+	var blocks []*ast.FencedCodeBlock
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if code, ok := n.(*ast.FencedCodeBlock); ok && entering && code.Info != nil {
+			blocks = append(blocks, code)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, code := range blocks {
+		info := string(code.Info.Segment.Value(source))
+		for _, provider := range videoProviders {
+			if strings.Contains(info, provider) {
+				video := &Video{Provider: provider, Source: t.videoSource(provider, code, source)}
+				code.Parent().ReplaceChild(code.Parent(), code, video)
+				break
+			}
+		}
+	}
+}
+
+// videoSource returns the video ID or URL of a video block, or an empty
+// string if none was given
+func (t *videoTransformer) videoSource(provider string, code *ast.FencedCodeBlock, source []byte) string {
+	lines := make([]string, 0, code.Lines().Len())
+	for i := 0; i < code.Lines().Len(); i++ {
+		line := code.Lines().At(i)
+		lines = append(lines, strings.TrimSuffix(string(line.Value(source)), "\n"))
+	}
+	content := strings.Join(lines, "\n")
+
+	switch provider {
+	case "youtube":
+		return ExtractYouTubeID(content)
+	case "vimeo":
+		return ExtractVimeoID(content)
+	default:
+		videoPath := strings.TrimSpace(content)
+		if videoPath == "" {
+			return ""
+		}
+		return TransformMP4Path(videoPath, t.ctx.DocPath)
+	}
+}
+
+func (r *nodeRenderer) renderVideo(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*Video)
+	if !entering || n.Source == "" {
+		return ast.WalkContinue, nil
+	}
+
+	switch n.Provider {
+	case "youtube":
+		renderYouTube(w, n.Source)
+	case "vimeo":
+		renderVimeo(w, n.Source)
+	default:
+		renderMP4(w, n.Source)
+	}
+	return ast.WalkContinue, nil
+}
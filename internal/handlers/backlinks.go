@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"wiki-go/internal/auth"
+	"wiki-go/internal/config"
+	"wiki-go/internal/goldext"
+)
+
+// Backlink represents a document that links to or includes another document
+type Backlink struct {
+	Title string `json:"title"`
+	Path  string `json:"path"`
+	Type  string `json:"type"` // "link" or "include"
+}
+
+// BacklinksHandler handles GET /api/backlinks?path=/doc and returns the
+// documents that link to or include the given document
+func BacklinksHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Method not allowed",
+		})
+		return
+	}
+
+	target := normalizeDocPath(r.URL.Query().Get("path"))
+
+	session := auth.GetSession(r)
+	if !auth.CanAccessDocument(target, session, cfg) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Access denied",
+		})
+		return
+	}
+
+	backlinks := findBacklinks(target, session, cfg)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"path":      target,
+		"backlinks": backlinks,
+	})
+}
+
+// findBacklinks scans all documents the session can access for links and
+// includes pointing at target
+func findBacklinks(target string, session *auth.Session, cfg *config.Config) []Backlink {
+	backlinks := []Backlink{}
+
+	forEachDocument(cfg, func(urlPath string, content string) {
+		if urlPath == target || !auth.CanAccessDocument(urlPath, session, cfg) {
+			return
+		}
+
+		for _, inc := range goldext.FindIncludes(content) {
+			if inc.Path == target {
+				backlinks = append(backlinks, Backlink{Title: extractTitle(content), Path: urlPath, Type: "include"})
+				return
+			}
+		}
+
+		for _, link := range goldext.FindDocumentLinks(content) {
+			if link == target {
+				backlinks = append(backlinks, Backlink{Title: extractTitle(content), Path: urlPath, Type: "link"})
+				return
+			}
+		}
+	})
+
+	return backlinks
+}
+
+// forEachDocument calls fn with the URL path and content of the homepage and
+// every document.md in the documents directory
+func forEachDocument(cfg *config.Config, fn func(urlPath string, content string)) {
+	homePath := filepath.Join(cfg.Wiki.RootDir, "pages", "home", "document.md")
+	if content, err := os.ReadFile(homePath); err == nil {
+		fn("/", string(content))
+	}
+
+	docsPath := filepath.Join(cfg.Wiki.RootDir, cfg.Wiki.DocumentsDir)
+	filepath.Walk(docsPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if info.IsDir() {
+			// Skip hidden directories
+			if path != docsPath && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.Name() != "document.md" {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(docsPath, filepath.Dir(path))
+		if err != nil {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		fn("/"+filepath.ToSlash(relPath), string(content))
+		return nil
+	})
+}
+
+// normalizeDocPath cleans a document path and makes sure it starts with /
+func normalizeDocPath(path string) string {
+	path = filepath.ToSlash(filepath.Clean("/" + strings.Trim(path, "/")))
+	if path == "." {
+		return "/"
+	}
+	return path
+}
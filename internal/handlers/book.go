@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"wiki-go/internal/auth"
+	"wiki-go/internal/config"
+	"wiki-go/internal/goldext"
+	"wiki-go/internal/types"
+)
+
+// bookTOCDepth is the deepest heading level listed in a book's table of
+// contents
+const bookTOCDepth = 3
+
+var (
+	// bookIDRegex and bookHrefRegex match the id and href attributes that
+	// are rewritten when a page becomes a chapter of a book
+	bookIDRegex   = regexp.MustCompile(`\sid="([^"]*)"`)
+	bookHrefRegex = regexp.MustCompile(`\shref="([^"]*)"`)
+	// bookHeadingTagRegex matches heading tags, which are shifted down by
+	// the depth of their chapter
+	bookHeadingTagRegex = regexp.MustCompile(`<(/?)h([1-6])\b`)
+	// bookHeadingRegex matches a heading with an ID in rendered HTML
+	bookHeadingRegex = regexp.MustCompile(`(?s)<h([1-6]) id="([^"]+)"[^>]*>(.*?)</h[1-6]>`)
+	// bookAnchorRegex and bookTagRegex strip markup from heading text
+	bookAnchorRegex = regexp.MustCompile(`(?s)<a class="heading-anchor"[^>]*>.*?</a>`)
+	bookTagRegex    = regexp.MustCompile(`<[^>]+>`)
+)
+
+// bookChapter is a page rendered as part of a book
+type bookChapter struct {
+	Path  string // URL path of the page
+	Title string
+	Depth int    // Levels below the book's first page
+	ID    string // Anchor of the chapter within the book
+	HTML  string // The page with headings shifted by Depth and links pointing into the book
+}
+
+// bookChapterID returns the anchor of the chapter for the page at path
+func bookChapterID(path string) string {
+	return "book" + strings.ReplaceAll(strings.TrimSuffix(path, "/"), "/", "-")
+}
+
+// buildBook renders the page at root and every page below it that the
+// viewer of session can access, in navigation order. Headings are shifted
+// down a level for each level below root, IDs are made unique to their
+// chapter, and links between pages of the book become in-page anchors.
+func buildBook(root *types.NavItem, session *auth.Session, cfg *config.Config) []bookChapter {
+	var chapters []bookChapter
+	var walk func(item *types.NavItem, depth int)
+	walk = func(item *types.NavItem, depth int) {
+		if !auth.CanAccessDocument(item.Path, session, cfg) {
+			return
+		}
+		chapters = append(chapters, bookChapter{
+			Path:  item.Path,
+			Title: item.Title,
+			Depth: depth,
+			ID:    bookChapterID(item.Path),
+		})
+		for _, child := range item.Children {
+			walk(child, depth+1)
+		}
+	}
+	walk(root, 0)
+
+	chapterIDs := make(map[string]string, len(chapters))
+	for _, c := range chapters {
+		chapterIDs[c.Path] = c.ID
+	}
+
+	for i := range chapters {
+		c := &chapters[i]
+		file, docPath := bookDocumentFile(cfg, c.Path)
+
+		var html string
+		content, err := os.ReadFile(file)
+		if err == nil {
+			html = string(renderDocument(string(content), docPath, session))
+		}
+		// Pages without a title, or directories without a page, still start
+		// a chapter
+		if !strings.Contains(html, "<h1") {
+			html = fmt.Sprintf("<h1 id=\"title\">%s</h1>\n", template.HTMLEscapeString(c.Title)) + html
+		}
+		c.HTML = rewriteBookHTML(html, c, chapterIDs)
+	}
+	return chapters
+}
+
+// bookDocumentFile returns the document.md file of the page at the URL
+// path urlPath and the path it is rendered with
+func bookDocumentFile(cfg *config.Config, urlPath string) (string, string) {
+	if urlPath == "/" {
+		// The home page lives outside the documents directory
+		return filepath.Join(cfg.Wiki.RootDir, "pages", "home", "document.md"), ""
+	}
+	docPath, err := url.PathUnescape(urlPath)
+	if err != nil {
+		docPath = urlPath
+	}
+	return filepath.Join(cfg.Wiki.RootDir, cfg.Wiki.DocumentsDir, filepath.FromSlash(docPath), "document.md"), docPath
+}
+
+// rewriteBookHTML turns the rendered HTML of a page into a chapter of a
+// book: IDs get the chapter's prefix, links to pages in the book become
+// anchors, and headings are shifted down by the chapter's depth
+func rewriteBookHTML(html string, chapter *bookChapter, chapterIDs map[string]string) string {
+	prefix := chapter.ID + "--"
+
+	html = bookIDRegex.ReplaceAllStringFunc(html, func(m string) string {
+		id := bookIDRegex.FindStringSubmatch(m)[1]
+		return ` id="` + prefix + id + `"`
+	})
+
+	html = bookHrefRegex.ReplaceAllStringFunc(html, func(m string) string {
+		href := bookHrefRegex.FindStringSubmatch(m)[1]
+		if strings.HasPrefix(href, "#") && href != "#" {
+			return ` href="#` + prefix + href[1:] + `"`
+		}
+		if !strings.HasPrefix(href, "/") || strings.HasPrefix(href, "//") {
+			return m
+		}
+
+		target, fragment, _ := strings.Cut(href, "#")
+		target, _, _ = strings.Cut(target, "?")
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
+		if target != "/" {
+			target = strings.TrimSuffix(target, "/")
+		}
+		id, ok := chapterIDs[target]
+		if !ok {
+			return m
+		}
+		if fragment != "" {
+			return ` href="#` + id + "--" + fragment + `"`
+		}
+		return ` href="#` + id + `"`
+	})
+
+	if chapter.Depth > 0 {
+		html = bookHeadingTagRegex.ReplaceAllStringFunc(html, func(m string) string {
+			parts := bookHeadingTagRegex.FindStringSubmatch(m)
+			level, _ := strconv.Atoi(parts[2])
+			return fmt.Sprintf("<%sh%d", parts[1], min(level+chapter.Depth, 6))
+		})
+	}
+	return html
+}
+
+// renderBook returns the HTML of a book: a table of contents of the
+// chapters' headings followed by the chapters
+func renderBook(chapters []bookChapter) template.HTML {
+	var headings []goldext.TOCHeading
+	for _, c := range chapters {
+		for _, m := range bookHeadingRegex.FindAllStringSubmatch(c.HTML, -1) {
+			level, _ := strconv.Atoi(m[1])
+			if level > bookTOCDepth {
+				continue
+			}
+			text := bookTagRegex.ReplaceAllString(bookAnchorRegex.ReplaceAllString(m[3], ""), "")
+			headings = append(headings, goldext.TOCHeading{Level: level, Text: strings.TrimSpace(text), ID: m[2]})
+		}
+	}
+
+	var buf strings.Builder
+	buf.WriteString("<div class=\"wiki-book\">\n")
+	buf.WriteString(goldext.RenderTOC(headings))
+	buf.WriteString("\n")
+	for _, c := range chapters {
+		fmt.Fprintf(&buf, "<section class=\"book-chapter\" id=\"%s\" data-path=\"%s\">\n%s</section>\n",
+			c.ID, template.HTMLEscapeString(c.Path), c.HTML)
+	}
+	buf.WriteString("</div>\n")
+	return template.HTML(buf.String())
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRewriteBookHTML(t *testing.T) {
+	chapterIDs := map[string]string{
+		"/guide":         bookChapterID("/guide"),
+		"/guide/install": bookChapterID("/guide/install"),
+	}
+	chapter := &bookChapter{Path: "/guide/install", Depth: 1, ID: chapterIDs["/guide/install"]}
+
+	html := `<h1 id="install">Install</h1>
+<h2 id="linux">Linux</h2>
+<p><a href="#linux">below</a>, <a href="/guide">the guide</a>, <a href="/guide/install/#linux">here</a>, <a href="/other">elsewhere</a>, <a href="https://example.com/guide">web</a></p>
+`
+	got := rewriteBookHTML(html, chapter, chapterIDs)
+	for _, want := range []string{
+		`<h2 id="book-guide-install--install">Install</h2>`,
+		`<h3 id="book-guide-install--linux">Linux</h3>`,
+		`<a href="#book-guide-install--linux">below</a>`,
+		`<a href="#book-guide">the guide</a>`,
+		`<a href="#book-guide-install--linux">here</a>`,
+		`<a href="/other">elsewhere</a>`,
+		`<a href="https://example.com/guide">web</a>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+
+	book := string(renderBook([]bookChapter{{Path: "/guide/install", ID: chapter.ID, HTML: got}}))
+	if want := `<a href="#book-guide-install--install">Install</a>`; !strings.Contains(book, want) {
+		t.Errorf("expected %q in the table of contents of %q", want, book)
+	}
+}
@@ -221,6 +221,7 @@ func SaveHandler(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	invalidateRenderCache(strings.TrimPrefix(relativePath, "documents/"))
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -352,6 +353,7 @@ func CreateDocumentHandler(w http.ResponseWriter, r *http.Request) {
 		sendJSONError(w, "Failed to create document", http.StatusInternalServerError, err.Error())
 		return
 	}
+	invalidateRenderCache(cleanPath)
 
 	// Return success
 	w.Header().Set("Content-Type", "application/json")
@@ -477,6 +479,7 @@ func DeleteDocumentHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		log.Printf("Deleted file: %s", fullPath)
 	}
+	invalidateRenderCache(strings.TrimSuffix(docPath, ".md"))
 
 	// Also delete the corresponding versions directory
 	var versionsPath string
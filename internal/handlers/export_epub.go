@@ -0,0 +1,673 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+
+	"wiki-go/internal/auth"
+	"wiki-go/internal/config"
+	"wiki-go/internal/frontmatter"
+	"wiki-go/internal/types"
+	"wiki-go/internal/utils"
+)
+
+// epubStylesheet is the stylesheet shared by the chapters of an EPUB
+const epubStylesheet = `body { font-family: serif; line-height: 1.5; }
+h1, h2, h3, h4, h5, h6 { font-family: sans-serif; line-height: 1.2; }
+pre, code { font-family: monospace; font-size: 0.9em; }
+pre { white-space: pre-wrap; padding: 0.5em; background: #f5f5f5; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #999; padding: 0.25em 0.5em; }
+img, svg { max-width: 100%; height: auto; }
+blockquote { margin-left: 1em; padding-left: 1em; border-left: 3px solid #ccc; }
+`
+
+// epubImageTypes are the core media types of EPUB images, by extension.
+// Attachments of these types shown on a page are embedded in the book.
+var epubImageTypes = map[string]string{
+	".gif":  "image/gif",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".svg":  "image/svg+xml",
+	".webp": "image/webp",
+}
+
+// epubVoidElements are the HTML elements without content or closing tag,
+// which XHTML closes with />
+var epubVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// epubDroppedElements are removed from chapters with their content: scripts,
+// embedded players and widgets that only work in a browser
+var epubDroppedElements = map[string]bool{
+	"audio": true, "button": true, "canvas": true, "dialog": true, "embed": true, "form": true,
+	"iframe": true, "noscript": true, "object": true, "script": true, "select": true, "source": true,
+	"style": true, "template": true, "textarea": true, "track": true, "video": true,
+}
+
+var (
+	// epubFragmentHrefRegex matches links to anchors, which point into other
+	// chapters once the book is split into files
+	epubFragmentHrefRegex = regexp.MustCompile(`\shref="#([^"]*)"`)
+	// epubSiteLinkRegex matches links to wiki pages outside the book and to
+	// attachments, which have no target in the book and are unwrapped
+	epubSiteLinkRegex = regexp.MustCompile(`(?s)<a\s[^>]*?href="/[^"]*"[^>]*>(.*?)</a>`)
+	// epubImageRegex, epubSrcRegex and epubAltRegex match images and their
+	// source and alternative text
+	epubImageRegex = regexp.MustCompile(`<img\s[^>]*>`)
+	epubSrcRegex   = regexp.MustCompile(`\ssrc="([^"]*)"`)
+	epubAltRegex   = regexp.MustCompile(`\salt="([^"]*)"`)
+	// epubAttrNameRegex matches attribute names that are valid in XHTML
+	epubAttrNameRegex = regexp.MustCompile(`^[A-Za-z_][-A-Za-z0-9_.]*$`)
+)
+
+// epubFile is a file in the OEBPS directory of an EPUB
+type epubFile struct {
+	ID         string
+	Name       string
+	MediaType  string
+	Properties string
+	Data       []byte
+}
+
+// epubBook collects the files of an EPUB as its chapters are converted
+type epubBook struct {
+	session *auth.Session
+	cfg     *config.Config
+	images  map[string]string // Attachment URL of each embedded image to its file in the book
+	files   []epubFile
+}
+
+// EPUBExportHandler handles GET /api/export/epub?path=/guide, returning the
+// page and the pages below it that the viewer can access as an EPUB 3 book
+// with a chapter for each page
+func EPUBExportHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Method not allowed",
+		})
+		return
+	}
+
+	root := normalizeDocPath(r.URL.Query().Get("path"))
+	session := auth.GetSession(r)
+	if !auth.CanAccessDocument(root, session, cfg) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Access denied",
+		})
+		return
+	}
+
+	nav, err := utils.BuildNavigation(cfg.Wiki.RootDir, cfg.Wiki.DocumentsDir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	nav = utils.FilterNavigation(nav, func(p string) bool {
+		return auth.CanAccessDocument(p, session, cfg)
+	})
+
+	item := &types.NavItem{Title: cfg.Wiki.Title, Path: "/", IsDir: true, Children: nav.Children}
+	if root != "/" {
+		item = utils.FindNavItem(nav, root)
+	}
+	if item == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Page not found",
+		})
+		return
+	}
+
+	data, title, err := buildEPUB(buildBook(item, session, cfg), session, cfg)
+	if err != nil {
+		http.Error(w, "Error building EPUB: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	name := slug.Make(title)
+	if name == "" {
+		name = "wiki"
+	}
+	w.Header().Set("Content-Type", "application/epub+zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.epub"`, name))
+	w.Write(data)
+}
+
+// buildEPUB packages the chapters of a book as an EPUB 3 file and returns
+// it with the book's title. The title, description and tags come from the
+// frontmatter of the first page.
+func buildEPUB(chapters []bookChapter, session *auth.Session, cfg *config.Config) ([]byte, string, error) {
+	if len(chapters) == 0 {
+		return nil, "", fmt.Errorf("no pages to export")
+	}
+
+	var metadata frontmatter.Metadata
+	file, _ := bookDocumentFile(cfg, chapters[0].Path)
+	if content, err := os.ReadFile(file); err == nil {
+		metadata, _, _ = frontmatter.Parse(string(content))
+	}
+	title := metadata.Title
+	if title == "" {
+		title = chapters[0].Title
+	}
+
+	chapterFiles := make(map[string]string, len(chapters))
+	for i, c := range chapters {
+		chapterFiles[c.ID] = fmt.Sprintf("chapter-%03d.xhtml", i+1)
+	}
+
+	book := &epubBook{session: session, cfg: cfg, images: map[string]string{}}
+	book.files = append(book.files, epubFile{ID: "style", Name: "style.css", MediaType: "text/css", Data: []byte(epubStylesheet)})
+
+	lang := cfg.Wiki.Language
+	if lang == "" {
+		lang = "en"
+	}
+
+	var modified time.Time
+	var spine []string
+	for i, c := range chapters {
+		name := chapterFiles[c.ID]
+		body := book.chapterXHTML(c, name, chapterFiles)
+
+		properties := ""
+		if strings.Contains(body, "<svg") {
+			properties = "svg"
+		}
+		id := fmt.Sprintf("chapter-%d", i+1)
+		book.files = append(book.files, epubFile{
+			ID:         id,
+			Name:       name,
+			MediaType:  "application/xhtml+xml",
+			Properties: properties,
+			Data: []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="%[1]s" lang="%[1]s">
+<head>
+<title>%[2]s</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+<section id="%[3]s" epub:type="chapter">
+%[4]s
+</section>
+</body>
+</html>
+`, html.EscapeString(lang), html.EscapeString(c.Title), html.EscapeString(c.ID), body)),
+		})
+		spine = append(spine, id)
+
+		file, _ := bookDocumentFile(cfg, c.Path)
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+	if modified.IsZero() {
+		modified = time.Now()
+	}
+
+	book.files = append(book.files, epubFile{
+		ID:         "nav",
+		Name:       "nav.xhtml",
+		MediaType:  "application/xhtml+xml",
+		Properties: "nav",
+		Data:       []byte(epubNavDocument(chapters, chapterFiles, title, lang)),
+	})
+
+	// A stable identifier lets readers recognise a new export of the same
+	// pages as a new version of the book
+	sum := sha1.Sum([]byte(cfg.Wiki.Title + "\x00" + chapters[0].Path))
+	identifier := fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+
+	var opf strings.Builder
+	fmt.Fprintf(&opf, `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="%s">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:identifier id="book-id">%s</dc:identifier>
+<dc:title>%s</dc:title>
+<dc:language>%s</dc:language>
+<dc:publisher>%s</dc:publisher>
+`, html.EscapeString(lang), identifier, html.EscapeString(title), html.EscapeString(lang), html.EscapeString(cfg.Wiki.Title))
+	if metadata.Description != "" {
+		fmt.Fprintf(&opf, "<dc:description>%s</dc:description>\n", html.EscapeString(metadata.Description))
+	}
+	for _, tag := range metadata.Tags {
+		fmt.Fprintf(&opf, "<dc:subject>%s</dc:subject>\n", html.EscapeString(tag))
+	}
+	fmt.Fprintf(&opf, "<meta property=\"dcterms:modified\">%s</meta>\n</metadata>\n<manifest>\n", modified.UTC().Format("2006-01-02T15:04:05Z"))
+	for _, f := range book.files {
+		properties := ""
+		if f.Properties != "" {
+			properties = fmt.Sprintf(` properties="%s"`, f.Properties)
+		}
+		fmt.Fprintf(&opf, "<item id=\"%s\" href=\"%s\" media-type=\"%s\"%s/>\n", f.ID, html.EscapeString(f.Name), f.MediaType, properties)
+	}
+	opf.WriteString("</manifest>\n<spine>\n")
+	for _, id := range spine {
+		fmt.Fprintf(&opf, "<itemref idref=\"%s\"/>\n", id)
+	}
+	opf.WriteString("</spine>\n</package>\n")
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	// The mimetype comes first and uncompressed so that readers can
+	// identify the file
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store, Modified: modified})
+	if err != nil {
+		return nil, "", err
+	}
+	mw.Write([]byte("application/epub+zip"))
+
+	entries := []epubFile{
+		{Name: "META-INF/container.xml", Data: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+`)},
+		{Name: "OEBPS/content.opf", Data: []byte(opf.String())},
+	}
+	for _, f := range book.files {
+		entries = append(entries, epubFile{Name: "OEBPS/" + f.Name, Data: f.Data})
+	}
+	for _, f := range entries {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := fw.Write(f.Data); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), title, nil
+}
+
+// chapterXHTML converts the HTML of a chapter written to the file name to
+// XHTML: anchors of other chapters point to their files, attachments shown
+// as images are embedded, links without a target in the book are unwrapped
+// and scripts and widgets are removed
+func (b *epubBook) chapterXHTML(c bookChapter, name string, chapterFiles map[string]string) string {
+	content := bookAnchorRegex.ReplaceAllString(c.HTML, "")
+
+	content = epubFragmentHrefRegex.ReplaceAllStringFunc(content, func(m string) string {
+		fragment := epubFragmentHrefRegex.FindStringSubmatch(m)[1]
+		owner := ""
+		for id := range chapterFiles {
+			if (fragment == id || strings.HasPrefix(fragment, id+"--")) && len(id) > len(owner) {
+				owner = id
+			}
+		}
+		if owner == "" || chapterFiles[owner] == name {
+			return m
+		}
+		return fmt.Sprintf(` href="%s#%s"`, chapterFiles[owner], fragment)
+	})
+
+	content = epubSiteLinkRegex.ReplaceAllString(content, "$1")
+
+	content = epubImageRegex.ReplaceAllStringFunc(content, func(img string) string {
+		src := epubSrcRegex.FindStringSubmatch(img)
+		if src == nil || strings.HasPrefix(src[1], "data:") {
+			return img
+		}
+		if file, ok := b.embedImage(html.UnescapeString(src[1])); ok {
+			return strings.Replace(img, src[0], fmt.Sprintf(` src="%s"`, file), 1)
+		}
+		// Images that are not in the book would need a connection
+		if alt := epubAltRegex.FindStringSubmatch(img); alt != nil {
+			return alt[1]
+		}
+		return ""
+	})
+
+	return htmlToXHTML(content)
+}
+
+// embedImage adds the attachment at the URL src to the book, if it is an
+// image on a page the viewer can access, and returns its file in the book
+func (b *epubBook) embedImage(src string) (string, bool) {
+	if file, ok := b.images[src]; ok {
+		return file, true
+	}
+
+	target, _, _ := strings.Cut(src, "?")
+	if !strings.HasPrefix(target, "/api/files/") {
+		return "", false
+	}
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	owner, name := path.Split(strings.TrimPrefix(target, "/api/files"))
+	owner = strings.TrimSuffix(owner, "/")
+
+	ext := strings.ToLower(path.Ext(name))
+	mediaType, ok := epubImageTypes[ext]
+	if !ok || name == "" || strings.Contains(owner, "..") {
+		return "", false
+	}
+
+	var file string
+	if owner == "/pages/home" {
+		if !auth.CanAccessDocument("/", b.session, b.cfg) {
+			return "", false
+		}
+		file = filepath.Join(b.cfg.Wiki.RootDir, "pages", "home", name)
+	} else {
+		if !auth.CanAccessDocument(owner, b.session, b.cfg) {
+			return "", false
+		}
+		file = filepath.Join(b.cfg.Wiki.RootDir, b.cfg.Wiki.DocumentsDir, filepath.FromSlash(owner), name)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", false
+	}
+
+	id := fmt.Sprintf("image-%d", len(b.images)+1)
+	b.images[src] = "images/" + id + ext
+	b.files = append(b.files, epubFile{ID: id, Name: b.images[src], MediaType: mediaType, Data: data})
+	return b.images[src], true
+}
+
+// epubNavDocument returns the navigation document of a book, listing the
+// chapters nested by their depth
+func epubNavDocument(chapters []bookChapter, chapterFiles map[string]string, title, lang string) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="%[1]s" lang="%[1]s">
+<head>
+<title>%[2]s</title>
+</head>
+<body>
+<nav epub:type="toc" id="toc">
+<h1>%[2]s</h1>
+<ol>
+`, html.EscapeString(lang), html.EscapeString(title))
+
+	depth := 0
+	for i, c := range chapters {
+		if i > 0 {
+			if c.Depth > depth {
+				buf.WriteString("\n<ol>\n")
+			} else {
+				buf.WriteString("</li>\n")
+				for ; depth > c.Depth; depth-- {
+					buf.WriteString("</ol>\n</li>\n")
+				}
+			}
+		}
+		depth = c.Depth
+		fmt.Fprintf(&buf, `<li><a href="%s">%s</a>`, chapterFiles[c.ID], html.EscapeString(c.Title))
+	}
+	buf.WriteString("</li>\n")
+	for ; depth > 0; depth-- {
+		buf.WriteString("</ol>\n</li>\n")
+	}
+	buf.WriteString("</ol>\n</nav>\n</body>\n</html>\n")
+	return buf.String()
+}
+
+// htmlTag is a start or end tag read from HTML
+type htmlTag struct {
+	Name        string
+	Attrs       [][2]string
+	Closing     bool
+	SelfClosing bool
+}
+
+// parseHTMLTag reads the tag at the start of s and returns it with the rest
+// of s. Attributes without a value get their name as value, as XHTML
+// requires.
+func parseHTMLTag(s string) (htmlTag, string, bool) {
+	var tag htmlTag
+	isSpace := func(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' }
+
+	j := 1
+	if j < len(s) && s[j] == '/' {
+		tag.Closing = true
+		j++
+	}
+	start := j
+	for j < len(s) && (s[j] >= 'a' && s[j] <= 'z' || s[j] >= 'A' && s[j] <= 'Z' || j > start && (s[j] >= '0' && s[j] <= '9' || s[j] == '-' || s[j] == ':')) {
+		j++
+	}
+	if j == start {
+		return tag, s, false
+	}
+	tag.Name = s[start:j]
+
+	for {
+		for j < len(s) && isSpace(s[j]) {
+			j++
+		}
+		if j >= len(s) {
+			return tag, s, false
+		}
+		if s[j] == '>' {
+			return tag, s[j+1:], true
+		}
+		if s[j] == '/' {
+			j++
+			if j < len(s) && s[j] == '>' {
+				tag.SelfClosing = true
+				return tag, s[j+1:], true
+			}
+			continue
+		}
+
+		nameStart := j
+		for j < len(s) && !isSpace(s[j]) && s[j] != '=' && s[j] != '>' && s[j] != '/' {
+			j++
+		}
+		if j == nameStart {
+			j++
+			continue
+		}
+		name := s[nameStart:j]
+		value := name
+
+		k := j
+		for k < len(s) && isSpace(s[k]) {
+			k++
+		}
+		if k < len(s) && s[k] == '=' {
+			j = k + 1
+			for j < len(s) && isSpace(s[j]) {
+				j++
+			}
+			if j >= len(s) {
+				return tag, s, false
+			}
+			if q := s[j]; q == '"' || q == '\'' {
+				end := strings.IndexByte(s[j+1:], q)
+				if end < 0 {
+					return tag, s, false
+				}
+				value = html.UnescapeString(s[j+1 : j+1+end])
+				j += end + 2
+			} else {
+				valueStart := j
+				for j < len(s) && !isSpace(s[j]) && s[j] != '>' {
+					j++
+				}
+				value = html.UnescapeString(s[valueStart:j])
+			}
+		}
+		tag.Attrs = append(tag.Attrs, [2]string{name, value})
+	}
+}
+
+// htmlToXHTML converts an HTML fragment to well-formed XHTML: void elements
+// are closed, attributes quoted, entities replaced by characters, unclosed
+// elements closed and stray end tags dropped. Scripts, event handlers and
+// the elements in epubDroppedElements are removed.
+func htmlToXHTML(s string) string {
+	var out strings.Builder
+	var open []string
+	skip, skipDepth := "", 0
+
+	text := func(t string) {
+		if skip == "" {
+			out.WriteString(html.EscapeString(html.UnescapeString(t)))
+		}
+	}
+
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			text(s)
+			break
+		}
+		text(s[:i])
+		s = s[i:]
+
+		if strings.HasPrefix(s, "<!--") {
+			end := strings.Index(s, "-->")
+			if end < 0 {
+				break
+			}
+			s = s[end+3:]
+			continue
+		}
+		if strings.HasPrefix(s, "<!") || strings.HasPrefix(s, "<?") {
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				break
+			}
+			s = s[end+1:]
+			continue
+		}
+
+		tag, rest, ok := parseHTMLTag(s)
+		if !ok {
+			text("&lt;")
+			s = s[1:]
+			continue
+		}
+		s = rest
+
+		// SVG element names are case-sensitive, HTML ones are not
+		inSVG := false
+		for _, name := range open {
+			if name == "svg" {
+				inSVG = true
+			}
+		}
+		lower := strings.ToLower(tag.Name)
+		name := lower
+		if inSVG {
+			name = tag.Name
+		}
+
+		if skip != "" {
+			if lower == skip && !tag.SelfClosing {
+				if tag.Closing {
+					skipDepth--
+				} else {
+					skipDepth++
+				}
+				if skipDepth == 0 {
+					skip = ""
+				}
+			}
+			continue
+		}
+
+		checkbox := false
+		for _, attr := range tag.Attrs {
+			if strings.EqualFold(attr[0], "type") && strings.EqualFold(attr[1], "checkbox") {
+				checkbox = true
+			}
+		}
+		if epubDroppedElements[lower] || lower == "input" && !checkbox {
+			if !tag.Closing && !tag.SelfClosing && !epubVoidElements[lower] {
+				skip, skipDepth = lower, 1
+			}
+			continue
+		}
+
+		if tag.Closing {
+			for j := len(open) - 1; j >= 0; j-- {
+				if open[j] == name {
+					for k := len(open) - 1; k >= j; k-- {
+						out.WriteString("</" + open[k] + ">")
+					}
+					open = open[:j]
+					break
+				}
+			}
+			continue
+		}
+
+		out.WriteString("<" + name)
+		seen := map[string]bool{}
+		for _, attr := range tag.Attrs {
+			attrName := attr[0]
+			if !inSVG && name != "svg" {
+				attrName = strings.ToLower(attrName)
+			}
+			switch {
+			case attrName == "xlink:href":
+				attrName = "href"
+			case attrName == "xml:lang":
+			case !epubAttrNameRegex.MatchString(attrName), strings.HasPrefix(strings.ToLower(attrName), "on"):
+				continue
+			}
+			if (attrName == "href" || attrName == "src") && strings.HasPrefix(strings.ToLower(strings.TrimSpace(attr[1])), "javascript:") {
+				continue
+			}
+			if seen[attrName] {
+				continue
+			}
+			seen[attrName] = true
+			fmt.Fprintf(&out, ` %s="%s"`, attrName, html.EscapeString(attr[1]))
+		}
+		if name == "svg" && !seen["xmlns"] {
+			out.WriteString(` xmlns="http://www.w3.org/2000/svg"`)
+		}
+
+		if tag.SelfClosing || epubVoidElements[name] && !inSVG {
+			out.WriteString("/>")
+		} else {
+			out.WriteString(">")
+			open = append(open, name)
+		}
+	}
+
+	for k := len(open) - 1; k >= 0; k-- {
+		out.WriteString("</" + open[k] + ">")
+	}
+	return out.String()
+}
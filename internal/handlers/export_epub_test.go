@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestHTMLToXHTML(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{`<p>a<br>b&nbsp;&amp; c</p><hr>`, "<p>a<br/>b\u00a0&amp; c</p><hr/>"},
+		{`<img src="x.png" alt='A "b"'>`, `<img src="x.png" alt="A &#34;b&#34;"/>`},
+		{`<li><input checked="" disabled="" type="checkbox"> done</li>`, `<li><input checked="" disabled="" type="checkbox"/> done</li>`},
+		{`<div onclick="go()">x<script>alert("</div>")</script><button>Go</button><iframe src="v"></iframe></div>`, `<div>x</div>`},
+		{`<div><p>unclosed</div></span>`, `<div><p>unclosed</p></div>`},
+		{`<details open><summary>S</summary>a < b</details>`, `<details open="open"><summary>S</summary>a &lt; b</details>`},
+		{`<svg viewBox="0 0 1 1"><linearGradient id="g"/><path d="M0"/></svg>`, `<svg viewBox="0 0 1 1" xmlns="http://www.w3.org/2000/svg"><linearGradient id="g"/><path d="M0"/></svg>`},
+		{`<a href="javascript:alert(1)">x</a><!-- note -->`, `<a>x</a>`},
+	}
+
+	for _, tt := range tests {
+		got := htmlToXHTML(tt.html)
+		if got != tt.want {
+			t.Errorf("htmlToXHTML(%q) = %q, want %q", tt.html, got, tt.want)
+		}
+
+		decoder := xml.NewDecoder(strings.NewReader("<body>" + got + "</body>"))
+		for {
+			if _, err := decoder.Token(); err != nil {
+				if err != io.EOF {
+					t.Errorf("htmlToXHTML(%q) is not well-formed: %v", tt.html, err)
+				}
+				break
+			}
+		}
+	}
+}
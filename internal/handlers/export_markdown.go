@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+
+	"wiki-go/internal/auth"
+	"wiki-go/internal/config"
+	"wiki-go/internal/frontmatter"
+	"wiki-go/internal/goldext"
+	"wiki-go/internal/types"
+	"wiki-go/internal/utils"
+)
+
+// markdownAssetsDir is the folder of a markdown export that attachments
+// are copied to
+const markdownAssetsDir = "assets"
+
+// markdownNoteNameRegex matches characters that are not allowed in note
+// names: those reserved by file systems and those with a meaning in
+// Obsidian links
+var markdownNoteNameRegex = regexp.MustCompile(`[\\/:*?"<>|#^\[\]\x00-\x1f]+`)
+
+// markdownFile is a note or attachment of a markdown export
+type markdownFile struct {
+	Name     string // Path within the export
+	Source   string // File the note or attachment is read from
+	DocPath  string // Document path of the page, for notes
+	Note     bool
+	Modified time.Time
+}
+
+// markdownVault collects the notes and attachments of a markdown export
+type markdownVault struct {
+	session *auth.Session
+	cfg     *config.Config
+	byTitle bool
+	expand  bool
+	notes   map[string]string // URL path of each exported page to its note
+	assets  map[string]string // Attachment URL of each exported attachment to its file in the export
+	used    map[string]bool   // Note names taken, in lower case
+	files   []markdownFile
+}
+
+// MarkdownExportHandler handles GET /api/export/markdown?path=/guide,
+// returning the page and the pages below it that the viewer can access as
+// a ZIP of markdown notes that can be opened as an Obsidian vault.
+// names=title names each note after its page's title instead of keeping
+// the page's path, and expand=true replaces shortcodes with what they show.
+func MarkdownExportHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Method not allowed",
+		})
+		return
+	}
+
+	query := r.URL.Query()
+	names := query.Get("names")
+	if names != "" && names != "path" && names != "title" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "names must be path or title",
+		})
+		return
+	}
+
+	root := normalizeDocPath(query.Get("path"))
+	session := auth.GetSession(r)
+	if !auth.CanAccessDocument(root, session, cfg) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Access denied",
+		})
+		return
+	}
+
+	nav, err := utils.BuildNavigation(cfg.Wiki.RootDir, cfg.Wiki.DocumentsDir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	nav = utils.FilterNavigation(nav, func(p string) bool {
+		return auth.CanAccessDocument(p, session, cfg)
+	})
+
+	item := &types.NavItem{Title: cfg.Wiki.Title, Path: "/", IsDir: true, Children: nav.Children}
+	if root != "/" {
+		item = utils.FindNavItem(nav, root)
+	}
+	if item == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Page not found",
+		})
+		return
+	}
+
+	vault := &markdownVault{
+		session: session,
+		cfg:     cfg,
+		byTitle: names == "title",
+		expand:  query.Get("expand") == "true",
+		notes:   map[string]string{},
+		assets:  map[string]string{},
+		// No note is named like the assets folder
+		used: map[string]bool{markdownAssetsDir: true},
+	}
+	vault.collect(item)
+
+	data, err := vault.build()
+	if err != nil {
+		http.Error(w, "Error building export: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	name := slug.Make(item.Title)
+	if name == "" {
+		name = "wiki"
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-markdown.zip"`, name))
+	w.Write(data)
+}
+
+// collect adds the note and attachments of item and of every page below it
+// that the viewer can access, in navigation order
+func (v *markdownVault) collect(item *types.NavItem) {
+	if !auth.CanAccessDocument(item.Path, v.session, v.cfg) {
+		return
+	}
+
+	file, docPath := bookDocumentFile(v.cfg, item.Path)
+	docPath = strings.Trim(docPath, "/")
+	filesURL := "/api/files/" + docPath
+	assetsDir := path.Join(markdownAssetsDir, docPath)
+	if item.Path == "/" {
+		filesURL = "/api/files/pages/home"
+		assetsDir = markdownAssetsDir
+	}
+
+	// Directories without a page of their own only hold the notes below them
+	if info, err := os.Stat(file); err == nil {
+		name := v.noteName(item, docPath)
+		v.notes[item.Path] = name
+		v.files = append(v.files, markdownFile{Name: name, Source: file, DocPath: docPath, Note: true, Modified: info.ModTime()})
+	}
+
+	dir := filepath.Dir(file)
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.Type().IsRegular() || name == "document.md" || strings.HasPrefix(name, ".") || !config.IsAllowedExtension(strings.ToLower(filepath.Ext(name))) {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			asset := path.Join(assetsDir, name)
+			v.assets[filesURL+"/"+name] = asset
+			v.files = append(v.files, markdownFile{Name: asset, Source: filepath.Join(dir, name), Modified: info.ModTime()})
+		}
+	}
+
+	for _, child := range item.Children {
+		v.collect(child)
+	}
+}
+
+// noteName returns the file of the note for item. Notes keep the path of
+// their page, or are named after its title with a number added when two
+// pages share a title.
+func (v *markdownVault) noteName(item *types.NavItem, docPath string) string {
+	if !v.byTitle {
+		if item.Path == "/" {
+			return "Home.md"
+		}
+		return docPath + ".md"
+	}
+
+	title := item.Title
+	if item.Path == "/" {
+		title = "Home"
+	}
+	title = strings.Trim(strings.Join(strings.Fields(markdownNoteNameRegex.ReplaceAllString(title, " ")), " "), ".")
+	if title == "" {
+		title = path.Base(docPath)
+	}
+
+	name := title
+	for n := 2; v.used[strings.ToLower(name)]; n++ {
+		name = fmt.Sprintf("%s (%d)", title, n)
+	}
+	v.used[strings.ToLower(name)] = true
+	return name + ".md"
+}
+
+// build returns the ZIP of the collected notes and attachments. The
+// frontmatter of each note is kept as it is; links to exported pages and
+// attachments are made relative to the note.
+func (v *markdownVault) build() ([]byte, error) {
+	if len(v.files) == 0 {
+		return nil, fmt.Errorf("no pages to export")
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range v.files {
+		data, err := os.ReadFile(f.Source)
+		if err != nil {
+			return nil, err
+		}
+		if f.Note {
+			data = []byte(v.note(string(data), f))
+		}
+
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: f.Modified})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// note returns the markdown of the note f with its links rewritten and, if
+// requested, its shortcodes expanded
+func (v *markdownVault) note(content string, f markdownFile) string {
+	metadata, body, _ := frontmatter.Parse(content)
+	head := ""
+	if strings.HasSuffix(content, body) {
+		head = content[:len(content)-len(body)]
+	} else {
+		body = content
+	}
+
+	rewrite := func(destination string) string {
+		return v.rewriteLink(destination, f.Name, f.DocPath)
+	}
+
+	if v.expand {
+		ctx := &goldext.RenderContext{
+			DocPath: f.DocPath,
+			CanAccess: func(p string) bool {
+				return auth.CanAccessDocument(p, v.session, v.cfg)
+			},
+			Variables: metadata.Variables,
+		}
+		body = goldext.ExpandShortcodes(body, ctx, func(shortcode string) string {
+			rendered := strings.TrimSpace(string(utils.RenderMarkdownWithContext(shortcode, &goldext.RenderContext{
+				DocPath:   ctx.DocPath,
+				CanAccess: ctx.CanAccess,
+			})))
+			if strings.HasPrefix(rendered, "<p>") && strings.HasSuffix(rendered, "</p>") && strings.Count(rendered, "<p>") == 1 {
+				rendered = strings.TrimSuffix(strings.TrimPrefix(rendered, "<p>"), "</p>")
+			}
+			return bookHrefRegex.ReplaceAllStringFunc(rendered, func(m string) string {
+				return ` href="` + rewrite(bookHrefRegex.FindStringSubmatch(m)[1]) + `"`
+			})
+		})
+	}
+
+	body = goldext.RewriteLinks(body, func(destination string, _ bool) string {
+		return rewrite(destination)
+	})
+	return head + body
+}
+
+// rewriteLink returns the destination of a link in the note named note,
+// for the page at docPath, pointing at the exported page or attachment it
+// links to relative to the note. Other links are returned unchanged.
+func (v *markdownVault) rewriteLink(destination, note, docPath string) string {
+	if strings.HasPrefix(destination, "//") || strings.Contains(destination, ":") {
+		return destination
+	}
+	target, fragment, hasFragment := strings.Cut(destination, "#")
+	target, _, _ = strings.Cut(target, "?")
+	if target == "" {
+		return destination
+	}
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+
+	var linked string
+	var ok bool
+	switch {
+	case strings.HasPrefix(target, "/api/files/"):
+		linked, ok = v.assets[target]
+	case strings.HasPrefix(target, "/"):
+		if target != "/" {
+			target = strings.TrimSuffix(target, "/")
+		}
+		linked, ok = v.notes[target]
+	default:
+		// Relative links are to the page's attachments
+		filesURL := "/api/files/" + docPath
+		if docPath == "" {
+			filesURL = "/api/files/pages/home"
+		}
+		linked, ok = v.assets[filesURL+"/"+path.Clean(target)]
+	}
+	if !ok {
+		return destination
+	}
+
+	rel, err := filepath.Rel(filepath.FromSlash(path.Dir(note)), filepath.FromSlash(linked))
+	if err != nil {
+		return destination
+	}
+	linked = (&url.URL{Path: filepath.ToSlash(rel)}).EscapedPath()
+	if hasFragment {
+		linked += "#" + fragment
+	}
+	return linked
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"testing"
+
+	"wiki-go/internal/types"
+)
+
+func TestMarkdownVaultLinks(t *testing.T) {
+	v := &markdownVault{
+		notes: map[string]string{
+			"/":              "Home.md",
+			"/guide":         "guide.md",
+			"/guide/install": "guide/install.md",
+		},
+		assets: map[string]string{
+			"/api/files/guide/install/my pic.png": "assets/guide/install/my pic.png",
+			"/api/files/pages/home/logo.png":      "assets/logo.png",
+		},
+	}
+
+	tests := []struct {
+		destination, note, docPath, want string
+	}{
+		{"/guide#setup", "guide/install.md", "guide/install", "../guide.md#setup"},
+		{"/guide/install/", "Home.md", "", "guide/install.md"},
+		{"my%20pic.png", "guide/install.md", "guide/install", "../assets/guide/install/my%20pic.png"},
+		{"/api/files/pages/home/logo.png", "guide/install.md", "guide/install", "../assets/logo.png"},
+		{"logo.png", "Home.md", "", "assets/logo.png"},
+		{"/missing", "Home.md", "", "/missing"},
+		{"https://example.com/guide", "Home.md", "", "https://example.com/guide"},
+		{"#top", "Home.md", "", "#top"},
+	}
+	for _, tt := range tests {
+		if got := v.rewriteLink(tt.destination, tt.note, tt.docPath); got != tt.want {
+			t.Errorf("rewriteLink(%q, %q) = %q, want %q", tt.destination, tt.note, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownVaultNoteNames(t *testing.T) {
+	v := &markdownVault{byTitle: true, used: map[string]bool{markdownAssetsDir: true}}
+
+	for _, tt := range []struct {
+		title, docPath, want string
+	}{
+		{"Install", "guide/install", "Install.md"},
+		{"install", "admin/install", "install (2).md"},
+		{"What is [X]? a/b", "faq", "What is X a b.md"},
+		{"Assets", "assets", "Assets (2).md"},
+	} {
+		if got := v.noteName(&types.NavItem{Title: tt.title, Path: "/" + tt.docPath}, tt.docPath); got != tt.want {
+			t.Errorf("noteName(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"wiki-go/internal/auth"
+	"wiki-go/internal/config"
+	"wiki-go/internal/frontmatter"
+	"wiki-go/internal/i18n"
+	"wiki-go/internal/resources"
+	"wiki-go/internal/types"
+	"wiki-go/internal/utils"
+)
+
+const (
+	// staticSearchIndexFile and staticSitemapFile are written to the root of
+	// a static export
+	staticSearchIndexFile = "search-index.json"
+	staticSitemapFile     = "sitemap.xml"
+)
+
+// staticLinkRegex matches the root-relative links of a rendered page, which
+// a static export rewrites to relative paths
+var staticLinkRegex = regexp.MustCompile(`\s(href|src)="(/[^"]*)"`)
+
+// StaticSearchEntry is a page in the search index of a static export
+type StaticSearchEntry struct {
+	Title string `json:"title"`
+	Path  string `json:"path"` // File of the page relative to the root of the export
+	Text  string `json:"text"` // Rendered text of the page without markup
+}
+
+// staticExport writes the pages below one path to a directory as a static
+// site
+type staticExport struct {
+	cfg   *config.Config
+	out   string
+	root  string            // URL path of the first exported page
+	nav   *types.NavItem    // Navigation of the pages anonymous visitors can access
+	files map[string]string // URL path of each exported page to its file below out
+}
+
+// ExportStatic writes the page at root and the pages below it that
+// anonymous visitors can access to outDir as a static site. Pages are
+// rendered with the same templates as the server and written as
+// path/index.html, with links between them, to their attachments and to
+// static assets rewritten to relative paths. A search index for the search
+// box and a sitemap, with URLs under baseURL when given, are written too.
+// It returns the number of pages written.
+func ExportStatic(cfg *config.Config, outDir, root, baseURL string) (int, error) {
+	root = normalizeDocPath(root)
+	if !auth.CanAccessDocument(root, nil, cfg) {
+		return 0, fmt.Errorf("%s is not accessible to anonymous visitors", root)
+	}
+
+	nav, err := utils.BuildNavigation(cfg.Wiki.RootDir, cfg.Wiki.DocumentsDir)
+	if err != nil {
+		return 0, err
+	}
+	nav = utils.FilterNavigation(nav, func(p string) bool {
+		return auth.CanAccessDocument(p, nil, cfg)
+	})
+
+	// Below the home page, the exported page becomes the only top-level
+	// entry of the sidebar
+	rootItem := nav
+	if root != "/" {
+		rootItem = utils.FindNavItem(nav, root)
+		if rootItem == nil {
+			return 0, fmt.Errorf("page %s not found", root)
+		}
+		nav = &types.NavItem{Title: nav.Title, Path: "/", IsDir: true, Children: []*types.NavItem{rootItem}}
+	}
+
+	e := &staticExport{cfg: cfg, out: outDir, root: root, nav: nav, files: map[string]string{"/": "index.html"}}
+
+	var pages []*types.NavItem
+	if root == "/" {
+		pages = append(pages, &types.NavItem{Title: "Home", Path: "/", IsDir: true})
+		for _, child := range rootItem.Children {
+			pages = e.collectPages(child, pages)
+		}
+	} else {
+		pages = e.collectPages(rootItem, pages)
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return 0, err
+	}
+
+	var index []StaticSearchEntry
+	var urls []SitemapURL
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	for _, item := range pages {
+		file := e.files[item.Path]
+		content, lastModified, err := e.writePage(item, file)
+		if err != nil {
+			return 0, fmt.Errorf("exporting %s: %w", item.Path, err)
+		}
+
+		index = append(index, StaticSearchEntry{
+			Title: item.Title,
+			Path:  file,
+			Text:  staticSearchText(content),
+		})
+
+		loc := strings.TrimSuffix((&url.URL{Path: file}).EscapedPath(), "index.html")
+		if baseURL != "" {
+			loc = baseURL + "/" + loc
+		} else if loc == "" {
+			loc = "./"
+		}
+		urls = append(urls, SitemapURL{Location: loc, LastMod: lastModified.Format(time.RFC3339)})
+	}
+
+	if err := e.copyStaticAssets(); err != nil {
+		return 0, err
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, staticSearchIndexFile), data, 0644); err != nil {
+		return 0, err
+	}
+
+	data, err = xml.MarshalIndent(Sitemap{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9", URLs: urls}, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, staticSitemapFile), append([]byte(xml.Header), data...), 0644); err != nil {
+		return 0, err
+	}
+
+	return len(pages), nil
+}
+
+// collectPages appends item and the pages below it to pages, in navigation
+// order, and assigns each its file in the export
+func (e *staticExport) collectPages(item *types.NavItem, pages []*types.NavItem) []*types.NavItem {
+	rel := strings.Trim(strings.TrimPrefix(item.Path, e.root), "/")
+	if e.root == "/" {
+		rel = strings.Trim(item.Path, "/")
+	}
+	e.files[item.Path] = path.Join(rel, "index.html")
+
+	pages = append(pages, item)
+	for _, child := range item.Children {
+		pages = e.collectPages(child, pages)
+	}
+	return pages
+}
+
+// writePage renders the page of item as a visitor without a session sees
+// it, writes it to file with its attachments, and returns the rendered
+// document and when it was last modified
+func (e *staticExport) writePage(item *types.NavItem, file string) (template.HTML, time.Time, error) {
+	docPath, err := url.PathUnescape(item.Path)
+	if err != nil {
+		docPath = item.Path
+	}
+	dir := filepath.Join(e.cfg.Wiki.RootDir, e.cfg.Wiki.DocumentsDir, filepath.FromSlash(docPath))
+	renderPath := docPath
+	if item.Path == "/" {
+		dir = filepath.Join(e.cfg.Wiki.RootDir, "pages", "home")
+		renderPath = ""
+	}
+
+	var content, dirContent template.HTML
+	var lastModified time.Time
+	layout := ""
+	if md, err := os.ReadFile(filepath.Join(dir, "document.md")); err == nil {
+		if metadata, _, ok := frontmatter.Parse(string(md)); ok {
+			layout = metadata.Layout
+		}
+		content = renderDocument(string(md), renderPath, nil)
+		if strings.TrimSpace(string(content)) == "" {
+			content = template.HTML(" ")
+		}
+		if info, err := os.Stat(filepath.Join(dir, "document.md")); err == nil {
+			lastModified = info.ModTime()
+		}
+	} else {
+		var dirItems []string
+		for _, child := range item.Children {
+			dirItems = append(dirItems, fmt.Sprintf(`<div class="directory-item is-dir"><a href="%s">%s</a></div>`, child.Path, child.Title))
+		}
+		dirContent = template.HTML(strings.Join(dirItems, "\n"))
+		if info, err := os.Stat(dir); err == nil {
+			lastModified = info.ModTime()
+		}
+	}
+
+	nav := utils.FilterNavigation(e.nav, func(string) bool { return true })
+	utils.MarkActiveNavItem(nav, item.Path)
+
+	// Breadcrumbs above the exported pages have nowhere to point
+	var breadcrumbs []types.BreadcrumbItem
+	for _, b := range generateBreadcrumbs(nav, item.Path) {
+		if _, ok := e.files[b.Path]; ok {
+			breadcrumbs = append(breadcrumbs, b)
+		}
+	}
+
+	currentDir := item
+	if item.Path == "/" {
+		currentDir = &types.NavItem{Title: "Home", Path: "/", IsDir: true, IsActive: true}
+	}
+	currentDir.DocumentLayout = layout
+
+	tmpl, err := getTemplate()
+	if err != nil {
+		return "", lastModified, err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, &types.PageData{
+		Navigation:         &types.NavTree{Root: nav, AlwaysOpen: e.cfg.Wiki.AlwaysOpenChildrenInSidebar},
+		Content:            content,
+		DirContent:         dirContent,
+		Breadcrumbs:        breadcrumbs,
+		Config:             e.cfg,
+		LastModified:       lastModified,
+		CurrentDir:         currentDir,
+		AvailableLanguages: i18n.GetAvailableLanguages(),
+		DocPath:            renderPath,
+		DocumentLayout:     layout,
+		IsStaticExport:     true,
+	})
+	if err != nil {
+		return "", lastModified, err
+	}
+
+	target := filepath.Join(e.out, filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", lastModified, err
+	}
+	if err := os.WriteFile(target, []byte(e.rewriteLinks(buf.String(), file)), 0644); err != nil {
+		return "", lastModified, err
+	}
+
+	// Attachments sit next to the page so that relative links reach them
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return content, lastModified, nil
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || name == "document.md" || strings.HasPrefix(name, ".") || !config.IsAllowedExtension(strings.ToLower(filepath.Ext(name))) {
+			continue
+		}
+		if err := copyFile(filepath.Join(dir, name), filepath.Join(filepath.Dir(target), name)); err != nil {
+			return "", lastModified, err
+		}
+	}
+	return content, lastModified, nil
+}
+
+// rewriteLinks points the root-relative links of the page written to file
+// at the exported pages, attachments and static assets, relative to file.
+// Links to anything else that is not part of the export are left alone.
+func (e *staticExport) rewriteLinks(page, file string) string {
+	return staticLinkRegex.ReplaceAllStringFunc(page, func(m string) string {
+		parts := staticLinkRegex.FindStringSubmatch(m)
+		href := html.UnescapeString(parts[2])
+		if strings.HasPrefix(href, "//") {
+			return m
+		}
+
+		target, fragment, hasFragment := strings.Cut(href, "#")
+		target, _, _ = strings.Cut(target, "?")
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
+
+		linked, ok := e.exportedFile(target)
+		if !ok {
+			return m
+		}
+
+		rel, err := filepath.Rel(filepath.FromSlash(path.Dir(file)), filepath.FromSlash(linked))
+		if err != nil {
+			return m
+		}
+		link := (&url.URL{Path: filepath.ToSlash(rel)}).String()
+		if hasFragment {
+			link += "#" + fragment
+		}
+		return fmt.Sprintf(` %s="%s"`, parts[1], html.EscapeString(link))
+	})
+}
+
+// exportedFile returns the file below the export root that a root-relative
+// URL path is written to, if it is part of the export
+func (e *staticExport) exportedFile(target string) (string, bool) {
+	switch {
+	case strings.HasPrefix(target, "/static/"):
+		return strings.TrimPrefix(target, "/"), true
+	case target == "/"+staticSearchIndexFile:
+		return staticSearchIndexFile, true
+	case strings.HasPrefix(target, "/api/files/"):
+		owner, name := path.Split(strings.TrimPrefix(target, "/api/files"))
+		owner = strings.TrimSuffix(owner, "/")
+		if owner == "/pages/home" {
+			owner = "/"
+		}
+		if page, ok := e.files[owner]; ok && name != "" {
+			return path.Join(path.Dir(page), name), true
+		}
+		return "", false
+	}
+
+	if target != "/" {
+		target = strings.TrimSuffix(target, "/")
+	}
+	page, ok := e.files[target]
+	return page, ok
+}
+
+// staticSearchText returns the text of a rendered page for the search
+// index, without markup or heading permalinks
+func staticSearchText(content template.HTML) string {
+	text := bookTagRegex.ReplaceAllString(bookAnchorRegex.ReplaceAllString(string(content), ""), " ")
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
+
+// copyStaticAssets writes the embedded static files to the static directory
+// of the export, followed by the files customised in data/static
+func (e *staticExport) copyStaticAssets() error {
+	staticDir := filepath.Join(e.out, "static")
+	err := fs.WalkDir(resources.GetStaticFS(), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(resources.GetStaticFS(), name)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(staticDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+	if err != nil {
+		return err
+	}
+
+	customDir := filepath.Join(e.cfg.Wiki.RootDir, "static")
+	err = filepath.Walk(customDir, func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(customDir, name)
+		if err != nil {
+			return err
+		}
+		return copyFile(name, filepath.Join(staticDir, rel))
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// The server generates the allowed file types script on request
+	script := fmt.Sprintf("// File extensions configuration - dynamically generated\nvar ALLOWED_FILE_EXTENSIONS = %s;\nvar FILE_EXTENSION_MIME_TYPES = %s;\n",
+		config.GetAllowedExtensionsJSON(), config.GetExtensionMimeTypesJSON())
+	return os.WriteFile(filepath.Join(staticDir, "js", "file-extensions.js"), []byte(script), 0644)
+}
+
+// copyFile copies the file at src to dst, creating the directories of dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
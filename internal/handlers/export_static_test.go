@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStaticExportRewriteLinks(t *testing.T) {
+	e := &staticExport{
+		root: "/public",
+		files: map[string]string{
+			"/":               "index.html",
+			"/public":         "index.html",
+			"/public/install": "install/index.html",
+		},
+	}
+
+	page := `<link rel="stylesheet" href="/static/css/theme.css?=1.0">
+<a href="/public">up</a> <a href="/public/install/#linux">install</a> <a href="/secret">secret</a>
+<img src="/api/files/public/install/diagram%20v2.png"> <a href="//cdn.example.com/x">cdn</a> <a href="#top">top</a>`
+	got := e.rewriteLinks(page, "install/index.html")
+	for _, want := range []string{
+		`href="../static/css/theme.css"`,
+		`<a href="../index.html">up</a>`,
+		`<a href="index.html#linux">install</a>`,
+		`<a href="/secret">secret</a>`,
+		`<img src="diagram%20v2.png">`,
+		`<a href="//cdn.example.com/x">cdn</a>`,
+		`<a href="#top">top</a>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"wiki-go/internal/auth"
+	"wiki-go/internal/config"
+	"wiki-go/internal/frontmatter"
+	"wiki-go/internal/goldext"
+	"wiki-go/internal/i18n"
+	"wiki-go/internal/resources"
+	"wiki-go/internal/types"
+	"wiki-go/internal/utils"
+)
+
+// Default and maximum number of hops from the root page in the graph
+const (
+	defaultGraphDepth = 2
+	maxGraphDepth     = 10
+)
+
+// GraphNode represents a page in the knowledge graph
+type GraphNode struct {
+	ID     string   `json:"id"` // Document path
+	Title  string   `json:"title"`
+	Tags   []string `json:"tags"`
+	Layout string   `json:"layout,omitempty"`
+}
+
+// GraphEdge represents a relationship between two pages
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"` // "child", "link" or "include"
+}
+
+// GraphResponse is the JSON payload returned by the graph API
+type GraphResponse struct {
+	Root  string      `json:"root,omitempty"`
+	Depth int         `json:"depth"`
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphPage holds the data for the graph view template
+type GraphPage struct {
+	Title      string
+	Config     *config.Config
+	Root       string
+	Depth      int
+	GraphTitle string
+	BackToHome string
+}
+
+// GraphHandler handles GET /api/graph?root=/path&depth=2 and returns the
+// pages around root and the relationships between them
+func GraphHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Method not allowed",
+		})
+		return
+	}
+
+	session := auth.GetSession(r)
+
+	root := r.URL.Query().Get("root")
+	if root != "" {
+		root = normalizeDocPath(root)
+		if !auth.CanAccessDocument(root, session, cfg) {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"message": "Access denied",
+			})
+			return
+		}
+	}
+
+	depth := parseGraphDepth(r.URL.Query().Get("depth"))
+
+	graph, err := buildGraph(cfg, session)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Failed to build graph",
+		})
+		return
+	}
+
+	if root != "" {
+		graph = neighbourhood(graph, root, depth)
+		graph.Root = root
+	}
+	graph.Depth = depth
+
+	json.NewEncoder(w).Encode(graph)
+}
+
+// GraphPageHandler renders the interactive page relationship view
+func GraphPageHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	root := r.URL.Query().Get("root")
+	if root != "" {
+		root = normalizeDocPath(root)
+	}
+
+	data := GraphPage{
+		Title:      fmt.Sprintf("%s - %s", i18n.Translate("graph.title"), cfg.Wiki.Title),
+		Config:     cfg,
+		Root:       root,
+		Depth:      parseGraphDepth(r.URL.Query().Get("depth")),
+		GraphTitle: i18n.Translate("graph.title"),
+		BackToHome: i18n.Translate("nav.back_to_home"),
+	}
+
+	tmpl, err := template.ParseFS(resources.GetTemplatesFS(), "templates/graph.html")
+	if err != nil {
+		http.Error(w, "Error parsing graph template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering graph template: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// parseGraphDepth parses the depth query parameter, clamping it to a sane range
+func parseGraphDepth(value string) int {
+	depth, err := strconv.Atoi(value)
+	if err != nil || depth < 0 {
+		return defaultGraphDepth
+	}
+	if depth > maxGraphDepth {
+		return maxGraphDepth
+	}
+	return depth
+}
+
+// buildGraph builds the full page graph visible to the session: the
+// navigation hierarchy plus links and includes between documents
+func buildGraph(cfg *config.Config, session *auth.Session) (*GraphResponse, error) {
+	nav, err := utils.BuildNavigation(cfg.Wiki.RootDir, cfg.Wiki.DocumentsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	nav = utils.FilterNavigation(nav, func(p string) bool {
+		return auth.CanAccessDocument(p, session, cfg)
+	})
+
+	graph := &GraphResponse{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	known := make(map[string]bool)
+	contents := make(map[string]string)
+
+	// Collect nodes and hierarchy edges from the navigation tree
+	var walk func(item *types.NavItem, parent string)
+	walk = func(item *types.NavItem, parent string) {
+		title := item.Title
+		if item.Path == "/" {
+			title = i18n.Translate("nav.home")
+		}
+
+		node := GraphNode{ID: item.Path, Title: title, Tags: []string{}}
+		if content, err := os.ReadFile(graphDocumentFile(cfg, item.Path)); err == nil {
+			contents[item.Path] = string(content)
+			if metadata, _, ok := frontmatter.Parse(string(content)); ok {
+				node.Layout = metadata.Layout
+				if metadata.Tags != nil {
+					node.Tags = metadata.Tags
+				}
+			}
+		}
+
+		graph.Nodes = append(graph.Nodes, node)
+		known[item.Path] = true
+
+		if parent != "" {
+			graph.Edges = append(graph.Edges, GraphEdge{Source: parent, Target: item.Path, Type: "child"})
+		}
+
+		for _, child := range item.Children {
+			walk(child, item.Path)
+		}
+	}
+
+	if auth.CanAccessDocument("/", session, cfg) {
+		walk(nav, "")
+	} else {
+		for _, child := range nav.Children {
+			walk(child, "")
+		}
+	}
+
+	// Add link and include edges between visible documents
+	for _, node := range graph.Nodes {
+		content, ok := contents[node.ID]
+		if !ok {
+			continue
+		}
+
+		for _, inc := range goldext.FindIncludes(content) {
+			if known[inc.Path] && inc.Path != node.ID {
+				graph.Edges = append(graph.Edges, GraphEdge{Source: node.ID, Target: inc.Path, Type: "include"})
+			}
+		}
+
+		for _, link := range goldext.FindDocumentLinks(content) {
+			if known[link] && link != node.ID {
+				graph.Edges = append(graph.Edges, GraphEdge{Source: node.ID, Target: link, Type: "link"})
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+// neighbourhood returns the subgraph of nodes reachable from root within
+// depth hops, following edges in either direction
+func neighbourhood(graph *GraphResponse, root string, depth int) *GraphResponse {
+	adjacent := make(map[string][]string)
+	for _, edge := range graph.Edges {
+		adjacent[edge.Source] = append(adjacent[edge.Source], edge.Target)
+		adjacent[edge.Target] = append(adjacent[edge.Target], edge.Source)
+	}
+
+	distance := map[string]int{root: 0}
+	queue := []string{root}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if distance[current] >= depth {
+			continue
+		}
+		for _, next := range adjacent[current] {
+			if _, seen := distance[next]; !seen {
+				distance[next] = distance[current] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	result := &GraphResponse{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	for _, node := range graph.Nodes {
+		if _, ok := distance[node.ID]; ok {
+			result.Nodes = append(result.Nodes, node)
+		}
+	}
+	for _, edge := range graph.Edges {
+		_, sourceOK := distance[edge.Source]
+		_, targetOK := distance[edge.Target]
+		if sourceOK && targetOK {
+			result.Edges = append(result.Edges, edge)
+		}
+	}
+
+	return result
+}
+
+// graphDocumentFile returns the document.md path for a navigation path
+func graphDocumentFile(cfg *config.Config, path string) string {
+	if path == "/" {
+		return filepath.Join(cfg.Wiki.RootDir, "pages", "home", "document.md")
+	}
+	return filepath.Join(cfg.Wiki.RootDir, cfg.Wiki.DocumentsDir, filepath.FromSlash(strings.TrimPrefix(path, "/")), "document.md")
+}
@@ -3,13 +3,33 @@ package handlers
 import (
 	"log"
 	"wiki-go/internal/config"
+	"wiki-go/internal/goldext"
 	"wiki-go/internal/i18n"
+	"wiki-go/internal/types"
+	"wiki-go/internal/utils"
 )
 
 var cfg *config.Config
 
 // InitHandlers initializes the handlers with the given configuration
 func InitHandlers(config *config.Config) {
+	InitRenderer(config)
+
+	// Initialise IP-based ban list for login attempts
+	InitLoginBan(cfg)
+
+	// Load dead-link check results and start periodic checks if enabled
+	InitLinkChecker(cfg)
+
+	// Watch user shortcode templates for changes
+	WatchUserShortcodes(cfg)
+
+	// Routes are now managed in the routes package
+}
+
+// InitRenderer sets up what rendering pages needs, without the background
+// work of the server, for commands such as export-static that run once
+func InitRenderer(config *config.Config) {
 	cfg = config
 
 	// Initialize i18n package
@@ -17,10 +37,19 @@ func InitHandlers(config *config.Config) {
 		log.Printf("Warning: Failed to initialize i18n package: %v", err)
 	}
 
-	// Initialise IP-based ban list for login attempts
-	InitLoginBan(cfg)
+	// Give the page listing shortcodes the same page tree as the sidebar
+	goldext.SetNavigationProvider(func() (*types.NavItem, error) {
+		return utils.BuildNavigation(cfg.Wiki.RootDir, cfg.Wiki.DocumentsDir)
+	})
 
-	// Routes are now managed in the routes package
+	// Load the terms of glossary pages
+	goldext.RefreshGlossary()
+
+	// Load user shortcode templates
+	InitUserShortcodes(cfg)
+
+	// Create the rendered document cache if enabled
+	InitRenderCache(cfg)
 }
 
 // We don't need this anymore since main.go handles the routing
@@ -38,7 +38,7 @@ If you're running LeoMoon Wiki-Go without SSL/HTTPS and experiencing login issue
 ### Content Management
 - **Markdown Support**: Write content using Markdown syntax for rich formatting
 - **Emoji Shortcodes**: Use emoji shortcodes like ` + "`:::smile:::`" + ` in your Markdown content
-- **File Attachments**: Upload and manage images and documents (supports jpg, jpeg, png, gif, svg, txt, log, csv, sfd, zip, pdf, docx, xlsx, pptx, mp4)
+- **File Attachments**: Upload and manage images and documents (supports jpg, jpeg, png, gif, svg, txt, log, csv, sfd, bib, zip, pdf, docx, xlsx, pptx, mp4)
 - **Hierarchical Organization**: Organize content in nested directories
 - **Version History**: Track changes with full revision history and restore previous versions
 - **Document Management**: Create, edit, and delete documents with a user-friendly interface
@@ -455,6 +455,15 @@ LeoMoon Wiki-Go supports special shortcodes for dynamic content:
 ` + "```" + `
 
 These shortcodes display document statistics like total count or recent changes.
+
+**Include Shortcode**:
+` + "```" + `
+:::include /path/to/page:::
+
+:::include /path/to/page#section-heading:::
+` + "```" + `
+
+Embeds another page, or a single section of it, into the current page. Readers only see included pages they have access to.
 `
 
 // EnsureHomepageExists creates the default homepage if it doesn't exist
@@ -579,7 +588,7 @@ func HomeHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
 	}
 
 	// Render the markdown content
-	renderedContent := template.HTML(utils.RenderMarkdown(string(content)))
+	renderedContent := renderDocument(string(content), "", session)
 	
 	// If content is empty but home document exists, ensure we have something truthy for template conditions
 	if strings.TrimSpace(string(renderedContent)) == "" {
@@ -8,13 +8,16 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"sync"
 	"time"
 	"wiki-go/internal/auth"
 	"wiki-go/internal/config"
+	"wiki-go/internal/frontmatter"
+
+	"github.com/gosimple/slug"
 )
 
 // ImportResponse represents the response for the import API
@@ -41,6 +44,7 @@ type ImportStatusResponse struct {
 type ImportedFile struct {
 	OriginalPath string `json:"originalPath"`
 	NewPath      string `json:"newPath"`
+	Attachments  int    `json:"attachments,omitempty"` // Assets copied to the page's attachments
 }
 
 // importJobs stores the status of all import jobs
@@ -207,6 +211,9 @@ func processImportFromBytes(zipFileBytes []byte, jobID string, cfg *config.Confi
 		return
 	}
 
+	// Index the files so that links between them can be rewritten
+	archive := newImportArchive(zipReader.File, cfg.Wiki.Language)
+
 	// Count total files for progress calculation
 	var totalFiles int
 	var markdownFiles int
@@ -244,7 +251,7 @@ func processImportFromBytes(zipFileBytes []byte, jobID string, cfg *config.Confi
 		}
 
 		// Process the markdown file
-		err := processMarkdownFile(file, jobID, cfg)
+		err := processMarkdownFile(file, archive, jobID, cfg)
 		if err != nil {
 			// Add error but continue processing other files
 			addImportError(jobID, fmt.Sprintf("Error processing %s: %v", file.Name, err))
@@ -270,8 +277,9 @@ func processImportFromBytes(zipFileBytes []byte, jobID string, cfg *config.Confi
 	}
 }
 
-// processMarkdownFile processes a single markdown file from the ZIP
-func processMarkdownFile(file *zip.File, jobID string, cfg *config.Config) error {
+// processMarkdownFile processes a single markdown file from the ZIP, along
+// with the assets it links to or that sit next to it
+func processMarkdownFile(file *zip.File, archive *importArchive, jobID string, cfg *config.Config) error {
 	// Open the file from the ZIP
 	fileReader, err := file.Open()
 	if err != nil {
@@ -287,11 +295,18 @@ func processMarkdownFile(file *zip.File, jobID string, cfg *config.Config) error
 
 	// Determine the target path based on the file's path in the ZIP
 	originalPath := file.Name
-	targetPath, err := determineTargetPath(originalPath)
-	if err != nil {
-		return fmt.Errorf("failed to determine target path: %v", err)
+	name, _ := cleanArchivePath(originalPath)
+	targetPath, ok := archive.targets[name]
+	if !ok {
+		return fmt.Errorf("invalid path in ZIP")
 	}
 
+	// Point links to other imported files at their new paths
+	rewritten, assets := archive.rewriteLinks(name, string(content))
+	// Slugs lose the original name, so it becomes the title
+	rewritten = withTitleHeading(rewritten, strings.TrimSuffix(path.Base(name), path.Ext(name)))
+	content = []byte(rewritten)
+
 	// Create the full path to the document directory
 	docDir := filepath.Join(cfg.Wiki.RootDir, cfg.Wiki.DocumentsDir, targetPath)
 
@@ -309,6 +324,7 @@ func processMarkdownFile(file *zip.File, jobID string, cfg *config.Config) error
 	if err != nil {
 		return fmt.Errorf("failed to write file: %v", err)
 	}
+	invalidateRenderCache(targetPath)
 	
 	// Explicitly set permissions to ensure it's readable and writable
 	err = os.Chmod(docPath, 0644)
@@ -316,46 +332,63 @@ func processMarkdownFile(file *zip.File, jobID string, cfg *config.Config) error
 		return fmt.Errorf("failed to set file permissions: %v", err)
 	}
 
+	// Copy the page's assets to its attachments. A rejected asset is
+	// reported but does not fail the page.
+	attachments := 0
+	for _, asset := range assets {
+		if err := importAttachment(archive.files[asset.Source], filepath.Join(docDir, asset.Name), cfg); err != nil {
+			addImportError(jobID, fmt.Sprintf("Error importing %s: %v", asset.Source, err))
+			continue
+		}
+		attachments++
+	}
+
 	// Add to successful imports
-	addImportedFile(jobID, originalPath, "/"+targetPath)
+	addImportedFile(jobID, originalPath, "/"+targetPath, attachments)
 
 	return nil
 }
 
-// determineTargetPath converts an original file path to a target path
-func determineTargetPath(originalPath string) (string, error) {
-	// Remove file extension
-	pathWithoutExt := strings.TrimSuffix(originalPath, filepath.Ext(originalPath))
-
-	// Split path into components
-	components := strings.Split(pathWithoutExt, "/")
-
-	// Process each component
-	for i, component := range components {
-		// Normalize the component
-		normalized := normalizePathComponent(component)
-		components[i] = normalized
+// normalizePathComponent turns a file or folder name into a path segment
+// the way page slugs are made from titles, transliterating other scripts
+// with the substitutions of the wiki's language
+func normalizePathComponent(component, lang string) string {
+	var segment string
+	if lang != "" {
+		segment = slug.MakeLang(component, lang)
+	} else {
+		segment = slug.Make(component)
 	}
-
-	// Join components back together
-	targetPath := strings.Join(components, "/")
-
-	return targetPath, nil
+	if segment == "" {
+		segment = "untitled"
+	}
+	return segment
 }
 
-// normalizePathComponent normalizes a path component
-func normalizePathComponent(component string) string {
-	// Convert to lowercase
-	component = strings.ToLower(component)
-
-	// Replace spaces with dashes
-	component = strings.ReplaceAll(component, " ", "-")
-
-	// Remove special characters
-	reg := regexp.MustCompile(`[^a-z0-9-]`)
-	component = reg.ReplaceAllString(component, "")
+// withTitleHeading returns the markdown of an imported page with a title
+// heading of its original name added after any frontmatter, unless the
+// page already has one outside code blocks
+func withTitleHeading(content, title string) string {
+	inCodeBlock := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if !inCodeBlock && strings.HasPrefix(trimmed, "# ") {
+			return content
+		}
+	}
 
-	return component
+	_, body, _ := frontmatter.Parse(content)
+	head := ""
+	if strings.HasSuffix(content, body) {
+		head = content[:len(content)-len(body)]
+	} else {
+		body = content
+	}
+	return head + "# " + title + "\n\n" + body
 }
 
 // updateImportStatus updates the status of an import job
@@ -392,7 +425,7 @@ func updateImportStatusFile(jobID, currentFile string) {
 }
 
 // addImportedFile adds a successfully imported file to the job status
-func addImportedFile(jobID, originalPath, newPath string) {
+func addImportedFile(jobID, originalPath, newPath string, attachments int) {
 	importJobsMutex.Lock()
 	defer importJobsMutex.Unlock()
 
@@ -400,6 +433,7 @@ func addImportedFile(jobID, originalPath, newPath string) {
 		job.ImportedFiles = append(job.ImportedFiles, ImportedFile{
 			OriginalPath: originalPath,
 			NewPath:      newPath,
+			Attachments:  attachments,
 		})
 		job.SuccessCount++
 	}
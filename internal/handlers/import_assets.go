@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"wiki-go/internal/config"
+	"wiki-go/internal/goldext"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// importArchive indexes the files of a ZIP being imported, so that links
+// between them can be followed to the pages and attachments they become
+type importArchive struct {
+	files     map[string]*zip.File // Each file by its cleaned path in the ZIP
+	targets   map[string]string    // Each markdown file to the document path it is imported as
+	colocated map[string][]string  // Each markdown file to the assets next to it that no page links to
+	segments  map[string]string    // Each folder or page name, by its parent's path, to its path segment
+	taken     map[string]bool      // Document paths given to a folder or page
+}
+
+// importAsset is a file of the ZIP copied to a page's attachments
+type importAsset struct {
+	Source string // Path in the ZIP
+	Name   string // Name of the attachment
+}
+
+// newImportArchive indexes files, whose names are made into paths with
+// the slug substitutions of lang. Assets no page links to belong to the
+// page of the folder they are in: a markdown file named like the folder,
+// or else the only markdown file in the folder.
+func newImportArchive(files []*zip.File, lang string) *importArchive {
+	a := &importArchive{
+		files:     make(map[string]*zip.File),
+		targets:   make(map[string]string),
+		colocated: make(map[string][]string),
+		segments:  make(map[string]string),
+		taken:     make(map[string]bool),
+	}
+
+	var names []string
+	markdownByDir := make(map[string][]string)
+	for _, file := range files {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		name, ok := cleanArchivePath(file.Name)
+		if !ok {
+			continue
+		}
+		a.files[name] = file
+		names = append(names, name)
+		if strings.HasSuffix(strings.ToLower(name), ".md") {
+			a.targets[name] = a.targetPath(name, lang)
+			markdownByDir[path.Dir(name)] = append(markdownByDir[path.Dir(name)], name)
+		}
+	}
+
+	linked := make(map[string]bool)
+	for _, name := range names {
+		if _, ok := a.targets[name]; !ok {
+			continue
+		}
+		content, err := readArchiveFile(a.files[name])
+		if err != nil {
+			continue
+		}
+		goldext.RewriteLinks(string(content), func(destination string, _ bool) string {
+			if target, ok := a.resolve(name, destination); ok {
+				linked[target] = true
+			}
+			return destination
+		})
+	}
+
+	for _, name := range names {
+		if _, ok := a.targets[name]; ok || linked[name] || isHiddenArchivePath(name) {
+			continue
+		}
+		dir := path.Dir(name)
+		owner := ""
+		for _, md := range markdownByDir[path.Dir(dir)] {
+			if strings.TrimSuffix(md, path.Ext(md)) == dir {
+				owner = md
+			}
+		}
+		if owner == "" && len(markdownByDir[dir]) == 1 {
+			owner = markdownByDir[dir][0]
+		}
+		if owner != "" {
+			a.colocated[owner] = append(a.colocated[owner], name)
+		}
+	}
+	return a
+}
+
+// targetPath returns the document path the markdown file name is imported
+// as. A page and the folder of its subpages share a path segment; other
+// names that make the same segment as an earlier one in the same folder get
+// a numbered suffix.
+func (a *importArchive) targetPath(name, lang string) string {
+	target := ""
+	for _, component := range strings.Split(strings.TrimSuffix(name, path.Ext(name)), "/") {
+		// Names differing only in case or Unicode normalization, as ZIPs
+		// made on macOS store them, are the same folder
+		key := target + "/" + strings.ToLower(norm.NFC.String(component))
+		segment, ok := a.segments[key]
+		if !ok {
+			base := normalizePathComponent(component, lang)
+			segment = base
+			for n := 2; a.taken[path.Join(target, segment)]; n++ {
+				segment = fmt.Sprintf("%s-%d", base, n)
+			}
+			a.segments[key] = segment
+			a.taken[path.Join(target, segment)] = true
+		}
+		target = path.Join(target, segment)
+	}
+	return target
+}
+
+// cleanArchivePath returns the path of a file in a ZIP relative to its
+// root, and false for paths that leave it
+func cleanArchivePath(name string) (string, bool) {
+	name = path.Clean(strings.TrimPrefix(strings.ReplaceAll(name, "\\", "/"), "/"))
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	return name, true
+}
+
+// isHiddenArchivePath reports whether a file in a ZIP is metadata added by
+// the tool that made it, such as .DS_Store or __MACOSX, rather than content
+func isHiddenArchivePath(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
+
+// readArchiveFile returns the content of a file in a ZIP
+func readArchiveFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// resolve returns the path in the ZIP of the file a link in the markdown
+// file from points to, if it is part of the ZIP
+func (a *importArchive) resolve(from, destination string) (string, bool) {
+	target, _, _ := strings.Cut(destination, "#")
+	target, _, _ = strings.Cut(target, "?")
+	if target == "" || strings.HasPrefix(target, "//") || strings.Contains(target, ":") {
+		return "", false
+	}
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	if !strings.HasPrefix(target, "/") {
+		target = path.Join(path.Dir(from), target)
+	}
+	target, ok := cleanArchivePath(target)
+	if !ok {
+		return "", false
+	}
+	if _, ok := a.files[target]; !ok {
+		return "", false
+	}
+	return target, true
+}
+
+// rewriteLinks points the links of the markdown file name at the wiki
+// paths of the pages and attachments they link to, and returns the assets
+// to copy to the page's attachments: those it links to and those next to
+// it that no page links to
+func (a *importArchive) rewriteLinks(name, markdown string) (string, []importAsset) {
+	var assets []importAsset
+	attachments := make(map[string]string)
+	taken := make(map[string]bool)
+	attach := func(source string) string {
+		if attachment, ok := attachments[source]; ok {
+			return attachment
+		}
+		base := sanitizeFilename(path.Base(source))
+		ext := path.Ext(base)
+		attachment := base
+		for n := 2; taken[strings.ToLower(attachment)]; n++ {
+			attachment = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(base, ext), n, ext)
+		}
+		taken[strings.ToLower(attachment)] = true
+		attachments[source] = attachment
+		assets = append(assets, importAsset{Source: source, Name: attachment})
+		return attachment
+	}
+
+	markdown = goldext.RewriteLinks(markdown, func(destination string, _ bool) string {
+		linked, ok := a.resolve(name, destination)
+		if !ok {
+			return destination
+		}
+		_, fragment, hasFragment := strings.Cut(destination, "#")
+
+		var link string
+		if target, ok := a.targets[linked]; ok {
+			link = (&url.URL{Path: "/" + target}).EscapedPath()
+		} else {
+			// Relative links on a page are to its attachments
+			link = (&url.URL{Path: attach(linked)}).EscapedPath()
+		}
+		if hasFragment {
+			link += "#" + fragment
+		}
+		return link
+	})
+
+	for _, source := range a.colocated[name] {
+		attach(source)
+	}
+	return markdown, assets
+}
+
+// importAttachment writes the asset file of a ZIP to dst, subject to the
+// same checks as uploaded attachments: an allowed file type, content that
+// matches it, and the maximum upload size. SVG files are sanitized.
+func importAttachment(file *zip.File, dst string, cfg *config.Config) error {
+	ext := strings.ToLower(filepath.Ext(dst))
+	if !cfg.Wiki.DisableFileUploadChecking && !config.IsAllowedExtension(ext) {
+		return fmt.Errorf("file type not allowed")
+	}
+	if file.UncompressedSize64 > uint64(config.GetMaxUploadSizeBytes(cfg)) {
+		return fmt.Errorf("file larger than %s", config.GetMaxUploadSizeFormatted(cfg))
+	}
+
+	content, err := readArchiveFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %v", err)
+	}
+
+	if !cfg.Wiki.DisableFileUploadChecking {
+		buffer := content[:min(len(content), 8192)]
+		detected, err := detectFileContentType(buffer, filepath.Base(dst))
+		if err != nil {
+			return fmt.Errorf("failed to detect content type: %v", err)
+		}
+		if !isContentTypeCompatible(detected, config.GetMimeTypeForExtension(ext), buffer, filepath.Base(dst)) {
+			return fmt.Errorf("content does not match the file type")
+		}
+		if ext == ".svg" {
+			if content, err = sanitizeSVG(content); err != nil {
+				return fmt.Errorf("failed to sanitize SVG: %v", err)
+			}
+		}
+	}
+
+	return os.WriteFile(dst, content, 0644)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestImportArchiveRewriteLinks(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := map[string]string{
+		"Guide.md":                 "# Guide\n\nSee [Install](Guide/Install.md#linux) and ![Logo](img/logo.png).\n",
+		"Guide/Install.md":         "![Shot](../img/my%20shot.png) ![Logo](/img/logo.png) [Back](../Guide.md) [Web](https://example.com/a.md)\n\n```\n[code](../Guide.md)\n```\n",
+		"Guide/diagram.svg":        "<svg/>",
+		"img/logo.png":             "png",
+		"img/my shot.png":          "png",
+		"Notes/One.md":             "# One\n",
+		"Notes/Two.md":             "# Two\n",
+		"Notes/unclaimed.pdf":      "pdf",
+		"__MACOSX/Guide/._Install": "",
+	}
+	for _, name := range []string{"Guide.md", "Guide/Install.md", "Guide/diagram.svg", "img/logo.png", "img/my shot.png", "Notes/One.md", "Notes/Two.md", "Notes/unclaimed.pdf", "__MACOSX/Guide/._Install"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(files[name]))
+	}
+	zw.Close()
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := newImportArchive(zr.File, "en")
+
+	got, assets := archive.rewriteLinks("Guide.md", files["Guide.md"])
+	if want := "# Guide\n\nSee [Install](/guide/install#linux) and ![Logo](logo.png).\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	// The SVG sits in the folder of Guide.md and nothing links to it
+	if want := []importAsset{{"img/logo.png", "logo.png"}, {"Guide/diagram.svg", "diagram.svg"}}; !reflect.DeepEqual(assets, want) {
+		t.Errorf("expected assets %v, got %v", want, assets)
+	}
+
+	got, assets = archive.rewriteLinks("Guide/Install.md", files["Guide/Install.md"])
+	if want := "![Shot](my_shot.png) ![Logo](logo.png) [Back](/guide) [Web](https://example.com/a.md)\n\n```\n[code](../Guide.md)\n```\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if want := []importAsset{{"img/my shot.png", "my_shot.png"}, {"img/logo.png", "logo.png"}}; !reflect.DeepEqual(assets, want) {
+		t.Errorf("expected assets %v, got %v", want, assets)
+	}
+
+	// Assets in a folder of several pages without a folder page stay behind
+	if _, assets = archive.rewriteLinks("Notes/One.md", files["Notes/One.md"]); len(assets) != 0 {
+		t.Errorf("expected no assets, got %v", assets)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+func TestImportTargetPaths(t *testing.T) {
+	names := []string{
+		"Руководство/Установка.md",
+		"日本語メモ.md",
+		"Café.md",
+		"Cafe\u0301/Menu.md", // The same name as the page above, decomposed
+		"Cafe.md",
+		"Guide.md",
+		"guide/Install.md",
+		"!!!.md",
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		if _, err := zw.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	zw.Close()
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := newImportArchive(zr.File, "en")
+
+	want := map[string]string{
+		"Руководство/Установка.md": "rukovodstvo/ustanovka",
+		"日本語メモ.md":                 "ri-ben-yu-memo",
+		"Café.md":                  "cafe",
+		"Cafe\u0301/Menu.md":       "cafe/menu",
+		"Cafe.md":                  "cafe-2",
+		"Guide.md":                 "guide",
+		"guide/Install.md":         "guide/install",
+		"!!!.md":                   "untitled",
+	}
+	for name, target := range want {
+		if got := archive.targets[name]; got != target {
+			t.Errorf("target of %q = %q, want %q", name, got, target)
+		}
+	}
+}
+
+func TestWithTitleHeading(t *testing.T) {
+	tests := []struct {
+		content, want string
+	}{
+		{"Some text\n", "# Руководство\n\nSome text\n"},
+		{"---\ntags: [a]\n---\nSome text\n", "---\ntags: [a]\n---\n# Руководство\n\nSome text\n"},
+		{"Intro\n\n# Existing\n", "Intro\n\n# Existing\n"},
+		{"```sh\n# install deps\nmake\n```\n", "# Руководство\n\n```sh\n# install deps\nmake\n```\n"},
+	}
+	for _, tt := range tests {
+		if got := withTitleHeading(tt.content, "Руководство"); got != tt.want {
+			t.Errorf("withTitleHeading(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
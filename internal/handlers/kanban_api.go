@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"wiki-go/internal/auth"
+	"wiki-go/internal/frontmatter"
+	"wiki-go/internal/roles"
+	"wiki-go/internal/utils"
+)
+
+// kanbanMoveCoalesceWindow is how long after a move further moves on the same
+// document are saved without creating another version, so dragging cards
+// around produces a single version entry
+const kanbanMoveCoalesceWindow = 2 * time.Minute
+
+var (
+	// kanbanMutex serializes read-modify-write cycles on kanban documents
+	kanbanMutex sync.Mutex
+
+	// kanbanLastMove records when each document was last changed by a move
+	kanbanLastMove = make(map[string]time.Time)
+)
+
+// KanbanRequest represents the JSON payload for kanban operations. Boards,
+// columns and tasks are addressed by their index in the document.
+type KanbanRequest struct {
+	Action   string `json:"action"`
+	Board    int    `json:"board"`
+	Column   int    `json:"column"`
+	Task     int    `json:"task"`
+	Expect   string `json:"expect,omitempty"`   // Current task markdown, rejects changes to a stale board
+	Text     string `json:"text,omitempty"`     // Task text or column title
+	Index    *int   `json:"index,omitempty"`    // Position of a new task, appended if omitted
+	Lane     string `json:"lane,omitempty"`     // Swimlane of a new or moved task
+	Checked  bool   `json:"checked,omitempty"`  // New state for check_task
+	ToBoard  *int   `json:"to_board,omitempty"` // Target board of a move, defaults to board
+	ToColumn int    `json:"to_column"`
+	ToIndex  int    `json:"to_index"` // Position of the moved task in the target column
+	Indent   int    `json:"indent"`   // Indentation level of the moved task
+	Order    []int  `json:"order,omitempty"`
+}
+
+// KanbanTaskView is the JSON representation of a kanban task
+type KanbanTaskView struct {
+	Text      string   `json:"text"`
+	Checked   bool     `json:"checked"`
+	Indent    int      `json:"indent"`
+	Lane      string   `json:"lane,omitempty"`
+	Assignees []string `json:"assignees,omitempty"`
+	Labels    []string `json:"labels,omitempty"`
+	Due       string   `json:"due,omitempty"`
+	Completed string   `json:"completed,omitempty"`
+	Priority  string   `json:"priority,omitempty"`
+}
+
+// KanbanColumnView is the JSON representation of a kanban column
+type KanbanColumnView struct {
+	Title    string           `json:"title"`
+	Name     string           `json:"name"`
+	WIPLimit int              `json:"wip_limit,omitempty"`
+	Done     bool             `json:"done,omitempty"`
+	Tasks    []KanbanTaskView `json:"tasks"`
+}
+
+// KanbanBoardView is the JSON representation of a kanban board
+type KanbanBoardView struct {
+	Title   string             `json:"title"`
+	Lanes   []string           `json:"lanes,omitempty"`
+	Columns []KanbanColumnView `json:"columns"`
+}
+
+// KanbanHandler handles /api/kanban/{doc}. GET returns the boards of a kanban
+// document; POST applies a single operation to them and writes the document
+// back in canonical form. GET /api/kanban/{doc}/export downloads the boards.
+func KanbanHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		sendKanbanError(w, "Method not allowed", http.StatusMethodNotAllowed, "")
+		return
+	}
+
+	if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/export") {
+		exportKanbanDocument(w, r)
+		return
+	}
+
+	path, docPath, ok := resolveDocumentRequest(w, r, "/api/kanban/")
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		content, ok := readKanbanDocument(w, docPath)
+		if !ok {
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"boards":  kanbanBoardViews(frontmatter.ParseKanbanDocument(content).Boards),
+		})
+		return
+	}
+
+	session := auth.GetSession(r)
+	if session == nil || (session.Role != roles.RoleAdmin && session.Role != roles.RoleEditor) {
+		sendKanbanError(w, "Unauthorized. Admin or editor access required.", http.StatusUnauthorized, "")
+		return
+	}
+
+	var req KanbanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendKanbanError(w, "Invalid request payload", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	kanbanMutex.Lock()
+	defer kanbanMutex.Unlock()
+
+	content, ok := readKanbanDocument(w, docPath)
+	if !ok {
+		return
+	}
+
+	doc := frontmatter.ParseKanbanDocument(content)
+	if err := applyKanbanRequest(doc, req); err != nil {
+		switch {
+		case errors.Is(err, frontmatter.ErrKanbanConflict):
+			sendKanbanError(w, "The board has changed, reload the page and try again", http.StatusConflict, err.Error())
+		case errors.Is(err, frontmatter.ErrKanbanNotFound):
+			sendKanbanError(w, "Board, column or task not found", http.StatusNotFound, err.Error())
+		case errors.Is(err, frontmatter.ErrKanbanWIPLimit):
+			sendKanbanError(w, "The column is at its WIP limit", http.StatusUnprocessableEntity, err.Error())
+		default:
+			sendKanbanError(w, "Invalid kanban operation", http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	if updated := doc.Markdown(); updated != content {
+		// Moves in quick succession share the version created by the first one
+		now := time.Now()
+		isMove := req.Action == "move_task"
+		if !isMove || now.Sub(kanbanLastMove[docPath]) > kanbanMoveCoalesceWindow {
+			createDocumentVersion(docPath, path)
+		}
+		if isMove {
+			kanbanLastMove[docPath] = now
+		} else {
+			delete(kanbanLastMove, docPath)
+		}
+
+		if err := utils.WriteFileAtomic(docPath, []byte(updated), 0644); err != nil {
+			sendKanbanError(w, "Failed to save document", http.StatusInternalServerError, err.Error())
+			return
+		}
+		invalidateRenderCache(path)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Board updated",
+		"boards":  kanbanBoardViews(doc.Boards),
+	})
+}
+
+// applyKanbanRequest applies a kanban operation to a parsed document
+func applyKanbanRequest(doc *frontmatter.KanbanDocument, req KanbanRequest) error {
+	switch req.Action {
+	case "add_task":
+		index := -1
+		if req.Index != nil {
+			index = *req.Index
+		}
+		return doc.AddTask(req.Board, req.Column, index, req.Lane, req.Text)
+	case "edit_task":
+		return doc.EditTask(req.Board, req.Column, req.Task, req.Expect, req.Text)
+	case "check_task":
+		return doc.SetTaskChecked(req.Board, req.Column, req.Task, req.Expect, req.Checked)
+	case "delete_task":
+		return doc.DeleteTask(req.Board, req.Column, req.Task, req.Expect)
+	case "move_task":
+		toBoard := req.Board
+		if req.ToBoard != nil {
+			toBoard = *req.ToBoard
+		}
+		return doc.MoveTask(req.Board, req.Column, req.Task, req.Expect, toBoard, req.ToColumn, req.ToIndex, req.Indent, req.Lane)
+	case "add_column":
+		return doc.AddColumn(req.Board, req.Text)
+	case "rename_column":
+		return doc.RenameColumn(req.Board, req.Column, req.Text)
+	case "delete_column":
+		return doc.DeleteColumn(req.Board, req.Column)
+	case "reorder_columns":
+		return doc.ReorderColumns(req.Board, req.Order)
+	default:
+		return errors.New("unknown action: " + req.Action)
+	}
+}
+
+// readKanbanDocument reads a document and checks that it uses the kanban
+// layout. It sends an error response and returns false otherwise.
+func readKanbanDocument(w http.ResponseWriter, docPath string) (string, bool) {
+	data, err := os.ReadFile(docPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			sendKanbanError(w, "Document not found", http.StatusNotFound, "")
+		} else {
+			sendKanbanError(w, "Failed to read document", http.StatusInternalServerError, err.Error())
+		}
+		return "", false
+	}
+
+	content := string(data)
+	metadata, _, _ := frontmatter.Parse(content)
+	if metadata.Layout != "kanban" {
+		sendKanbanError(w, "Document is not a kanban board", http.StatusBadRequest, "")
+		return "", false
+	}
+
+	return content, true
+}
+
+// kanbanBoardViews converts boards to their JSON representation
+func kanbanBoardViews(boards []frontmatter.KanbanBoard) []KanbanBoardView {
+	views := make([]KanbanBoardView, 0, len(boards))
+	for _, board := range boards {
+		boardView := KanbanBoardView{Title: board.Title, Lanes: board.Lanes, Columns: []KanbanColumnView{}}
+		for _, column := range board.Columns {
+			columnView := KanbanColumnView{
+				Title:    column.Title,
+				Name:     column.Name,
+				WIPLimit: column.WIPLimit,
+				Done:     column.Done,
+				Tasks:    []KanbanTaskView{},
+			}
+			for _, task := range column.Tasks {
+				taskView := KanbanTaskView{
+					Text:      frontmatter.KanbanTaskSource(task.Text),
+					Checked:   task.Checked,
+					Indent:    task.IndentLevel,
+					Lane:      task.Lane,
+					Assignees: task.Assignees,
+					Labels:    task.Labels,
+					Priority:  task.Priority,
+				}
+				if !task.Due.IsZero() {
+					taskView.Due = task.Due.Format("2006-01-02")
+				}
+				if !task.Completed.IsZero() {
+					taskView.Completed = task.Completed.Format("2006-01-02")
+				}
+				columnView.Tasks = append(columnView.Tasks, taskView)
+			}
+			boardView.Columns = append(boardView.Columns, columnView)
+		}
+		views = append(views, boardView)
+	}
+	return views
+}
+
+// sendKanbanError sends a JSON error response for kanban operations
+func sendKanbanError(w http.ResponseWriter, message string, statusCode int, err string) {
+	response := map[string]interface{}{
+		"success": false,
+		"message": message,
+	}
+	if err != "" {
+		response["error"] = err
+	}
+
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
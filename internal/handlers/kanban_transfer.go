@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"wiki-go/internal/auth"
+	"wiki-go/internal/frontmatter"
+	"wiki-go/internal/utils"
+)
+
+// KanbanImportHandler handles POST /api/import/kanban. It creates a new
+// kanban document from an uploaded Trello board export or CSV file. The
+// document path is given by the path query parameter; title and format
+// (trello or csv) are optional, the format is detected from the file if
+// omitted.
+func KanbanImportHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		sendKanbanError(w, "Method not allowed", http.StatusMethodNotAllowed, "")
+		return
+	}
+
+	// Authentication is handled by editorMiddleware, so we can proceed directly
+
+	cleanPath := utils.SanitizePath(strings.Trim(r.URL.Query().Get("path"), "/"))
+	if cleanPath == "" {
+		sendKanbanError(w, "Path is required", http.StatusBadRequest, "")
+		return
+	}
+
+	if !auth.CanAccessDocument("/"+cleanPath, auth.GetSession(r), cfg) {
+		sendKanbanError(w, "Access denied", http.StatusForbidden, "")
+		return
+	}
+
+	upload, err := readImportUpload(w, r)
+	if err != nil {
+		sendKanbanError(w, "Failed to read import file", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+		if strings.HasPrefix(strings.TrimSpace(upload), "{") {
+			format = "trello"
+		}
+	}
+
+	title := strings.TrimSpace(r.URL.Query().Get("title"))
+	defaultName := title
+	if defaultName == "" {
+		defaultName = path.Base(cleanPath)
+	}
+
+	var boards []frontmatter.KanbanBoard
+	switch format {
+	case "trello":
+		var name string
+		name, boards, err = frontmatter.ImportTrelloBoard([]byte(upload), defaultName)
+		if title == "" {
+			title = name
+		}
+	case "csv":
+		boards, err = frontmatter.ImportKanbanCSV([]byte(upload), defaultName)
+	default:
+		sendKanbanError(w, "Unsupported import format", http.StatusBadRequest, format)
+		return
+	}
+	if err != nil {
+		sendKanbanError(w, "Failed to import board", http.StatusBadRequest, err.Error())
+		return
+	}
+	if title == "" {
+		title = defaultName
+	}
+
+	fullPath := filepath.Join(cfg.Wiki.RootDir, cfg.Wiki.DocumentsDir, cleanPath)
+	docFile := filepath.Join(fullPath, "document.md")
+	if _, err := os.Stat(docFile); err == nil {
+		sendKanbanError(w, "Document already exists", http.StatusConflict, "")
+		return
+	}
+
+	if err := os.MkdirAll(fullPath, 0755); err != nil {
+		sendKanbanError(w, "Failed to create directories", http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	content := frontmatter.NewKanbanMarkdown(title, boards)
+	if err := utils.WriteFileAtomic(docFile, []byte(content), 0644); err != nil {
+		sendKanbanError(w, "Failed to create document", http.StatusInternalServerError, err.Error())
+		return
+	}
+	invalidateRenderCache(cleanPath)
+
+	tasks := 0
+	for _, board := range boards {
+		for _, column := range board.Columns {
+			tasks += len(column.Tasks)
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"url":     "/" + cleanPath,
+		"message": fmt.Sprintf("Imported %d boards with %d tasks", len(boards), tasks),
+	})
+}
+
+// exportKanbanDocument handles GET /api/kanban/{doc}/export. It returns the
+// boards of a kanban document as JSON, or as CSV with ?format=csv, in the
+// format read by KanbanImportHandler.
+func exportKanbanDocument(w http.ResponseWriter, r *http.Request) {
+	docRequest := r.Clone(r.Context())
+	docRequest.URL.Path = strings.TrimSuffix(r.URL.Path, "/export")
+
+	relPath, docPath, ok := resolveDocumentRequest(w, docRequest, "/api/kanban/")
+	if !ok {
+		return
+	}
+
+	content, ok := readKanbanDocument(w, docPath)
+	if !ok {
+		return
+	}
+	boards := frontmatter.ParseKanbanBoards(content)
+
+	name := path.Base(relPath) + "-kanban"
+
+	if r.URL.Query().Get("format") == "csv" {
+		data, err := frontmatter.ExportKanbanCSV(boards)
+		if err != nil {
+			sendKanbanError(w, "Failed to export board", http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, name))
+		w.Write(data)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, name))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"title":   extractTitle(content),
+		"boards":  kanbanBoardViews(boards),
+	})
+}
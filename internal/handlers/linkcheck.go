@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"wiki-go/internal/config"
+	"wiki-go/internal/frontmatter"
+	"wiki-go/internal/goldext"
+)
+
+// Link checker tuning
+const (
+	linkCheckTimeout = 10 * time.Second
+	linkCheckWorkers = 4
+)
+
+// LinkCheckResult records the outcome of checking a single external URL
+type LinkCheckResult struct {
+	URL         string    `json:"url"`
+	StatusCode  int       `json:"status_code"`
+	RedirectURL string    `json:"redirect_url,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Dead        bool      `json:"dead"`
+	CheckedAt   time.Time `json:"checked_at"`
+	Pages       []string  `json:"pages"` // Documents containing the URL
+}
+
+// LinkChecker periodically checks external links and keeps the latest results
+type LinkChecker struct {
+	mu        sync.RWMutex
+	results   map[string]*LinkCheckResult
+	storePath string
+	client    *http.Client
+	running   bool
+	lastRun   time.Time
+}
+
+// linkChecker is the shared checker used by the links renderer and admin report
+var linkChecker *LinkChecker
+
+// NewLinkChecker creates a link checker that persists its results to storePath.
+// Previously stored results are loaded if present.
+func NewLinkChecker(storePath string) *LinkChecker {
+	lc := &LinkChecker{
+		results:   make(map[string]*LinkCheckResult),
+		storePath: storePath,
+		client:    newFetchClient(linkCheckTimeout),
+	}
+
+	if data, err := os.ReadFile(storePath); err == nil {
+		var stored []*LinkCheckResult
+		if err := json.Unmarshal(data, &stored); err == nil {
+			for _, result := range stored {
+				lc.results[result.URL] = result
+				if result.CheckedAt.After(lc.lastRun) {
+					lc.lastRun = result.CheckedAt
+				}
+			}
+		}
+	}
+
+	return lc
+}
+
+// InitLinkChecker creates the shared link checker, registers it with the links
+// renderer and, when enabled in the configuration, starts periodic checks
+func InitLinkChecker(cfg *config.Config) {
+	linkChecker = NewLinkChecker(filepath.Join(cfg.Wiki.RootDir, "temp", "linkcheck.json"))
+
+	frontmatter.SetLinkStatusProvider(func(url string) (frontmatter.LinkStatus, bool) {
+		result, ok := linkChecker.Result(url)
+		if !ok {
+			return frontmatter.LinkStatus{}, false
+		}
+		return frontmatter.LinkStatus{
+			Dead:        result.Dead,
+			StatusCode:  result.StatusCode,
+			Error:       result.Error,
+			RedirectURL: result.RedirectURL,
+			CheckedAt:   result.CheckedAt,
+		}, true
+	})
+
+	if !cfg.LinkChecker.Enabled {
+		return
+	}
+
+	interval := time.Duration(cfg.LinkChecker.IntervalHours) * time.Hour
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+
+	go func() {
+		// Catch up right away if the last run is older than the interval
+		if time.Since(linkChecker.LastRun()) >= interval {
+			linkChecker.Run(collectExternalLinks(cfg))
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			linkChecker.Run(collectExternalLinks(cfg))
+		}
+	}()
+}
+
+// Result returns the last check result for a URL
+func (lc *LinkChecker) Result(url string) (LinkCheckResult, bool) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	result, ok := lc.results[url]
+	if !ok {
+		return LinkCheckResult{}, false
+	}
+	return *result, true
+}
+
+// Results returns all check results, dead links first
+func (lc *LinkChecker) Results() []LinkCheckResult {
+	lc.mu.RLock()
+	results := make([]LinkCheckResult, 0, len(lc.results))
+	for _, result := range lc.results {
+		results = append(results, *result)
+	}
+	lc.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Dead != results[j].Dead {
+			return results[i].Dead
+		}
+		return results[i].URL < results[j].URL
+	})
+	return results
+}
+
+// Running reports whether a check is in progress
+func (lc *LinkChecker) Running() bool {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.running
+}
+
+// LastRun returns when the last check finished
+func (lc *LinkChecker) LastRun() time.Time {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.lastRun
+}
+
+// Run checks every URL in targets, a map of URL to the documents containing
+// it, and replaces the stored results. It returns false without checking if a
+// run is already in progress.
+func (lc *LinkChecker) Run(targets map[string][]string) bool {
+	if !lc.begin() {
+		return false
+	}
+	lc.check(targets)
+	return true
+}
+
+// begin marks a check as running, returning false if one already is
+func (lc *LinkChecker) begin() bool {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if lc.running {
+		return false
+	}
+	lc.running = true
+	return true
+}
+
+// check performs a check started with begin and stores the results
+func (lc *LinkChecker) check(targets map[string][]string) {
+	urls := make(chan string)
+	results := make(chan *LinkCheckResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < linkCheckWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range urls {
+				result := checkLink(lc.client, url)
+				result.Pages = targets[url]
+				results <- result
+			}
+		}()
+	}
+
+	go func() {
+		for url := range targets {
+			urls <- url
+		}
+		close(urls)
+		wg.Wait()
+		close(results)
+	}()
+
+	checked := make(map[string]*LinkCheckResult, len(targets))
+	dead := 0
+	for result := range results {
+		checked[result.URL] = result
+		if result.Dead {
+			dead++
+		}
+	}
+
+	lc.mu.Lock()
+	lc.results = checked
+	lc.lastRun = time.Now()
+	lc.running = false
+	lc.mu.Unlock()
+
+	if err := lc.save(); err != nil {
+		log.Printf("Warning: Failed to save link check results: %v", err)
+	}
+
+	// Link status badges in cached pages are now out of date
+	renderCache.InvalidateLinkStatus()
+
+	log.Printf("Link check finished: %d links checked, %d dead", len(checked), dead)
+}
+
+// save writes the results to the store file
+func (lc *LinkChecker) save() error {
+	data, err := json.MarshalIndent(lc.Results(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(lc.storePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(lc.storePath, data, 0644)
+}
+
+// checkLink checks a single URL with a HEAD request, falling back to GET for
+// servers that reject HEAD. Redirects are followed and the final URL recorded.
+func checkLink(client *http.Client, url string) *LinkCheckResult {
+	result := &LinkCheckResult{URL: url, CheckedAt: time.Now()}
+
+	resp, err := doLinkRequest(client, http.MethodHead, url)
+	if err != nil || resp.StatusCode >= 400 {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		resp, err = doLinkRequest(client, http.MethodGet, url)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.Dead = true
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	if final := resp.Request.URL.String(); final != url {
+		result.RedirectURL = final
+	}
+
+	// Rate limiting says nothing about whether the link works
+	result.Dead = resp.StatusCode >= 400 && resp.StatusCode != http.StatusTooManyRequests
+	return result
+}
+
+// doLinkRequest performs a single link check request
+func doLinkRequest(client *http.Client, method, url string) (*http.Response, error) {
+	req, err := newFetchRequest(method, url)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// collectExternalLinks gathers the external URLs to check, mapped to the
+// documents containing them. Links in links-layout pages are always
+// included; links in other pages only when check_pages is enabled.
+func collectExternalLinks(cfg *config.Config) map[string][]string {
+	targets := make(map[string][]string)
+	add := func(url, page string) {
+		for _, existing := range targets[url] {
+			if existing == page {
+				return
+			}
+		}
+		targets[url] = append(targets[url], page)
+	}
+
+	forEachDocument(cfg, func(urlPath string, content string) {
+		metadata, _, hasFrontmatter := frontmatter.Parse(content)
+		if hasFrontmatter && metadata.Layout == "links" {
+			linksData, err := frontmatter.ParseLinksContent(content)
+			if err != nil {
+				return
+			}
+			for _, links := range linksData.Categories {
+				for _, link := range links {
+					add(link.URL, urlPath)
+				}
+			}
+			return
+		}
+
+		if cfg.LinkChecker.CheckPages {
+			for _, url := range goldext.FindExternalLinks(content) {
+				add(url, urlPath)
+			}
+		}
+	})
+
+	return targets
+}
+
+// LinkCheckReportHandler handles the admin dead-link report. GET returns the
+// latest results, POST starts a new check in the background.
+func LinkCheckReportHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if linkChecker == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Link checker is not initialized",
+		})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		results := linkChecker.Results()
+		dead := 0
+		for _, result := range results {
+			if result.Dead {
+				dead++
+			}
+		}
+
+		// Only dead links unless all results are requested
+		if r.URL.Query().Get("all") != "true" {
+			results = results[:dead]
+		}
+
+		var lastRun *time.Time
+		if t := linkChecker.LastRun(); !t.IsZero() {
+			lastRun = &t
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":  true,
+			"enabled":  cfg.LinkChecker.Enabled,
+			"running":  linkChecker.Running(),
+			"last_run": lastRun,
+			"dead":     dead,
+			"results":  results,
+		})
+
+	case http.MethodPost:
+		if !linkChecker.begin() {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"message": "A link check is already running",
+			})
+			return
+		}
+
+		go linkChecker.check(collectExternalLinks(cfg))
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Link check started",
+		})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Method not allowed",
+		})
+	}
+}
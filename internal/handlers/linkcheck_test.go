@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func newLinkCheckTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCheckLink(t *testing.T) {
+	server := newLinkCheckTestServer()
+	defer server.Close()
+
+	client := newFetchClient(linkCheckTimeout)
+
+	tests := []struct {
+		path     string
+		dead     bool
+		status   int
+		redirect string
+	}{
+		{"/ok", false, http.StatusOK, ""},
+		{"/gone", true, http.StatusNotFound, ""},
+		{"/moved", false, http.StatusOK, server.URL + "/ok"},
+		{"/no-head", false, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		result := checkLink(client, server.URL+tt.path)
+		if result.Dead != tt.dead || result.StatusCode != tt.status || result.RedirectURL != tt.redirect {
+			t.Errorf("%s: got dead=%v status=%d redirect=%q", tt.path, result.Dead, result.StatusCode, result.RedirectURL)
+		}
+		if result.CheckedAt.IsZero() {
+			t.Errorf("%s: check time not recorded", tt.path)
+		}
+	}
+
+	// Unreachable hosts are dead with an error
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	if result := checkLink(client, closed.URL); !result.Dead || result.Error == "" {
+		t.Errorf("unreachable host: got dead=%v error=%q", result.Dead, result.Error)
+	}
+}
+
+func TestLinkCheckerRunAndPersist(t *testing.T) {
+	server := newLinkCheckTestServer()
+	defer server.Close()
+
+	storePath := filepath.Join(t.TempDir(), "linkcheck.json")
+	checker := NewLinkChecker(storePath)
+
+	targets := map[string][]string{
+		server.URL + "/ok":   {"/links"},
+		server.URL + "/gone": {"/links", "/other"},
+	}
+	if !checker.Run(targets) {
+		t.Fatal("expected run to start")
+	}
+
+	results := checker.Results()
+	if len(results) != 2 || !results[0].Dead || results[0].URL != server.URL+"/gone" {
+		t.Fatalf("expected dead link first, got %+v", results)
+	}
+	if len(results[0].Pages) != 2 {
+		t.Errorf("expected pages to be recorded, got %v", results[0].Pages)
+	}
+
+	// Results survive a restart
+	reloaded := NewLinkChecker(storePath)
+	if result, ok := reloaded.Result(server.URL + "/gone"); !ok || !result.Dead {
+		t.Errorf("expected stored dead result, got %+v (found=%v)", result, ok)
+	}
+	if reloaded.LastRun().IsZero() {
+		t.Error("expected last run to be restored")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+	"wiki-go/internal/auth"
 	"wiki-go/internal/frontmatter"
 	"wiki-go/internal/utils"
 )
@@ -42,16 +43,8 @@ func AddLinkHandler(w http.ResponseWriter, r *http.Request) {
 	// Authentication is handled by editorMiddleware, so we can proceed directly
 
 	// Get document path from URL
-	path := strings.TrimPrefix(r.URL.Path, "/api/links/add/")
-	if path == "" {
-		sendLinkError(w, "Document path is required", http.StatusBadRequest, "")
-		return
-	}
-
-	// Decode URL-encoded path
-	decodedPath, err := url.QueryUnescape(path)
-	if err != nil {
-		sendLinkError(w, "Invalid document path", http.StatusBadRequest, err.Error())
+	path, docPath, ok := resolveDocumentRequest(w, r, "/api/links/add/")
+	if !ok {
 		return
 	}
 
@@ -68,20 +61,9 @@ func AddLinkHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get document path
-	docPath := getDocumentPath(decodedPath)
-	
-	// Read current document
-	content, err := os.ReadFile(docPath)
-	if err != nil {
-		sendLinkError(w, "Failed to read document", http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	// Parse current content to get links data
-	linksData, err := frontmatter.ParseLinksContent(string(content))
-	if err != nil {
-		sendLinkError(w, "Failed to parse links document", http.StatusBadRequest, err.Error())
+	// Read current document, which must be a links page
+	content, linksData, ok := readLinksDocument(w, docPath)
+	if !ok {
 		return
 	}
 
@@ -95,13 +77,10 @@ func AddLinkHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Add link to the appropriate category
-	if linksData.Categories == nil {
-		linksData.Categories = make(map[string][]frontmatter.Link)
-	}
-	linksData.Categories[req.Category] = append(linksData.Categories[req.Category], newLink)
+	linksData.AddLink(newLink)
 
 	// Generate updated markdown
-	updatedContent, err := generateLinksMarkdown(linksData, string(content))
+	updatedContent, err := generateLinksMarkdown(linksData, content)
 	if err != nil {
 		sendLinkError(w, "Failed to generate updated content", http.StatusInternalServerError, err.Error())
 		return
@@ -133,16 +112,8 @@ func EditLinkHandler(w http.ResponseWriter, r *http.Request) {
 	// Authentication is handled by editorMiddleware, so we can proceed directly
 
 	// Get document path from URL
-	path := strings.TrimPrefix(r.URL.Path, "/api/links/edit/")
-	if path == "" {
-		sendLinkError(w, "Document path is required", http.StatusBadRequest, "")
-		return
-	}
-
-	// Decode URL-encoded path
-	decodedPath, err := url.QueryUnescape(path)
-	if err != nil {
-		sendLinkError(w, "Invalid document path", http.StatusBadRequest, err.Error())
+	path, docPath, ok := resolveDocumentRequest(w, r, "/api/links/edit/")
+	if !ok {
 		return
 	}
 
@@ -162,20 +133,9 @@ func EditLinkHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get document path
-	docPath := getDocumentPath(decodedPath)
-	
-	// Read current document
-	content, err := os.ReadFile(docPath)
-	if err != nil {
-		sendLinkError(w, "Failed to read document", http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	// Parse current content
-	linksData, err := frontmatter.ParseLinksContent(string(content))
-	if err != nil {
-		sendLinkError(w, "Failed to parse links document", http.StatusBadRequest, err.Error())
+	// Read current document, which must be a links page
+	content, linksData, ok := readLinksDocument(w, docPath)
+	if !ok {
 		return
 	}
 
@@ -185,7 +145,7 @@ func EditLinkHandler(w http.ResponseWriter, r *http.Request) {
 		for i, link := range links {
 			if link.URL == req.OldURL {
 				// Update the link
-				linksData.Categories[category][i] = frontmatter.Link{
+				updated := frontmatter.Link{
 					Title:       req.NewLink.Title,
 					URL:         req.NewLink.URL,
 					Description: req.NewLink.Description,
@@ -198,12 +158,9 @@ func EditLinkHandler(w http.ResponseWriter, r *http.Request) {
 					// Remove from old category
 					linksData.Categories[category] = append(links[:i], links[i+1:]...)
 					// Add to new category
-					if linksData.Categories[req.NewLink.Category] == nil {
-						linksData.Categories[req.NewLink.Category] = []frontmatter.Link{}
-					}
-					linksData.Categories[req.NewLink.Category] = append(
-						linksData.Categories[req.NewLink.Category], 
-						linksData.Categories[category][i])
+					linksData.AddLink(updated)
+				} else {
+					linksData.Categories[category][i] = updated
 				}
 				
 				linkFound = true
@@ -221,7 +178,7 @@ func EditLinkHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate updated markdown
-	updatedContent, err := generateLinksMarkdown(linksData, string(content))
+	updatedContent, err := generateLinksMarkdown(linksData, content)
 	if err != nil {
 		sendLinkError(w, "Failed to generate updated content", http.StatusInternalServerError, err.Error())
 		return
@@ -253,16 +210,8 @@ func DeleteLinkHandler(w http.ResponseWriter, r *http.Request) {
 	// Authentication is handled by editorMiddleware, so we can proceed directly
 
 	// Get document path from URL
-	path := strings.TrimPrefix(r.URL.Path, "/api/links/delete/")
-	if path == "" {
-		sendLinkError(w, "Document path is required", http.StatusBadRequest, "")
-		return
-	}
-
-	// Decode URL-encoded path
-	decodedPath, err := url.QueryUnescape(path)
-	if err != nil {
-		sendLinkError(w, "Invalid document path", http.StatusBadRequest, err.Error())
+	path, docPath, ok := resolveDocumentRequest(w, r, "/api/links/delete/")
+	if !ok {
 		return
 	}
 
@@ -283,20 +232,9 @@ func DeleteLinkHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get document path
-	docPath := getDocumentPath(decodedPath)
-	
-	// Read current document
-	content, err := os.ReadFile(docPath)
-	if err != nil {
-		sendLinkError(w, "Failed to read document", http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	// Parse current content
-	linksData, err := frontmatter.ParseLinksContent(string(content))
-	if err != nil {
-		sendLinkError(w, "Failed to parse links document", http.StatusBadRequest, err.Error())
+	// Read current document, which must be a links page
+	content, linksData, ok := readLinksDocument(w, docPath)
+	if !ok {
 		return
 	}
 
@@ -338,7 +276,7 @@ func DeleteLinkHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate updated markdown
-	updatedContent, err := generateLinksMarkdown(linksData, string(content))
+	updatedContent, err := generateLinksMarkdown(linksData, content)
 	if err != nil {
 		sendLinkError(w, "Failed to generate updated content", http.StatusInternalServerError, err.Error())
 		return
@@ -358,8 +296,114 @@ func DeleteLinkHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// ListLinksHandler handles GET requests returning all links of a links document as JSON
+func ListLinksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		sendLinkError(w, "Method not allowed", http.StatusMethodNotAllowed, "")
+		return
+	}
+
+	path, docPath, ok := resolveDocumentRequest(w, r, "/api/links/list/")
+	if !ok {
+		return
+	}
+
+	_, linksData, ok := readLinksDocument(w, docPath)
+	if !ok {
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"path":    "/" + path,
+		"links":   linksData,
+	})
+}
+
+// SearchLinksHandler handles GET requests searching the links of a links
+// document. All words of the q parameter must match the title, URL,
+// description or category; the optional category parameter narrows the search.
+func SearchLinksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		sendLinkError(w, "Method not allowed", http.StatusMethodNotAllowed, "")
+		return
+	}
+
+	path, docPath, ok := resolveDocumentRequest(w, r, "/api/links/search/")
+	if !ok {
+		return
+	}
+
+	_, linksData, ok := readLinksDocument(w, docPath)
+	if !ok {
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	category := strings.TrimSpace(r.URL.Query().Get("category"))
+	terms := strings.Fields(strings.ToLower(query))
+
+	results := []frontmatter.Link{}
+	for _, name := range linksData.OrderedCategories() {
+		if category != "" && !strings.EqualFold(name, category) {
+			continue
+		}
+		for _, link := range linksData.Categories[name] {
+			haystack := strings.ToLower(strings.Join([]string{link.Title, link.URL, link.Description, link.Category}, " "))
+			matched := true
+			for _, term := range terms {
+				if !strings.Contains(haystack, term) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				results = append(results, link)
+			}
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"path":     "/" + path,
+		"query":    query,
+		"category": category,
+		"total":    len(results),
+		"results":  results,
+	})
+}
+
 // Helper functions
 
+// readLinksDocument reads and parses a links-layout document. It sends an
+// error response and returns false if the document is missing or does not
+// use the links layout.
+func readLinksDocument(w http.ResponseWriter, docPath string) (string, *frontmatter.LinksData, bool) {
+	content, err := os.ReadFile(docPath)
+	if err != nil {
+		sendLinkError(w, "Document not found", http.StatusNotFound, "")
+		return "", nil, false
+	}
+
+	metadata, _, hasFrontmatter := frontmatter.Parse(string(content))
+	if !hasFrontmatter || metadata.Layout != "links" {
+		sendLinkError(w, "Document does not use the links layout", http.StatusBadRequest, "")
+		return "", nil, false
+	}
+
+	linksData, err := frontmatter.ParseLinksContent(string(content))
+	if err != nil {
+		sendLinkError(w, "Failed to parse links document", http.StatusBadRequest, err.Error())
+		return "", nil, false
+	}
+
+	return string(content), linksData, true
+}
+
 func sendLinkError(w http.ResponseWriter, message string, statusCode int, error string) {
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(LinkResponse{
@@ -411,6 +455,38 @@ func parseDate(dateStr string) time.Time {
 	return time.Now()
 }
 
+// resolveDocumentRequest extracts the document path following prefix in the
+// request URL and returns it with the path of its document.md. It sends an
+// error response and returns false if the path is invalid or not accessible.
+func resolveDocumentRequest(w http.ResponseWriter, r *http.Request, prefix string) (string, string, bool) {
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	if path == "" {
+		sendLinkError(w, "Document path is required", http.StatusBadRequest, "")
+		return "", "", false
+	}
+
+	// Decode URL-encoded path
+	decodedPath, err := url.QueryUnescape(path)
+	if err != nil {
+		sendLinkError(w, "Invalid document path", http.StatusBadRequest, err.Error())
+		return "", "", false
+	}
+
+	// Block directory traversal
+	if strings.Contains(decodedPath, "..") {
+		sendLinkError(w, "Invalid document path", http.StatusBadRequest, "")
+		return "", "", false
+	}
+	decodedPath = strings.Trim(decodedPath, "/")
+
+	if !auth.CanAccessDocument("/"+decodedPath, auth.GetSession(r), cfg) {
+		sendLinkError(w, "Access denied", http.StatusForbidden, "")
+		return "", "", false
+	}
+
+	return decodedPath, getDocumentPath(decodedPath), true
+}
+
 func getDocumentPath(path string) string {
 	// Clean and normalize the path
 	path = filepath.Clean(path)
@@ -470,8 +546,9 @@ func generateLinksMarkdown(linksData *frontmatter.LinksData, originalContent str
 		result.WriteString("\n\n")
 	}
 	
-	// Add categories and links
-	for category, links := range linksData.Categories {
+	// Add categories and links, keeping the original category order
+	for _, category := range linksData.OrderedCategories() {
+		links := linksData.Categories[category]
 		if len(links) == 0 {
 			continue
 		}
@@ -506,6 +583,27 @@ func generateLinksMarkdown(linksData *frontmatter.LinksData, originalContent str
 }
 
 func saveDocumentWithVersioning(docPath, relativePath string, content []byte) error {
+	createDocumentVersion(docPath, relativePath)
+
+	// Create directory if it doesn't exist
+	dir := filepath.Dir(docPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	// Write the content to the file
+	if err := os.WriteFile(docPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to save document: %v", err)
+	}
+	invalidateRenderCache(relativePath)
+
+	return nil
+}
+
+// createDocumentVersion saves the current content of a document as a version
+// before it is overwritten. relativePath is the document path relative to the
+// documents directory.
+func createDocumentVersion(docPath, relativePath string) {
 	// VERSION CONTROL: Save current version before overwriting (same logic as SaveHandler)
 	if _, err := os.Stat(docPath); err == nil && cfg.Wiki.MaxVersions > 0 {
 		// Document exists, read its current content
@@ -533,17 +631,4 @@ func saveDocumentWithVersioning(docPath, relativePath string, content []byte) er
 			}
 		}
 	}
-
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(docPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %v", err)
-	}
-
-	// Write the content to the file
-	if err := os.WriteFile(docPath, content, 0644); err != nil {
-		return fmt.Errorf("failed to save document: %v", err)
-	}
-
-	return nil
 }
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wiki-go/internal/config"
+)
+
+func TestLinkHandlersRefuseOtherLayouts(t *testing.T) {
+	saved := cfg
+	defer func() { cfg = saved }()
+	cfg = &config.Config{}
+	cfg.Wiki.RootDir = t.TempDir()
+	cfg.Wiki.DocumentsDir = "documents"
+
+	docPath := filepath.Join(cfg.Wiki.RootDir, "documents", "notes", "document.md")
+	if err := os.MkdirAll(filepath.Dir(docPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	original := "# Notes\n\nNot a list of links.\n"
+	if err := os.WriteFile(docPath, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	link := `{"url":"https://example.com","title":"Example","category":"General"}`
+	for _, tt := range []struct {
+		method, path, body string
+		handler            http.HandlerFunc
+	}{
+		{http.MethodPost, "/api/links/add/notes", link, AddLinkHandler},
+		{http.MethodPut, "/api/links/edit/notes", `{"oldUrl":"https://example.com","newLink":` + link + `}`, EditLinkHandler},
+		{http.MethodDelete, "/api/links/delete/notes", `{"url":"https://example.com"}`, DeleteLinkHandler},
+	} {
+		rec := httptest.NewRecorder()
+		tt.handler(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: expected status 400, got %d", tt.method, tt.path, rec.Code)
+		}
+		if content, _ := os.ReadFile(docPath); string(content) != original {
+			t.Errorf("%s %s: page changed to %q", tt.method, tt.path, content)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"wiki-go/internal/frontmatter"
+)
+
+// maxImportUploadSize limits the size of an uploaded bookmark or board file
+const maxImportUploadSize = 10 << 20
+
+// ExportLinksHandler handles GET requests exporting a links document as a
+// Netscape bookmark HTML file that browsers can import
+func ExportLinksHandler(w http.ResponseWriter, r *http.Request) {
+	// Errors are reported as JSON until the export is written
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		sendLinkError(w, "Method not allowed", http.StatusMethodNotAllowed, "")
+		return
+	}
+
+	docPath, filePath, ok := resolveDocumentRequest(w, r, "/api/links/export/")
+	if !ok {
+		return
+	}
+
+	_, linksData, ok := readLinksDocument(w, filePath)
+	if !ok {
+		return
+	}
+
+	filename := path.Base(docPath) + "-bookmarks.html"
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	io.WriteString(w, frontmatter.RenderNetscapeBookmarks(linksData))
+}
+
+// ImportLinksHandler handles POST requests importing a Netscape bookmark HTML
+// file into a links document. The file is sent either as the "file" field of
+// a multipart form or as the raw request body. Bookmarks whose URL is already
+// in the document are skipped.
+func ImportLinksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		sendLinkError(w, "Method not allowed", http.StatusMethodNotAllowed, "")
+		return
+	}
+
+	// Authentication is handled by editorMiddleware, so we can proceed directly
+
+	docPath, filePath, ok := resolveDocumentRequest(w, r, "/api/links/import/")
+	if !ok {
+		return
+	}
+
+	content, linksData, ok := readLinksDocument(w, filePath)
+	if !ok {
+		return
+	}
+
+	bookmarks, err := readImportUpload(w, r)
+	if err != nil {
+		sendLinkError(w, "Failed to read bookmark file", http.StatusBadRequest, err.Error())
+		return
+	}
+
+	links, invalid := frontmatter.ParseNetscapeBookmarks(bookmarks)
+	if len(links) == 0 && invalid == 0 {
+		sendLinkError(w, "No bookmarks found in file", http.StatusBadRequest, "")
+		return
+	}
+
+	// Skip bookmarks already present in the document
+	existing := make(map[string]bool)
+	for _, categoryLinks := range linksData.Categories {
+		for _, link := range categoryLinks {
+			existing[link.URL] = true
+		}
+	}
+
+	imported, duplicates := 0, 0
+	for _, link := range links {
+		if existing[link.URL] {
+			duplicates++
+			continue
+		}
+		existing[link.URL] = true
+		linksData.AddLink(link)
+		imported++
+	}
+
+	if imported > 0 {
+		updatedContent, err := generateLinksMarkdown(linksData, content)
+		if err != nil {
+			sendLinkError(w, "Failed to generate updated content", http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if err := saveDocumentWithVersioning(filePath, docPath, []byte(updatedContent)); err != nil {
+			sendLinkError(w, "Failed to save document", http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"message":    fmt.Sprintf("Imported %d links", imported),
+		"imported":   imported,
+		"duplicates": duplicates,
+		"invalid":    invalid,
+	})
+}
+
+// readImportUpload returns the uploaded file from a multipart upload or the raw request body
+func readImportUpload(w http.ResponseWriter, r *http.Request) (string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportUploadSize)
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxImportUploadSize); err != nil {
+			return "", err
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"net/http"
 	"wiki-go/internal/auth"
+	"wiki-go/internal/goldext"
 	"wiki-go/internal/utils"
 )
 
@@ -39,13 +40,14 @@ func RenderMarkdownHandler(w http.ResponseWriter, r *http.Request) {
 	// Get the document path from the query parameter
 	docPath := r.URL.Query().Get("path")
 
-	// Use the utility function to render markdown to HTML with the document path
-	var html []byte
-	if docPath != "" {
-		html = utils.RenderMarkdownWithPath(string(markdown), docPath)
-	} else {
-		html = utils.RenderMarkdown(string(markdown))
-	}
+	// Use the utility function to render markdown to HTML with the document path.
+	// Includes in the preview are resolved with the previewing user's access.
+	html := utils.RenderMarkdownWithContext(string(markdown), &goldext.RenderContext{
+		DocPath: docPath,
+		CanAccess: func(p string) bool {
+			return auth.CanAccessDocument(p, session, cfg)
+		},
+	})
 
 	// Set content type to HTML
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
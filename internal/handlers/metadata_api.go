@@ -80,11 +80,10 @@ type URLMetadata struct {
 	Description string
 }
 
-// fetchURLMetadata fetches and parses HTML metadata from a URL
-func fetchURLMetadata(targetURL string) (*URLMetadata, error) {
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 2 * time.Second,
+// newFetchClient creates the HTTP client used to fetch external pages
+func newFetchClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			// Allow up to 5 redirects
 			if len(via) >= 5 {
@@ -93,9 +92,11 @@ func fetchURLMetadata(targetURL string) (*URLMetadata, error) {
 			return nil
 		},
 	}
+}
 
-	// Create request with proper headers
-	req, err := http.NewRequest("GET", targetURL, nil)
+// newFetchRequest creates a request for an external page with browser-like headers
+func newFetchRequest(method, targetURL string) (*http.Request, error) {
+	req, err := http.NewRequest(method, targetURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
@@ -109,6 +110,20 @@ func fetchURLMetadata(targetURL string) (*URLMetadata, error) {
 	req.Header.Set("Cache-Control", "no-cache")
 	req.Header.Set("Connection", "keep-alive")
 
+	return req, nil
+}
+
+// fetchURLMetadata fetches and parses HTML metadata from a URL
+func fetchURLMetadata(targetURL string) (*URLMetadata, error) {
+	// Create HTTP client with timeout
+	client := newFetchClient(2 * time.Second)
+
+	// Create request with proper headers
+	req, err := newFetchRequest("GET", targetURL)
+	if err != nil {
+		return nil, err
+	}
+
 	// Perform request
 	resp, err := client.Do(req)
 	if err != nil {
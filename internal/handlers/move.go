@@ -222,6 +222,8 @@ func MoveDocumentHandler(w http.ResponseWriter, r *http.Request, cfg *config.Con
 		sendJSONResponse(w, false, "Failed to move: "+err.Error(), http.StatusInternalServerError, "", "")
 		return
 	}
+	invalidateRenderCache(moveReq.SourcePath)
+	invalidateRenderCache(newPath)
 
 	// Handle versions directory
 	var versionsSourcePath, versionsTargetPath string
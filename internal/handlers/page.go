@@ -114,6 +114,27 @@ func PageHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
 	// Generate breadcrumbs
 	breadcrumbs := generateBreadcrumbs(nav, path)
 
+	// Book view renders the page and the pages below it as one document
+	if r.URL.Query().Get("view") == "book" && !isEditMode {
+		userRole := ""
+		if session != nil {
+			userRole = session.Role
+		}
+		renderTemplate(w, &types.PageData{
+			Navigation:         &types.NavTree{Root: nav, AlwaysOpen: cfg.Wiki.AlwaysOpenChildrenInSidebar},
+			Content:            renderBook(buildBook(navItem, session, cfg)),
+			Breadcrumbs:        breadcrumbs,
+			Config:             cfg,
+			LastModified:       info.ModTime(),
+			CurrentDir:         navItem,
+			AvailableLanguages: i18n.GetAvailableLanguages(),
+			IsAuthenticated:    session != nil,
+			UserRole:           userRole,
+			DocPath:            decodedPath,
+		})
+		return
+	}
+
 	var content template.HTML
 	var lastModified time.Time
 	var dirContent template.HTML
@@ -143,7 +164,7 @@ func PageHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
 		}
 
 		// Use the document path for rendering to handle local file references
-		content = template.HTML(utils.RenderMarkdownWithPath(string(mdContent), decodedPath))
+		content = renderDocument(string(mdContent), decodedPath, session)
 		
 		// If content is empty but document exists, ensure we have something truthy for template conditions
 		if strings.TrimSpace(string(content)) == "" {
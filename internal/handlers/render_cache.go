@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+
+	"wiki-go/internal/auth"
+	"wiki-go/internal/config"
+	"wiki-go/internal/goldext"
+	"wiki-go/internal/rendercache"
+	"wiki-go/internal/utils"
+)
+
+// renderCache holds rendered document HTML, nil when caching is disabled
+var renderCache *rendercache.Cache
+
+// InitRenderCache creates the render cache if it is enabled in the configuration
+func InitRenderCache(cfg *config.Config) {
+	if !cfg.RenderCache.Enabled {
+		renderCache = nil
+		return
+	}
+
+	diskDir := ""
+	if cfg.RenderCache.Disk {
+		diskDir = filepath.Join(cfg.Wiki.RootDir, "cache", "render")
+	}
+
+	renderCache = rendercache.New(cfg.RenderCache.MaxEntries, diskDir, renderCacheFingerprint(cfg))
+}
+
+// renderCacheFingerprint returns the settings, shortcode templates,
+// glossary terms and variables that change rendered output
+func renderCacheFingerprint(cfg *config.Config) string {
+	return cfg.Wiki.Language + "|" + cfg.Wiki.Timezone + "|" + cfg.Citations.Bibliography + "|" + cfg.Citations.Style + "|" +
+		goldext.UserShortcodesVersion() + "|" + goldext.GlossaryVersion() + "|" + goldext.VariablesVersion()
+}
+
+// renderDocument renders a document for the viewer of session, using the
+// render cache when possible
+func renderDocument(content, docPath string, session *auth.Session) template.HTML {
+	ctx := &goldext.RenderContext{
+		DocPath: docPath,
+		CanAccess: func(p string) bool {
+			return auth.CanAccessDocument(p, session, cfg)
+		},
+	}
+
+	return template.HTML(renderCache.Render(content, ctx, func(ctx *goldext.RenderContext) []byte {
+		return utils.RenderMarkdownWithContext(content, ctx)
+	}))
+}
+
+// invalidateRenderCache drops cached renders affected by a change to the
+// document at docPath or the documents below it. If the change affects the
+// glossary, every render is dropped, since any page may use its terms.
+func invalidateRenderCache(docPath string) {
+	renderCache.Invalidate(filepath.ToSlash(docPath))
+	if goldext.RefreshGlossary() {
+		renderCache.SetFingerprint(renderCacheFingerprint(cfg))
+	}
+}
+
+// RenderCacheHandler handles /api/render-cache. GET returns the cache size
+// and hit and miss counters; DELETE purges the cache.
+func RenderCacheHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"stats":   renderCache.Stats(),
+		})
+
+	case http.MethodDelete:
+		renderCache.Purge()
+		log.Printf("Render cache purged")
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Render cache purged",
+		})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Method not allowed",
+		})
+	}
+}
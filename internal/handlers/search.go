@@ -9,6 +9,7 @@ import (
 
 	"wiki-go/internal/auth"
 	"wiki-go/internal/config"
+	"wiki-go/internal/goldext"
 )
 
 type SearchRequest struct {
@@ -72,9 +73,13 @@ func performSearch(query string, session *auth.Session, cfg *config.Config) []Se
 				return nil
 			}
 
-			if matches := matchContent(string(content), searchTerms); matches {
+			// Index the text of transcluded pages too, so a page matches on
+			// content readers see through :::include::: shortcodes
+			searchable := string(content) + includedSearchText(string(content), session, cfg)
+
+			if matches := matchContent(searchable, searchTerms); matches {
 				title := extractTitle(string(content))
-				excerpt := extractExcerpt(string(content), searchTerms)
+				excerpt := extractExcerpt(searchable, searchTerms)
 
 				results = append(results, SearchResult{
 					Title:   title,
@@ -93,6 +98,40 @@ func performSearch(query string, session *auth.Session, cfg *config.Config) []Se
 	return results
 }
 
+// includedSearchText returns the markdown of every page included by content
+// that the session can access, following nested includes once per page
+func includedSearchText(content string, session *auth.Session, cfg *config.Config) string {
+	var text strings.Builder
+	visited := make(map[string]bool)
+
+	pending := goldext.FindIncludes(content)
+	for len(pending) > 0 {
+		inc := pending[0]
+		pending = pending[1:]
+
+		key := inc.Path + "#" + inc.Section
+		if visited[key] || len(visited) >= goldext.MaxIncludeDepth*4 {
+			continue
+		}
+		visited[key] = true
+
+		if !auth.CanAccessDocument(inc.Path, session, cfg) {
+			continue
+		}
+
+		source, err := goldext.ReadIncludeSource(inc.Path, inc.Section)
+		if err != nil {
+			continue
+		}
+
+		text.WriteString("\n")
+		text.WriteString(source)
+		pending = append(pending, goldext.FindIncludes(source)...)
+	}
+
+	return text.String()
+}
+
 type SearchTerms struct {
 	ExactPhrases []string
 	IncludeWords []string
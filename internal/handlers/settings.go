@@ -165,6 +165,7 @@ func UpdateWikiSettingsHandler(w http.ResponseWriter, r *http.Request) {
 	// Apply updated timezone to shortcode rendering so :::stats recent:::
 	// reflects the new setting without requiring a restart.
 	goldext.SetWikiTimezone(updatedConfig.Wiki.Timezone)
+	renderCache.SetFingerprint(renderCacheFingerprint(cfg))
 
 	// Send success response
 	w.Header().Set("Content-Type", "application/json")
@@ -228,5 +229,8 @@ func saveConfig(path string, cfg *config.Config) error {
 		return fmt.Errorf("failed to write configuration file: %w", err)
 	}
 
+	// Cached pages may depend on the old settings
+	renderCache.Purge()
+
 	return nil
 }
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"wiki-go/internal/config"
+	"wiki-go/internal/goldext"
+)
+
+// shortcodeReloadInterval is how often the shortcodes directory is checked
+// for changed templates
+const shortcodeReloadInterval = 5 * time.Second
+
+// shortcodesDir returns the directory holding user shortcode templates
+func shortcodesDir(cfg *config.Config) string {
+	return filepath.Join(cfg.Wiki.RootDir, "shortcodes")
+}
+
+// InitUserShortcodes loads the user shortcode templates
+func InitUserShortcodes(cfg *config.Config) {
+	dir := shortcodesDir(cfg)
+	if err := goldext.LoadUserShortcodes(dir); err != nil {
+		log.Printf("Warning: Failed to load shortcodes from %s: %v", dir, err)
+	}
+}
+
+// WatchUserShortcodes reloads the user shortcode templates whenever they
+// change, dropping cached renders that may use them
+func WatchUserShortcodes(cfg *config.Config) {
+	dir := shortcodesDir(cfg)
+	goldext.WatchUserShortcodes(dir, shortcodeReloadInterval, func() {
+		log.Printf("Shortcodes in %s changed, reloaded", dir)
+		renderCache.SetFingerprint(renderCacheFingerprint(cfg))
+	})
+}
+
+// ShortcodesHandler handles GET /api/shortcodes, listing the built-in
+// shortcodes and the user shortcodes with any template errors
+func ShortcodesHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Method not allowed",
+		})
+		return
+	}
+
+	// Pick up changes made since the last check
+	if goldext.ReloadUserShortcodes(shortcodesDir(cfg)) {
+		renderCache.SetFingerprint(renderCacheFingerprint(cfg))
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"directory":  shortcodesDir(cfg),
+		"builtin":    goldext.BuiltinShortcodeNames(),
+		"shortcodes": goldext.UserShortcodes(),
+	})
+}
+
+// ShortcodeValidateRequest is the body of POST /api/shortcodes/validate.
+// Source is checked if given, otherwise the loaded template called Name.
+type ShortcodeValidateRequest struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Args   string `json:"args"` // Arguments to render with, as written in a page
+	Path   string `json:"path"` // Page to render for
+}
+
+// ShortcodeValidateHandler handles POST /api/shortcodes/validate. It parses
+// a template and renders it with sample arguments, returning the sanitized
+// HTML or the error.
+func ShortcodeValidateHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Method not allowed",
+		})
+		return
+	}
+
+	var req ShortcodeValidateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Invalid request body",
+		})
+		return
+	}
+
+	var sc *goldext.UserShortcode
+	if req.Source != "" {
+		sc = goldext.ParseUserShortcode(req.Name, req.Source)
+	} else {
+		var ok bool
+		sc, ok = goldext.LookupUserShortcode(req.Name)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"message": "Shortcode not found",
+			})
+			return
+		}
+	}
+
+	html, err := sc.Render(req.Args, req.Path)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"valid":   false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"valid":   true,
+		"html":    html,
+	})
+}
@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"wiki-go/internal/auth"
+	"wiki-go/internal/config"
+	"wiki-go/internal/frontmatter"
+	"wiki-go/internal/i18n"
+	"wiki-go/internal/resources"
+)
+
+// anyTaskUser is the user filter value matching tasks regardless of assignee
+const anyTaskUser = "*"
+
+// htmlTagRegex matches HTML tags in rendered task text
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// TaskFilter selects the open tasks returned by the task views
+type TaskFilter struct {
+	User  string    // Assignee, or anyTaskUser for all tasks
+	Label string    // Label, empty for any
+	From  time.Time // Earliest due date, zero for no limit
+	To    time.Time // Latest due date, zero for no limit
+}
+
+// TaskEntry is an open task in the task views
+type TaskEntry struct {
+	Text      string        `json:"text"` // Task markdown
+	HTML      template.HTML `json:"html"` // Task text without metadata
+	Assignees []string      `json:"assignees,omitempty"`
+	Labels    []string      `json:"labels,omitempty"`
+	Due       string        `json:"due,omitempty"`
+	Priority  string        `json:"priority,omitempty"`
+	Overdue   bool          `json:"overdue,omitempty"`
+}
+
+// TaskColumnGroup holds the tasks of one kanban column or checklist section
+type TaskColumnGroup struct {
+	Board  string      `json:"board,omitempty"`
+	Column string      `json:"column,omitempty"`
+	Tasks  []TaskEntry `json:"tasks"`
+}
+
+// TaskDocumentGroup holds the tasks of one document
+type TaskDocumentGroup struct {
+	Path   string            `json:"path"`
+	URL    string            `json:"url"` // Link to the document, filtered on kanban boards
+	Title  string            `json:"title"`
+	Kanban bool              `json:"kanban"`
+	Groups []TaskColumnGroup `json:"groups"`
+}
+
+// TasksPage holds the data for the tasks view template
+type TasksPage struct {
+	Title       string
+	Config      *config.Config
+	TasksTitle  string
+	BackToHome  string
+	User        string
+	Label       string
+	From        string
+	To          string
+	CalendarURL string
+	Documents   []TaskDocumentGroup
+	Count       int
+}
+
+// TasksHandler handles GET /api/tasks?user=&label=&from=&to= and returns the
+// open tasks of all accessible documents, grouped by document and column
+func TasksHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Method not allowed",
+		})
+		return
+	}
+
+	session := auth.GetSession(r)
+	filter, err := parseTaskFilter(r, session)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	documents, count := collectTasks(cfg, session, filter, time.Now())
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"user":      filter.User,
+		"count":     count,
+		"documents": documents,
+	})
+}
+
+// TasksPageHandler renders the tasks view
+func TasksPageHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	session := auth.GetSession(r)
+	filter, err := parseTaskFilter(r, session)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	documents, count := collectTasks(cfg, session, filter, time.Now())
+
+	data := TasksPage{
+		Title:       fmt.Sprintf("%s - %s", i18n.Translate("tasks.title"), cfg.Wiki.Title),
+		Config:      cfg,
+		TasksTitle:  i18n.Translate("tasks.title"),
+		BackToHome:  i18n.Translate("nav.back_to_home"),
+		User:        filter.User,
+		Label:       filter.Label,
+		From:        formatTaskDate(filter.From),
+		To:          formatTaskDate(filter.To),
+		CalendarURL: "/api/tasks/calendar.ics?" + taskFilterQuery(filter).Encode(),
+		Documents:   documents,
+		Count:       count,
+	}
+
+	funcs := template.FuncMap{"t": i18n.Translate}
+	tmpl, err := template.New("tasks.html").Funcs(funcs).ParseFS(resources.GetTemplatesFS(), "templates/tasks.html")
+	if err != nil {
+		http.Error(w, "Error parsing tasks template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering tasks template: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// TasksCalendarHandler handles GET /api/tasks/calendar.ics and returns the
+// open tasks with a due date as an iCalendar feed of all-day events
+func TasksCalendarHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session := auth.GetSession(r)
+	filter, err := parseTaskFilter(r, session)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	documents, _ := collectTasks(cfg, session, filter, now)
+	baseURL := getBaseURL(r, cfg)
+
+	var b strings.Builder
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:-//wiki-go//Tasks//EN")
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+	writeICSLine(&b, "X-WR-CALNAME:"+escapeICSText(cfg.Wiki.Title+" - "+i18n.Translate("tasks.title")))
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, document := range documents {
+		for _, group := range document.Groups {
+			for _, task := range group.Tasks {
+				if task.Due == "" {
+					continue
+				}
+				due, _ := time.Parse("2006-01-02", task.Due)
+
+				location := document.Title
+				if group.Column != "" {
+					location += " / " + group.Column
+				}
+
+				// Stable UID so calendar clients update events instead of duplicating them
+				sum := sha1.Sum([]byte(document.Path + "\n" + group.Board + "\n" + group.Column + "\n" + task.Text))
+
+				writeICSLine(&b, "BEGIN:VEVENT")
+				writeICSLine(&b, "UID:"+hex.EncodeToString(sum[:])+"@wiki-go")
+				writeICSLine(&b, "DTSTAMP:"+stamp)
+				writeICSLine(&b, "DTSTART;VALUE=DATE:"+due.Format("20060102"))
+				writeICSLine(&b, "DTEND;VALUE=DATE:"+due.AddDate(0, 0, 1).Format("20060102"))
+				writeICSLine(&b, "SUMMARY:"+escapeICSText(stripHTMLTags(string(task.HTML))))
+				writeICSLine(&b, "DESCRIPTION:"+escapeICSText(location))
+				writeICSLine(&b, "URL:"+baseURL+document.URL)
+				if len(task.Labels) > 0 {
+					labels := make([]string, len(task.Labels))
+					for i, label := range task.Labels {
+						labels[i] = escapeICSText(label)
+					}
+					writeICSLine(&b, "CATEGORIES:"+strings.Join(labels, ","))
+				}
+				writeICSLine(&b, "END:VEVENT")
+			}
+		}
+	}
+
+	writeICSLine(&b, "END:VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="tasks.ics"`)
+	w.Write([]byte(b.String()))
+}
+
+// parseTaskFilter reads the task filter from the query string. Without a
+// user parameter the tasks of the logged-in user are selected, or all tasks
+// for anonymous visitors.
+func parseTaskFilter(r *http.Request, session *auth.Session) (TaskFilter, error) {
+	query := r.URL.Query()
+	filter := TaskFilter{
+		User:  strings.TrimPrefix(strings.TrimSpace(query.Get("user")), "@"),
+		Label: strings.TrimPrefix(strings.TrimSpace(query.Get("label")), "#"),
+	}
+
+	if !query.Has("user") && session != nil {
+		filter.User = session.Username
+	}
+	if filter.User == "" {
+		filter.User = anyTaskUser
+	}
+
+	var err error
+	if value := query.Get("from"); value != "" {
+		if filter.From, err = time.Parse("2006-01-02", value); err != nil {
+			return filter, fmt.Errorf("invalid from date, expected YYYY-MM-DD")
+		}
+	}
+	if value := query.Get("to"); value != "" {
+		if filter.To, err = time.Parse("2006-01-02", value); err != nil {
+			return filter, fmt.Errorf("invalid to date, expected YYYY-MM-DD")
+		}
+	}
+
+	return filter, nil
+}
+
+// taskFilterQuery returns the query parameters selecting filter
+func taskFilterQuery(filter TaskFilter) url.Values {
+	query := url.Values{}
+	query.Set("user", filter.User)
+	if filter.Label != "" {
+		query.Set("label", filter.Label)
+	}
+	if !filter.From.IsZero() {
+		query.Set("from", formatTaskDate(filter.From))
+	}
+	if !filter.To.IsZero() {
+		query.Set("to", formatTaskDate(filter.To))
+	}
+	return query
+}
+
+// matches reports whether an open task is selected by the filter
+func (f TaskFilter) matches(task frontmatter.DocumentTask) bool {
+	if task.Checked {
+		return false
+	}
+	if f.User != anyTaskUser && !containsFold(task.Assignees, f.User) {
+		return false
+	}
+	if f.Label != "" && !containsFold(task.Labels, f.Label) {
+		return false
+	}
+	if !f.From.IsZero() || !f.To.IsZero() {
+		if task.Due.IsZero() {
+			return false
+		}
+		if !f.From.IsZero() && task.Due.Before(f.From) {
+			return false
+		}
+		if !f.To.IsZero() && task.Due.After(f.To) {
+			return false
+		}
+	}
+	return true
+}
+
+// collectTasks scans every document the session can access for open tasks
+// selected by filter. It returns the documents with matching tasks, sorted
+// by path, and the total number of tasks.
+func collectTasks(cfg *config.Config, session *auth.Session, filter TaskFilter, now time.Time) ([]TaskDocumentGroup, int) {
+	documents := []TaskDocumentGroup{}
+	count := 0
+
+	forEachDocument(cfg, func(urlPath string, content string) {
+		if !auth.CanAccessDocument(urlPath, session, cfg) {
+			return
+		}
+
+		var groups []TaskColumnGroup
+		kanban := false
+		for _, task := range frontmatter.FindDocumentTasks(content) {
+			kanban = kanban || task.Board != ""
+			if !filter.matches(task) {
+				continue
+			}
+
+			// Tasks arrive in document order, so a new group starts whenever
+			// the board or column changes
+			if n := len(groups); n == 0 || groups[n-1].Board != task.Board || groups[n-1].Column != task.Column {
+				groups = append(groups, TaskColumnGroup{Board: task.Board, Column: task.Column})
+			}
+
+			entry := TaskEntry{
+				Text:      frontmatter.KanbanTaskSource(task.Text),
+				HTML:      template.HTML(task.HTMLText),
+				Assignees: task.Assignees,
+				Labels:    task.Labels,
+				Priority:  task.Priority,
+				Overdue:   task.IsOverdue(now),
+			}
+			if !task.Due.IsZero() {
+				entry.Due = formatTaskDate(task.Due)
+			}
+
+			group := &groups[len(groups)-1]
+			group.Tasks = append(group.Tasks, entry)
+			count++
+		}
+
+		if len(groups) == 0 {
+			return
+		}
+
+		// Open kanban boards with the same filter applied
+		link := urlPath
+		if kanban {
+			query := url.Values{}
+			if filter.User != anyTaskUser {
+				query.Set("assignee", filter.User)
+			}
+			if filter.Label != "" {
+				query.Set("label", filter.Label)
+			}
+			if len(query) > 0 {
+				link += "?" + query.Encode()
+			}
+		}
+
+		documents = append(documents, TaskDocumentGroup{
+			Path:   urlPath,
+			URL:    link,
+			Title:  extractTitle(content),
+			Kanban: kanban,
+			Groups: groups,
+		})
+	})
+
+	sort.Slice(documents, func(i, j int) bool {
+		return documents[i].Path < documents[j].Path
+	})
+
+	return documents, count
+}
+
+// formatTaskDate formats a due date, or returns an empty string for a zero date
+func formatTaskDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// containsFold reports whether values contains value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripHTMLTags removes HTML tags and decodes entities, leaving plain text
+func stripHTMLTags(s string) string {
+	return html.UnescapeString(htmlTagRegex.ReplaceAllString(s, ""))
+}
+
+// escapeICSText escapes a value for an iCalendar TEXT property
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", "").Replace(s)
+}
+
+// writeICSLine writes an iCalendar content line, folding it at 75 octets
+// without splitting UTF-8 sequences
+func writeICSLine(b *strings.Builder, line string) {
+	const maxOctets = 75
+
+	for len(line) > maxOctets {
+		cut := maxOctets
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
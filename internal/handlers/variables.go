@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"wiki-go/internal/config"
+	"wiki-go/internal/frontmatter"
+	"wiki-go/internal/goldext"
+)
+
+// VariableUsage is a variable and the pages that use or override it
+type VariableUsage struct {
+	goldext.Variable
+	Defined      bool     `json:"defined"`       // False for variables used but not defined wiki-wide
+	UsedOn       []string `json:"used_on"`       // Pages referencing the variable with :::var name:::
+	OverriddenOn []string `json:"overridden_on"` // Pages setting their own value in the frontmatter
+}
+
+// VariablesHandler handles GET /api/variables, listing the wiki-wide
+// variables and the pages that use or override each one. Variables that
+// pages use but that are only defined in frontmatter, or not at all, are
+// listed with defined set to false.
+func VariablesHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Method not allowed",
+		})
+		return
+	}
+
+	usage := map[string]*VariableUsage{}
+	for _, v := range goldext.WikiVariables() {
+		usage[v.Name] = &VariableUsage{Variable: v, Defined: true, UsedOn: []string{}, OverriddenOn: []string{}}
+	}
+	lookup := func(name string) *VariableUsage {
+		if usage[name] == nil {
+			usage[name] = &VariableUsage{Variable: goldext.Variable{Name: name}, UsedOn: []string{}, OverriddenOn: []string{}}
+		}
+		return usage[name]
+	}
+
+	forEachDocument(cfg, func(urlPath, content string) {
+		metadata, body, _ := frontmatter.Parse(content)
+		if urlPath == goldext.VariablesPage {
+			return
+		}
+		for name := range metadata.Variables {
+			v := lookup(name)
+			v.OverriddenOn = append(v.OverriddenOn, urlPath)
+		}
+		for _, name := range goldext.UsedVariables(body) {
+			v := lookup(name)
+			v.UsedOn = append(v.UsedOn, urlPath)
+		}
+	})
+
+	list := make([]*VariableUsage, 0, len(usage))
+	for _, v := range usage {
+		sort.Strings(v.UsedOn)
+		sort.Strings(v.OverriddenOn)
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"page":      goldext.VariablesPage,
+		"variables": list,
+	})
+}
@@ -322,6 +322,11 @@ func handleVersionRestore(w http.ResponseWriter, r *http.Request, cfg *config.Co
 		sendJSONErrorVersion(w, "Failed to restore document", http.StatusInternalServerError)
 		return
 	}
+	if versionRelativePath == "pages/home" {
+		invalidateRenderCache("")
+	} else {
+		invalidateRenderCache(strings.TrimPrefix(versionRelativePath, "documents/"))
+	}
 
 	// Force update the file's modification time to ensure cache invalidation
 	now := time.Now()
@@ -0,0 +1,421 @@
+// Package rendercache caches the HTML of rendered documents. Entries are
+// keyed by the document path and a hash of its content, the renderer version
+// and the settings that affect rendering, and are checked against the
+// dependencies recorded while rendering before they are reused.
+package rendercache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"wiki-go/internal/goldext"
+	"wiki-go/internal/utils"
+)
+
+// RendererVersion identifies the output of the markdown renderer. Bump it
+// whenever rendering changes, so pages cached on disk by an older version
+// are not served.
+const RendererVersion = "10"
+
+// maxVariants limits how many renders of the same content are kept, for
+// viewers with different access to the pages it includes
+const maxVariants = 4
+
+// Entry is a single cached render and the dependencies it was rendered with
+type Entry struct {
+	DocPath  string             `json:"doc_path"`
+	HTML     string             `json:"html"`
+	Date     string             `json:"date,omitempty"` // Day the page was rendered on, if the output is dated
+	Deps     goldext.RenderDeps `json:"deps"`
+	Rendered time.Time          `json:"rendered"`
+}
+
+// Stats reports the state of a cache
+type Stats struct {
+	Enabled bool  `json:"enabled"`
+	Disk    bool  `json:"disk"`
+	Entries int   `json:"entries"`
+	Bytes   int64 `json:"bytes"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+}
+
+// item is a cache slot: all variants rendered from the same key
+type item struct {
+	key      string
+	variants []*Entry
+}
+
+// Cache is an LRU cache of rendered documents with an optional disk layer.
+// A nil *Cache is valid and renders every request.
+type Cache struct {
+	mu          sync.Mutex
+	items       map[string]*list.Element
+	lru         *list.List
+	maxEntries  int
+	diskDir     string
+	fingerprint string
+	hits        int64
+	misses      int64
+}
+
+// New creates a cache holding up to maxEntries documents. If diskDir is not
+// empty, renders are also written there and survive restarts. fingerprint
+// identifies the settings that affect rendering, such as language and
+// timezone.
+func New(maxEntries int, diskDir, fingerprint string) *Cache {
+	if maxEntries <= 0 {
+		maxEntries = 500
+	}
+	return &Cache{
+		items:       make(map[string]*list.Element),
+		lru:         list.New(),
+		maxEntries:  maxEntries,
+		diskDir:     diskDir,
+		fingerprint: fingerprint,
+	}
+}
+
+// Render returns the HTML of a document, from the cache if a valid render
+// exists, or by calling render and caching its output otherwise
+func (c *Cache) Render(content string, ctx *goldext.RenderContext, render func(*goldext.RenderContext) []byte) []byte {
+	if c == nil {
+		return render(ctx)
+	}
+
+	docPath := strings.Trim(ctx.DocPath, "/")
+	key := c.key(docPath, content)
+	now := time.Now()
+
+	if entry := c.lookup(key, ctx, now); entry != nil {
+		return []byte(entry.HTML)
+	}
+
+	deps := &goldext.RenderDeps{}
+	renderCtx := *ctx
+	renderCtx.Deps = deps
+	output := render(&renderCtx)
+
+	entry := &Entry{DocPath: docPath, HTML: string(output), Deps: *deps, Rendered: now}
+	if deps.Dated {
+		entry.Date = now.Format("2006-01-02")
+	}
+	c.store(key, entry)
+
+	return output
+}
+
+// key returns the cache key of a document's content
+func (c *Cache) key(docPath, content string) string {
+	h := sha256.New()
+	for _, part := range []string{RendererVersion, c.fingerprint, docPath, content} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lookup returns a cached render that is still valid for the viewer
+func (c *Cache) lookup(key string, ctx *goldext.RenderContext, now time.Time) *Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		if it := c.loadFromDisk(key); it != nil {
+			el = c.insert(it)
+			ok = true
+		}
+	}
+
+	if ok {
+		it := el.Value.(*item)
+		for _, entry := range it.variants {
+			if entry.validFor(ctx, now) {
+				c.lru.MoveToFront(el)
+				c.hits++
+				return entry
+			}
+		}
+	}
+
+	c.misses++
+	return nil
+}
+
+// validFor reports whether an entry can be served to the viewer of ctx
+func (e *Entry) validFor(ctx *goldext.RenderContext, now time.Time) bool {
+	if e.Date != "" && e.Date != now.Format("2006-01-02") {
+		return false
+	}
+	for path, allowed := range e.Deps.Access {
+		if (ctx.CanAccess != nil && ctx.CanAccess(path)) != allowed {
+			return false
+		}
+	}
+	for path, modTime := range e.Deps.Includes {
+		if goldext.PageModTime(path) != modTime {
+			return false
+		}
+	}
+	for path, modTime := range e.Deps.Files {
+		if goldext.FileModTime(path) != modTime {
+			return false
+		}
+	}
+	return true
+}
+
+// store adds a render to the cache, replacing a variant with the same
+// access results
+func (c *Cache) store(key string, entry *Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		el = c.insert(&item{key: key})
+	}
+	it := el.Value.(*item)
+
+	variants := []*Entry{entry}
+	for _, existing := range it.variants {
+		if len(variants) < maxVariants && !sameAccess(existing.Deps.Access, entry.Deps.Access) {
+			variants = append(variants, existing)
+		}
+	}
+	it.variants = variants
+	c.lru.MoveToFront(el)
+
+	c.saveToDisk(it)
+}
+
+// insert adds an item to the front of the LRU list, evicting the least
+// recently used items if the cache is full. Evicted items stay on disk.
+func (c *Cache) insert(it *item) *list.Element {
+	el := c.lru.PushFront(it)
+	c.items[it.key] = el
+
+	for c.lru.Len() > c.maxEntries {
+		back := c.lru.Back()
+		c.lru.Remove(back)
+		delete(c.items, back.Value.(*item).key)
+	}
+	return el
+}
+
+// remove drops an item from memory and disk
+func (c *Cache) remove(el *list.Element) {
+	it := el.Value.(*item)
+	c.lru.Remove(el)
+	delete(c.items, it.key)
+	if c.diskDir != "" {
+		os.Remove(c.diskPath(it.key))
+	}
+}
+
+// Invalidate drops the renders of the document at path and the documents
+// below it, renders that include any of them, and renders that list
+// documents across the wiki. It is called when documents are saved, moved
+// or deleted.
+func (c *Cache) Invalidate(path string) {
+	if c == nil {
+		return
+	}
+	path = strings.Trim(path, "/")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		for _, entry := range el.Value.(*item).variants {
+			if entry.dependsOn(path) {
+				c.remove(el)
+				break
+			}
+		}
+		el = next
+	}
+}
+
+// dependsOn reports whether an entry must be dropped when the document at
+// path or below it changes
+func (e *Entry) dependsOn(path string) bool {
+	if e.Deps.Tree || underPath(e.DocPath, path) {
+		return true
+	}
+	for include := range e.Deps.Includes {
+		if underPath(strings.Trim(include, "/"), path) {
+			return true
+		}
+	}
+	for page := range e.Deps.Access {
+		if underPath(strings.Trim(page, "/"), path) {
+			return true
+		}
+	}
+	return false
+}
+
+// InvalidateLinkStatus drops renders that show link checker results
+func (c *Cache) InvalidateLinkStatus() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		for _, entry := range el.Value.(*item).variants {
+			if entry.Deps.LinkStatus {
+				c.remove(el)
+				break
+			}
+		}
+		el = next
+	}
+}
+
+// Purge drops every render from memory and disk
+func (c *Cache) Purge() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.lru.Init()
+	if c.diskDir != "" {
+		os.RemoveAll(c.diskDir)
+	}
+}
+
+// SetFingerprint changes the settings fingerprint, dropping every render
+// made with other settings
+func (c *Cache) SetFingerprint(fingerprint string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	changed := c.fingerprint != fingerprint
+	c.fingerprint = fingerprint
+	c.mu.Unlock()
+
+	if changed {
+		c.Purge()
+	}
+}
+
+// Stats returns the size of the cache and its hit and miss counters
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := Stats{
+		Enabled: true,
+		Disk:    c.diskDir != "",
+		Entries: c.lru.Len(),
+		Hits:    c.hits,
+		Misses:  c.misses,
+	}
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		for _, entry := range el.Value.(*item).variants {
+			stats.Bytes += int64(len(entry.HTML))
+		}
+	}
+	return stats
+}
+
+// diskPath returns the file a cache item is stored in
+func (c *Cache) diskPath(key string) string {
+	return filepath.Join(c.diskDir, key[:2], key+".json")
+}
+
+// saveToDisk writes the variants of an item that stay valid across restarts.
+// Renders that depend on the whole tree or on link checker results are kept
+// in memory only, since changes made while the wiki is stopped can't be
+// detected.
+func (c *Cache) saveToDisk(it *item) {
+	if c.diskDir == "" {
+		return
+	}
+
+	var persistent []*Entry
+	for _, entry := range it.variants {
+		if !entry.Deps.Tree && !entry.Deps.LinkStatus {
+			persistent = append(persistent, entry)
+		}
+	}
+
+	path := c.diskPath(it.key)
+	if len(persistent) == 0 {
+		os.Remove(path)
+		return
+	}
+
+	data, err := json.Marshal(persistent)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	utils.WriteFileAtomic(path, data, 0644)
+}
+
+// loadFromDisk reads a cache item written by an earlier run
+func (c *Cache) loadFromDisk(key string) *item {
+	if c.diskDir == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.diskPath(key))
+	if err != nil {
+		return nil
+	}
+
+	var variants []*Entry
+	if err := json.Unmarshal(data, &variants); err != nil || len(variants) == 0 {
+		return nil
+	}
+	return &item{key: key, variants: variants}
+}
+
+// sameAccess reports whether two renders were made with the same access results
+func sameAccess(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, allowed := range a {
+		if other, ok := b[path]; !ok || other != allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// underPath reports whether docPath is path or a document below it. The
+// empty path is the homepage.
+func underPath(docPath, path string) bool {
+	if path == "" {
+		return docPath == ""
+	}
+	return docPath == path || strings.HasPrefix(docPath, path+"/")
+}
@@ -0,0 +1,139 @@
+package rendercache
+
+import (
+	"testing"
+
+	"wiki-go/internal/goldext"
+)
+
+// countingRenderer returns a render function and a pointer to the number of
+// times it was called
+func countingRenderer(output string, record func(*goldext.RenderContext)) (func(*goldext.RenderContext) []byte, *int) {
+	calls := 0
+	return func(ctx *goldext.RenderContext) []byte {
+		calls++
+		if record != nil {
+			record(ctx)
+		}
+		return []byte(output)
+	}, &calls
+}
+
+func TestCacheHitsAndInvalidation(t *testing.T) {
+	c := New(10, "", "en")
+	render, calls := countingRenderer("<p>a</p>", nil)
+	ctx := &goldext.RenderContext{DocPath: "docs/a"}
+
+	for i := 0; i < 3; i++ {
+		if got := string(c.Render("# A", ctx, render)); got != "<p>a</p>" {
+			t.Fatalf("Render = %q", got)
+		}
+	}
+	if *calls != 1 {
+		t.Errorf("rendered %d times, want 1", *calls)
+	}
+	if stats := c.Stats(); stats.Hits != 2 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// Changed content is a different key
+	c.Render("# A changed", ctx, render)
+	if *calls != 2 {
+		t.Errorf("changed content was not rendered")
+	}
+
+	c.Invalidate("/docs")
+	c.Render("# A", ctx, render)
+	if *calls != 3 {
+		t.Errorf("invalidated document was served from the cache")
+	}
+}
+
+func TestCacheAccessVariants(t *testing.T) {
+	c := New(10, "", "en")
+	allowed := true
+	ctx := &goldext.RenderContext{DocPath: "a", CanAccess: func(string) bool { return allowed }}
+	render, calls := countingRenderer("<p>a</p>", func(ctx *goldext.RenderContext) {
+		ctx.CheckAccess("/secret")
+	})
+
+	c.Render("x", ctx, render)
+	allowed = false
+	c.Render("x", ctx, render)
+	allowed = true
+	c.Render("x", ctx, render)
+	allowed = false
+	c.Render("x", ctx, render)
+
+	if *calls != 2 {
+		t.Errorf("rendered %d times, want one per access result", *calls)
+	}
+
+	// A change to an accessed page drops the render
+	c.Invalidate("secret")
+	c.Render("x", ctx, render)
+	if *calls != 3 {
+		t.Errorf("render depending on an invalidated page was reused")
+	}
+}
+
+func TestCacheTreeAndLinkStatus(t *testing.T) {
+	c := New(10, "", "en")
+	stats, statsCalls := countingRenderer("stats", func(ctx *goldext.RenderContext) {
+		ctx.RecordContent(":::stats count=*:::", "")
+	})
+	links, linksCalls := countingRenderer("links", func(ctx *goldext.RenderContext) {
+		ctx.RecordContent("", "links")
+	})
+
+	c.Render("s", &goldext.RenderContext{DocPath: "s"}, stats)
+	c.Render("l", &goldext.RenderContext{DocPath: "l"}, links)
+
+	c.Invalidate("elsewhere")
+	c.InvalidateLinkStatus()
+	c.Render("s", &goldext.RenderContext{DocPath: "s"}, stats)
+	c.Render("l", &goldext.RenderContext{DocPath: "l"}, links)
+
+	if *statsCalls != 2 || *linksCalls != 2 {
+		t.Errorf("stats rendered %d times, links %d times, want 2 each", *statsCalls, *linksCalls)
+	}
+}
+
+func TestCacheDiskLayer(t *testing.T) {
+	dir := t.TempDir()
+	render, calls := countingRenderer("<p>a</p>", nil)
+	ctx := &goldext.RenderContext{DocPath: "a"}
+
+	New(10, dir, "en").Render("# A", ctx, render)
+
+	// A new cache, as after a restart, reads the stored render
+	c := New(10, dir, "en")
+	c.Render("# A", ctx, render)
+	if *calls != 1 {
+		t.Errorf("stored render was not reused")
+	}
+
+	// Different settings don't match stored renders
+	New(10, dir, "de").Render("# A", ctx, render)
+	if *calls != 2 {
+		t.Errorf("render stored with other settings was reused")
+	}
+
+	c.Purge()
+	New(10, dir, "en").Render("# A", ctx, render)
+	if *calls != 3 {
+		t.Errorf("purged render was reused")
+	}
+}
+
+func TestNilCacheRenders(t *testing.T) {
+	var c *Cache
+	render, calls := countingRenderer("x", nil)
+	c.Render("x", &goldext.RenderContext{}, render)
+	c.Render("x", &goldext.RenderContext{}, render)
+	c.Invalidate("x")
+	c.Purge()
+	if *calls != 2 {
+		t.Errorf("nil cache rendered %d times, want 2", *calls)
+	}
+}
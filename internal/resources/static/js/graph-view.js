@@ -0,0 +1,223 @@
+/**
+ * Graph View Module
+ * Renders pages and their relationships from /api/graph as a
+ * force-directed SVG graph without external libraries
+ */
+
+document.addEventListener('DOMContentLoaded', function() {
+    'use strict';
+
+    const container = document.getElementById('graphView');
+    if (!container) return;
+
+    const SVG_NS = 'http://www.w3.org/2000/svg';
+    const root = container.dataset.root || '';
+    const depth = container.dataset.depth || '2';
+
+    loadGraph();
+
+    async function loadGraph() {
+        let url = '/api/graph';
+        if (root) {
+            url += '?root=' + encodeURIComponent(root) + '&depth=' + encodeURIComponent(depth);
+        }
+
+        try {
+            const response = await fetch(url);
+            if (!response.ok) {
+                showMessage('Failed to load graph');
+                return;
+            }
+            const data = await response.json();
+            if (!data.nodes || data.nodes.length === 0) {
+                showMessage('No pages to display');
+                return;
+            }
+            renderGraph(data);
+        } catch (error) {
+            console.error('Error loading graph:', error);
+            showMessage('Failed to load graph');
+        }
+    }
+
+    function showMessage(message) {
+        container.innerHTML = '';
+        const div = document.createElement('div');
+        div.className = 'graph-empty';
+        div.textContent = message;
+        container.appendChild(div);
+    }
+
+    function renderGraph(data) {
+        const width = container.clientWidth || 800;
+        const height = container.clientHeight || 600;
+
+        // Initial positions on a circle so the simulation starts untangled
+        const nodes = data.nodes.map(function(node, i) {
+            const angle = (2 * Math.PI * i) / data.nodes.length;
+            return Object.assign({}, node, {
+                x: width / 2 + Math.cos(angle) * width / 3,
+                y: height / 2 + Math.sin(angle) * height / 3,
+                vx: 0,
+                vy: 0
+            });
+        });
+
+        const byId = {};
+        nodes.forEach(function(node) { byId[node.id] = node; });
+
+        const edges = data.edges
+            .filter(function(edge) { return byId[edge.source] && byId[edge.target]; })
+            .map(function(edge) {
+                return { source: byId[edge.source], target: byId[edge.target], type: edge.type };
+            });
+
+        simulate(nodes, edges, width, height);
+
+        container.innerHTML = '';
+        const svg = document.createElementNS(SVG_NS, 'svg');
+        svg.setAttribute('viewBox', '0 0 ' + width + ' ' + height);
+        const viewport = document.createElementNS(SVG_NS, 'g');
+        svg.appendChild(viewport);
+
+        edges.forEach(function(edge) {
+            const line = document.createElementNS(SVG_NS, 'line');
+            line.setAttribute('class', 'graph-edge edge-' + edge.type);
+            line.setAttribute('x1', edge.source.x);
+            line.setAttribute('y1', edge.source.y);
+            line.setAttribute('x2', edge.target.x);
+            line.setAttribute('y2', edge.target.y);
+            viewport.appendChild(line);
+        });
+
+        nodes.forEach(function(node) {
+            const group = document.createElementNS(SVG_NS, 'g');
+            group.setAttribute('class', 'graph-node' + (node.id === data.root ? ' is-root' : ''));
+            group.setAttribute('transform', 'translate(' + node.x + ',' + node.y + ')');
+
+            const title = document.createElementNS(SVG_NS, 'title');
+            let tooltip = node.id;
+            if (node.tags && node.tags.length > 0) {
+                tooltip += '\n#' + node.tags.join(' #');
+            }
+            title.textContent = tooltip;
+            group.appendChild(title);
+
+            const circle = document.createElementNS(SVG_NS, 'circle');
+            circle.setAttribute('r', 7);
+            group.appendChild(circle);
+
+            const label = document.createElementNS(SVG_NS, 'text');
+            label.setAttribute('x', 10);
+            label.setAttribute('y', 4);
+            label.textContent = node.title;
+            group.appendChild(label);
+
+            // Open the page on click, re-center the graph on shift+click
+            circle.addEventListener('click', function(event) {
+                if (event.shiftKey) {
+                    window.location.href = '/graph?root=' + encodeURIComponent(node.id) + '&depth=' + encodeURIComponent(depth);
+                } else {
+                    window.location.href = node.id;
+                }
+            });
+
+            viewport.appendChild(group);
+        });
+
+        container.appendChild(svg);
+        enablePanZoom(svg, viewport);
+    }
+
+    // Simple force simulation: node repulsion, edge springs and centering
+    function simulate(nodes, edges, width, height) {
+        const iterations = 300;
+        const repulsion = 4000;
+        const springLength = 80;
+        const springStrength = 0.02;
+        const centering = 0.005;
+
+        for (let step = 0; step < iterations; step++) {
+            const cooling = 1 - step / iterations;
+
+            for (let i = 0; i < nodes.length; i++) {
+                for (let j = i + 1; j < nodes.length; j++) {
+                    const a = nodes[i];
+                    const b = nodes[j];
+                    let dx = a.x - b.x;
+                    let dy = a.y - b.y;
+                    let distSq = dx * dx + dy * dy;
+                    if (distSq < 0.01) {
+                        dx = Math.random() - 0.5;
+                        dy = Math.random() - 0.5;
+                        distSq = 0.01;
+                    }
+                    const force = repulsion / distSq;
+                    const dist = Math.sqrt(distSq);
+                    a.vx += (dx / dist) * force;
+                    a.vy += (dy / dist) * force;
+                    b.vx -= (dx / dist) * force;
+                    b.vy -= (dy / dist) * force;
+                }
+            }
+
+            edges.forEach(function(edge) {
+                const dx = edge.target.x - edge.source.x;
+                const dy = edge.target.y - edge.source.y;
+                const dist = Math.sqrt(dx * dx + dy * dy) || 1;
+                const force = (dist - springLength) * springStrength;
+                edge.source.vx += (dx / dist) * force;
+                edge.source.vy += (dy / dist) * force;
+                edge.target.vx -= (dx / dist) * force;
+                edge.target.vy -= (dy / dist) * force;
+            });
+
+            nodes.forEach(function(node) {
+                node.vx += (width / 2 - node.x) * centering;
+                node.vy += (height / 2 - node.y) * centering;
+                node.x += Math.max(-10, Math.min(10, node.vx)) * cooling;
+                node.y += Math.max(-10, Math.min(10, node.vy)) * cooling;
+                node.vx *= 0.5;
+                node.vy *= 0.5;
+            });
+        }
+    }
+
+    function enablePanZoom(svg, viewport) {
+        let scale = 1;
+        let offsetX = 0;
+        let offsetY = 0;
+        let dragging = false;
+        let lastX = 0;
+        let lastY = 0;
+
+        function apply() {
+            viewport.setAttribute('transform', 'translate(' + offsetX + ',' + offsetY + ') scale(' + scale + ')');
+        }
+
+        svg.addEventListener('wheel', function(event) {
+            event.preventDefault();
+            scale = Math.max(0.2, Math.min(4, scale * (event.deltaY < 0 ? 1.1 : 0.9)));
+            apply();
+        }, { passive: false });
+
+        svg.addEventListener('mousedown', function(event) {
+            dragging = true;
+            lastX = event.clientX;
+            lastY = event.clientY;
+        });
+
+        window.addEventListener('mousemove', function(event) {
+            if (!dragging) return;
+            offsetX += event.clientX - lastX;
+            offsetY += event.clientY - lastY;
+            lastX = event.clientX;
+            lastY = event.clientY;
+            apply();
+        });
+
+        window.addEventListener('mouseup', function() {
+            dragging = false;
+        });
+    }
+});
@@ -227,7 +227,8 @@ document.addEventListener('DOMContentLoaded', function() {
             resultsHtml += '<ul class="imported-files-list">';
 
             data.importedFiles.forEach(file => {
-                resultsHtml += `<li>${file.originalPath} → <a href="${file.newPath}" target="_blank">${file.newPath}</a></li>`;
+                const attachments = file.attachments ? ` (${file.attachments} attachments)` : '';
+                resultsHtml += `<li>${file.originalPath} → <a href="${file.newPath}" target="_blank">${file.newPath}</a>${attachments}</li>`;
             });
 
             resultsHtml += '</ul>';
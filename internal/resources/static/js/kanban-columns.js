@@ -140,14 +140,17 @@ class KanbanColumnManager {
   deleteColumn(column, columnName) {
     console.log(`Deleting column: ${columnName}`);
 
+    // Remember where the column was before removing it
+    const persistenceManager = this.core.getPersistenceManager();
+    const position = persistenceManager.getColumnPosition(column);
+
     // Remove the column from the DOM
     column.remove();
 
     // Save changes through the core
-    const persistenceManager = this.core.getPersistenceManager();
-    if (persistenceManager) {
-      persistenceManager.saveKanbanChanges();
-    }
+    persistenceManager.deleteColumn(position).catch(err => {
+      console.error('Error deleting column:', err);
+    });
 
     console.log(`Column "${columnName}" deleted successfully`);
   }
@@ -237,10 +240,10 @@ class KanbanColumnManager {
       this.renamedColumns.set(originalTitle.toLowerCase(), newTitle);
 
       // Save changes through the core
-      const persistenceManager = this.core.getPersistenceManager();
-      if (persistenceManager) {
-        persistenceManager.saveKanbanChanges();
-      }
+      const column = columnHeader.closest('.kanban-column');
+      this.core.getPersistenceManager().renameColumn(column, newTitle).catch(err => {
+        console.error('Error renaming column:', err);
+      });
 
       console.log(`Column renamed from "${originalTitle}" to "${newTitle}"`);
     }
@@ -412,9 +415,6 @@ class KanbanColumnManager {
     // Check if the column name already exists
     const isDuplicate = this.checkForDuplicateColumnName(columnName, null);
 
-    // Create the new column
-    const newColumn = this.createNewColumnElement(columnName, isDuplicate);
-
     // Add column to the correct kanban board
     let kanbanBoard = null;
 
@@ -429,6 +429,8 @@ class KanbanColumnManager {
     }
 
     if (kanbanBoard) {
+      // Create the new column with the swimlanes of the board
+      const newColumn = this.createNewColumnElement(columnName, isDuplicate, this.getBoardLanes(kanbanBoard));
       kanbanBoard.appendChild(newColumn);
 
       // Update board name counts for tracking
@@ -442,9 +444,10 @@ class KanbanColumnManager {
       this.setupNewColumnFunctionality(newColumn);
 
       // Save changes through the core
-      const persistenceManager = this.core.getPersistenceManager();
-      if (persistenceManager) {
-        await persistenceManager.saveKanbanChanges();
+      try {
+        await this.core.getPersistenceManager().addColumn(newColumn, columnName);
+      } catch (err) {
+        console.error('Error saving new column:', err);
       }
 
       console.log('New column added:', columnName);
@@ -459,7 +462,7 @@ class KanbanColumnManager {
   /**
    * Create a new column element
    */
-  createNewColumnElement(columnName, isDuplicate) {
+  createNewColumnElement(columnName, isDuplicate, lanes = []) {
     // Create the new column
     const newColumn = document.createElement('div');
     newColumn.className = 'kanban-column';
@@ -468,7 +471,7 @@ class KanbanColumnManager {
     const columnHeader = this.createColumnHeader(columnName, isDuplicate);
 
     // Create column content
-    const columnContent = this.createColumnContent();
+    const columnContent = this.createColumnContent(lanes);
 
     // Add header and content to column
     newColumn.appendChild(columnHeader);
@@ -576,22 +579,66 @@ class KanbanColumnManager {
   }
 
   /**
-   * Create column content with task list
+   * Create column content with a task list, or one per swimlane
    */
-  createColumnContent() {
+  createColumnContent(lanes = []) {
     const columnContent = document.createElement('div');
     columnContent.className = 'kanban-column-content';
 
-    // Create task list
-    const taskList = document.createElement('ul');
-    taskList.className = 'task-list';
+    if (lanes.length === 0) {
+      const taskList = document.createElement('ul');
+      taskList.className = 'task-list';
+      columnContent.appendChild(taskList);
+      return columnContent;
+    }
+
+    lanes.forEach(lane => {
+      const laneElement = document.createElement('div');
+      laneElement.className = 'kanban-lane';
+      laneElement.setAttribute('data-lane', lane);
+
+      const laneTitle = document.createElement('div');
+      laneTitle.className = 'kanban-lane-title';
+      laneTitle.textContent = lane;
 
-    // Add task list to column content
-    columnContent.appendChild(taskList);
+      const taskList = document.createElement('ul');
+      taskList.className = 'task-list';
+
+      laneElement.appendChild(laneTitle);
+      laneElement.appendChild(taskList);
+      columnContent.appendChild(laneElement);
+    });
 
     return columnContent;
   }
 
+  /**
+   * Get the swimlanes of a board, empty if it has none
+   */
+  getBoardLanes(kanbanBoard) {
+    const firstColumn = kanbanBoard.querySelector('.kanban-column');
+    if (!firstColumn) return [];
+    return [...firstColumn.querySelectorAll('.kanban-lane')].map(lane => lane.getAttribute('data-lane'));
+  }
+
+  /**
+   * Update the WIP counts of all columns with a limit, and highlight the
+   * columns holding more cards than their limit
+   */
+  updateColumnLimits() {
+    document.querySelectorAll('.kanban-column[data-wip-limit]').forEach(column => {
+      const limit = parseInt(column.getAttribute('data-wip-limit'));
+      const count = [...column.querySelectorAll('.task-list-item-container')]
+        .filter(task => (task.getAttribute('data-indent-level') || '0') === '0').length;
+
+      const badge = column.querySelector('.kanban-wip');
+      if (badge) {
+        badge.textContent = `${count}/${limit}`;
+      }
+      column.classList.toggle('over-wip-limit', count > limit);
+    });
+  }
+
   /**
    * Setup functionality for a newly created column
    */
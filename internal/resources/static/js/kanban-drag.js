@@ -6,6 +6,8 @@ class KanbanDragHandler {
     this.originalContainer = null;
     this.originalNextSibling = null;
     this.originalIndentLevel = 0;
+    this.originalPosition = null;
+    this.originalAnchor = null;
     this.initialized = false;
   }
 
@@ -71,6 +73,11 @@ class KanbanDragHandler {
     this.originalContainer = item.parentNode;
     this.originalNextSibling = item.nextElementSibling;
     this.originalIndentLevel = parseInt(item.getAttribute('data-indent-level') || '0');
+    this.originalPosition = this.core.getPersistenceManager().getTaskPosition(item);
+
+    // The element after the task and its subtasks, to put them back if the move is refused
+    const descendants = this.findAllDescendants(item);
+    this.originalAnchor = (descendants.length ? descendants[descendants.length - 1] : item).nextElementSibling;
 
     // Add visual feedback
     item.classList.add('dragging');
@@ -91,27 +98,27 @@ class KanbanDragHandler {
     if (this.draggedItem) {
       this.draggedItem.classList.remove('dragging');
 
-      // Check if the item was moved
+      // Check if the item was moved or re-indented
       const wasMoved = this.draggedItem.parentNode !== this.originalContainer ||
-                       this.draggedItem.nextElementSibling !== this.originalNextSibling;
+                       this.draggedItem.nextElementSibling !== this.originalNextSibling ||
+                       parseInt(this.draggedItem.getAttribute('data-indent-level') || '0') !== this.originalIndentLevel;
 
       if (wasMoved) {
         console.log('Item was moved, saving changes');
-        console.log('Original container:', this.originalContainer);
-        console.log('New container:', this.draggedItem.parentNode);
-        console.log('Original next sibling:', this.originalNextSibling);
-        console.log('New next sibling:', this.draggedItem.nextElementSibling);
-
-        // Save changes through the persistence manager
-        const persistenceManager = this.core.getComponent('persistence');
-        if (persistenceManager && persistenceManager.saveKanbanChanges) {
-          console.log('Saving kanban changes after drag and drop');
-          persistenceManager.saveKanbanChanges().catch(err => {
-            console.error('Error saving kanban changes after drag and drop:', err);
-          });
-        } else {
-          console.warn('Persistence manager not available or saveKanbanChanges method missing');
-        }
+        const movedItem = this.draggedItem;
+        const originalContainer = this.originalContainer;
+        const originalAnchor = this.originalAnchor;
+        const originalIndentLevel = this.originalIndentLevel;
+
+        this.core.getPersistenceManager().moveTask(this.originalPosition, movedItem).catch(err => {
+          console.error('Error saving kanban changes after drag and drop:', err);
+
+          // The column is at its WIP limit, put the task back where it was
+          if (err.status === 422) {
+            this.moveItemWithDescendants(movedItem, originalContainer, originalAnchor, originalIndentLevel);
+            this.core.columnManager?.updateColumnLimits();
+          }
+        });
       } else {
         console.log('Item was not moved, no changes to save');
       }
@@ -177,21 +184,23 @@ class KanbanDragHandler {
   setupColumnDropTarget(newColumn) {
     console.log('Setting up drop target for new column');
 
-    // Find the task list (ul) within the new column
-    const columnUl = newColumn.querySelector('.kanban-column-content ul');
-    if (!columnUl) {
+    // Find the task lists (ul) within the new column, one per swimlane
+    const columnUls = newColumn.querySelectorAll('.kanban-column-content ul');
+    if (columnUls.length === 0) {
       console.error('Could not find task list in new column');
       return;
     }
 
-    // Setup drop events for the column
-    this.setupColumnDropEvents(columnUl);
+    columnUls.forEach(columnUl => {
+      // Setup drop events for the column
+      this.setupColumnDropEvents(columnUl);
 
-    // Setup empty column drop target
-    const columnContentDiv = columnUl.parentNode;
-    if (columnContentDiv && columnContentDiv.classList.contains('kanban-column-content')) {
-      this.setupEmptyColumnDropTarget(columnContentDiv, columnUl);
-    }
+      // Setup empty column drop target
+      const columnContentDiv = columnUl.parentNode;
+      if (columnContentDiv && columnContentDiv.classList.contains('kanban-column-content')) {
+        this.setupEmptyColumnDropTarget(columnContentDiv, columnUl);
+      }
+    });
 
     console.log('Drop target setup complete for new column');
   }
@@ -390,16 +399,7 @@ class KanbanDragHandler {
       }
     }
 
-    // Save changes through the persistence manager
-    const persistenceManager = this.core.getComponent('persistence');
-    if (persistenceManager && persistenceManager.saveKanbanChanges) {
-      console.log('Saving kanban changes after drag and drop');
-      persistenceManager.saveKanbanChanges().catch(err => {
-        console.error('Error saving kanban changes after drag and drop:', err);
-      });
-    } else {
-      console.warn('Persistence manager not available or saveKanbanChanges method missing');
-    }
+    // Changes are saved when the drag ends
   }
 
   /**
@@ -653,6 +653,8 @@ class KanbanDragHandler {
     this.originalContainer = null;
     this.originalNextSibling = null;
     this.originalIndentLevel = 0;
+    this.originalPosition = null;
+    this.originalAnchor = null;
   }
 
   /**
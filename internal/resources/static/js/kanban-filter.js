@@ -0,0 +1,49 @@
+/**
+ * Kanban Filter Module
+ * Filters kanban tasks by assignee and label using the data attributes
+ * rendered from inline task metadata (@user, #label)
+ */
+
+document.addEventListener('DOMContentLoaded', function() {
+    'use strict';
+
+    const containers = document.querySelectorAll('.kanban-container');
+    if (containers.length === 0) return;
+
+    // Allow links such as ?assignee=alice&label=bug to open a filtered board
+    const params = new URLSearchParams(window.location.search);
+
+    containers.forEach(container => {
+        const filters = container.querySelectorAll('.kanban-filter');
+        if (filters.length === 0) return;
+
+        filters.forEach(select => {
+            const initial = params.get(select.dataset.filter);
+            if (initial && Array.from(select.options).some(option => option.value === initial)) {
+                select.value = initial;
+            }
+            select.addEventListener('change', () => applyFilters(container));
+        });
+
+        applyFilters(container);
+    });
+
+    function applyFilters(container) {
+        const active = {};
+        container.querySelectorAll('.kanban-filter').forEach(select => {
+            if (select.value) {
+                active[select.dataset.filter] = select.value;
+            }
+        });
+
+        container.querySelectorAll('.task-list-item-container').forEach(task => {
+            const assignees = (task.dataset.assignees || '').split(' ');
+            const labels = (task.dataset.labels || '').split(' ');
+
+            const visible = (!active.assignee || assignees.includes(active.assignee)) &&
+                            (!active.label || labels.includes(active.label));
+
+            task.classList.toggle('kanban-filtered-out', !visible);
+        });
+    }
+});
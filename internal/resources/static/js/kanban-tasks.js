@@ -79,7 +79,9 @@ class KanbanTaskManager {
             if (matchingTask) {
               // Use existing task ID if found
               item.setAttribute('data-task-id', matchingTask.id);
-              item.setAttribute('data-original-markdown', matchingTask.content);
+              if (!item.hasAttribute('data-original-markdown')) {
+                item.setAttribute('data-original-markdown', matchingTask.content);
+              }
 
               // Store in the task ID map
               this.taskIdMap.set(matchingTask.id, item);
@@ -186,6 +188,10 @@ class KanbanTaskManager {
       const li = target.closest('li');
       if (!li) return;
 
+      // The click has already toggled the checkbox; preventDefault restores
+      // it once the handler returns, so remember the requested state
+      const desiredChecked = target.checked;
+
       // Get the task ID
       const taskId = li.getAttribute('data-task-id');
       if (!taskId) {
@@ -202,34 +208,20 @@ class KanbanTaskManager {
       allCheckboxes.forEach(cb => cb.disabled = true);
 
       try {
-        // 1. Fetch current markdown
-        const srcResp = await fetch(`/api/source/${this.core.getDocPath()}`);
-        if (!srcResp.ok) throw new Error('source fetch failed');
-        const markdown = await srcResp.text();
-
-        // 2. Build updated markdown
-        const updatedMarkdown = this.toggleTaskInMarkdown(markdown, li, taskId);
-
-        // 3. Save updated markdown
-        const saveResp = await fetch(`/api/save/${this.core.getDocPath()}`, {
-          method: 'POST',
-          headers: { 'Content-Type': 'text/markdown' },
-          body: updatedMarkdown,
-        });
+        // 1. Save the new state
+        await this.core.getPersistenceManager().setTaskChecked(li, desiredChecked);
 
-        if (!saveResp.ok) throw new Error('save failed');
-
-        // 4. Update UI checkbox state
-        target.checked = !target.checked;
+        // 2. Update UI checkbox state
+        target.checked = desiredChecked;
         // Success - no visual feedback needed
 
-        // 5. Clear the moved flag since we've successfully saved the task
+        // 3. Clear the moved flag since we've successfully saved the task
         if (li.hasAttribute('data-was-moved')) {
           li.removeAttribute('data-was-moved');
           console.log(`Cleared 'was-moved' flag for task ${taskId}`);
         }
 
-        // 6. If this was a new task, clear the new flag
+        // 4. If this was a new task, clear the new flag
         if (li.hasAttribute('data-is-new')) {
           li.removeAttribute('data-is-new');
           console.log(`Cleared 'is-new' flag for task ${taskId}`);
@@ -244,286 +236,6 @@ class KanbanTaskManager {
     };
   }
 
-  /**
-   * Toggle a task checkbox in the markdown content using task ID
-   */
-  toggleTaskInMarkdown(markdown, taskItem, taskId) {
-    const lines = markdown.split(/\r?\n/);
-    const desiredChecked = !taskItem.querySelector('.task-checkbox').checked;
-
-    // Get the task text and indentation level
-    const taskText = taskItem.querySelector('.task-text').textContent.trim();
-    const indentLevel = parseInt(taskItem.getAttribute('data-indent-level') || '0');
-
-    console.log(`Toggling task: "${taskText}" (ID: ${taskId}, indent: ${indentLevel}, desired state: ${desiredChecked ? 'checked' : 'unchecked'})`);
-
-    // Check if this is a newly created task that hasn't been saved yet
-    const isNewTask = taskItem.hasAttribute('data-is-new') && taskItem.getAttribute('data-is-new') === 'true';
-
-    // For new tasks, we need to find them by content since they don't have IDs in the markdown yet
-    if (isNewTask) {
-      console.log('This is a new task, finding by content');
-      return this.toggleNewTaskByContent(lines, taskText, indentLevel, desiredChecked, taskId);
-    }
-
-    // For existing tasks, try to find them by ID comment
-    return this.toggleExistingTaskById(lines, taskText, indentLevel, desiredChecked, taskId, taskItem);
-  }
-
-  /**
-   * Toggle a new task by content matching
-   */
-  toggleNewTaskByContent(lines, taskText, indentLevel, desiredChecked, taskId) {
-    let found = false;
-    const updatedLines = lines.map(line => {
-      if (found) return line;
-
-      const match = line.match(/^(\s*)([-*+])\s+\[(.| )\]\s+(.*)$/);
-      if (!match) return line;
-
-      const [, indent, listMarker, currentCheck, content] = match;
-      const lineIndentLevel = Math.floor(indent.length / 2);
-
-      // Extract content without comments
-      const contentWithoutComments = content.split('<!--')[0].trim();
-
-      // Normalize content for comparison
-      const normalizedContent = this.normalizeTaskContent(contentWithoutComments);
-      const normalizedTaskText = this.normalizeTaskContent(taskText);
-
-      // For new tasks, we need to match both content and indentation level
-      if (normalizedContent === normalizedTaskText && lineIndentLevel === indentLevel) {
-        found = true;
-        const newMark = desiredChecked ? 'x' : ' ';
-
-        console.log(`Found new task to toggle at indent level ${lineIndentLevel}`);
-
-        // Add task ID comment for future reference
-        return `${indent}${listMarker} [${newMark}] ${contentWithoutComments} <!-- task-id: ${taskId} -->`;
-      }
-
-      return line;
-    });
-
-    if (!found) {
-      console.warn('Could not find new task to toggle');
-    }
-
-    return updatedLines.join('\n');
-  }
-
-  /**
-   * Toggle an existing task by ID
-   */
-  toggleExistingTaskById(lines, taskText, indentLevel, desiredChecked, taskId, taskItem) {
-    console.log('Looking for task by ID in markdown');
-
-    // Check if this task was recently moved
-    const wasTaskMoved = taskItem.hasAttribute('data-was-moved') && taskItem.getAttribute('data-was-moved') === 'true';
-
-    if (wasTaskMoved) {
-      console.log('Task was moved, using extra care when toggling');
-    }
-
-    // Try different patterns for finding the task ID in the markdown
-    const idPatterns = [
-      `<!-- task-id: ${taskId} -->`,
-      `<!--task-id: ${taskId}-->`,
-      `<!-- task-id:${taskId} -->`,
-      `<!--task-id:${taskId}-->`
-    ];
-
-    // First, try to find the exact task by ID and indentation level
-    let foundExact = false;
-    let foundAny = false;
-    let exactMatchLine = -1;
-    let anyMatchLine = -1;
-
-    // First pass: find all potential matches
-    lines.forEach((line, index) => {
-      if (foundExact) return;
-
-      // Check if this line contains our task ID in any format
-      const hasTaskId = idPatterns.some(pattern => line.includes(pattern));
-
-      if (hasTaskId) {
-        foundAny = true;
-        anyMatchLine = index;
-
-        // Extract the task parts
-        const match = line.match(/^(\s*)([-*+])\s+\[(.| )\]\s+(.*)$/);
-        if (!match) return;
-
-        const [, indent, listMarker, currentCheck, content] = match;
-
-        // Calculate the line's indentation level
-        const lineIndentLevel = Math.floor(indent.length / 2);
-
-        // If indentation level matches, this is our exact match
-        if (lineIndentLevel === indentLevel) {
-          foundExact = true;
-          exactMatchLine = index;
-          console.log(`Found exact match for task ID ${taskId} at line ${index} with indent level ${lineIndentLevel}`);
-        }
-      }
-    });
-
-    // Now update the appropriate line
-    if (foundExact || foundAny) {
-      const lineIndex = foundExact ? exactMatchLine : anyMatchLine;
-      const line = lines[lineIndex];
-
-      // Extract the task parts
-      const match = line.match(/^(\s*)([-*+])\s+\[(.| )\]\s+(.*)$/);
-      if (!match) {
-        console.warn('Found task ID but line format is unexpected:', line);
-        return lines.join('\n');
-      }
-
-      const [, indent, listMarker, currentCheck, content] = match;
-
-      // Calculate the line's indentation level
-      const lineIndentLevel = Math.floor(indent.length / 2);
-
-      // Split content and comment
-      const parts = content.split('<!--');
-      const mainContent = parts[0].trim();
-      const comment = parts.length > 1 ? '<!--' + parts.slice(1).join('<!--') : '';
-
-      // Toggle the checkbox
-      const newMark = desiredChecked ? 'x' : ' ';
-
-      // Log the change with indentation info
-      console.log(`Toggling task by ID at line ${lineIndex}, indent level ${lineIndentLevel}, from [${currentCheck}] to [${newMark}]`);
-
-      // Update the line
-      lines[lineIndex] = `${indent}${listMarker} [${newMark}] ${mainContent} ${comment}`;
-
-      return lines.join('\n');
-    }
-
-    console.warn('Could not find task by ID, falling back to content matching');
-
-    // Fall back to content matching with indentation level
-    return this.fallbackToggleByContent(lines, taskText, indentLevel, desiredChecked, taskId, wasTaskMoved);
-  }
-
-  /**
-   * Fallback method to toggle a task by content and indentation level
-   */
-  fallbackToggleByContent(lines, taskText, indentLevel, desiredChecked, taskId, wasTaskMoved) {
-    console.log(`Fallback: toggling task by content: "${taskText}" at indent level ${indentLevel}, desired state: ${desiredChecked ? 'checked' : 'unchecked'}`);
-
-    // Normalize the task text
-    const normalizedTaskText = this.normalizeTaskContent(taskText);
-
-    // Collect all potential matches
-    const potentialMatches = [];
-
-    lines.forEach((line, index) => {
-      const match = line.match(/^(\s*)([-*+])\s+\[(.| )\]\s+(.*)$/);
-      if (!match) return;
-
-      const [, indent, listMarker, currentCheck, content] = match;
-      const lineIndentLevel = Math.floor(indent.length / 2);
-
-      // Extract content without comments
-      const contentWithoutComments = content.split('<!--')[0].trim();
-
-      // Normalize content
-      const normalizedContent = this.normalizeTaskContent(contentWithoutComments);
-
-      // If the task was moved, we need to be more strict about matching
-      let isMatch;
-
-      if (wasTaskMoved) {
-        // For moved tasks, require exact text match and correct indentation
-        isMatch = normalizedContent === normalizedTaskText && lineIndentLevel === indentLevel;
-      } else {
-        // For non-moved tasks, be more flexible with content but strict with indentation
-        isMatch = (normalizedContent === normalizedTaskText ||
-                  normalizedContent.includes(normalizedTaskText) ||
-                  normalizedTaskText.includes(normalizedContent)) &&
-                  lineIndentLevel === indentLevel;
-      }
-
-      if (isMatch) {
-        potentialMatches.push({
-          index,
-          line,
-          indentLevel: lineIndentLevel,
-          content,
-          contentWithoutComments,
-          indent,
-          listMarker,
-          exactMatch: normalizedContent === normalizedTaskText && lineIndentLevel === indentLevel,
-          currentCheck
-        });
-      }
-    });
-
-    // Log all potential matches for debugging
-    console.log(`Found ${potentialMatches.length} potential matches`);
-    potentialMatches.forEach((match, i) => {
-      console.log(`Match ${i+1}: "${match.contentWithoutComments}" (indent: ${match.indentLevel}, exact: ${match.exactMatch}, current: [${match.currentCheck}])`);
-    });
-
-    // If we have matches, use the one with the correct indentation level
-    if (potentialMatches.length > 0) {
-      // First try exact match with both content and indent level
-      const exactMatches = potentialMatches.filter(match => match.exactMatch);
-
-      let matchToUse;
-
-      if (exactMatches.length > 0) {
-        console.log('Using exact content and indent match');
-        matchToUse = exactMatches[0];
-      } else {
-        // Next try to find a match with the correct indent level
-        const indentMatches = potentialMatches.filter(match => match.indentLevel === indentLevel);
-
-        if (indentMatches.length > 0) {
-          console.log('Using indent-level match');
-          matchToUse = indentMatches[0];
-        } else {
-          // Use closest match by indentation
-          console.log('No exact matches, using closest match by indent');
-          potentialMatches.sort((a, b) =>
-            Math.abs(a.indentLevel - indentLevel) - Math.abs(b.indentLevel - indentLevel)
-          );
-          matchToUse = potentialMatches[0];
-        }
-      }
-
-      // Create updated lines
-      const updatedLines = [...lines];
-      const index = matchToUse.index;
-
-      // Extract content and comments
-      const parts = matchToUse.content.split('<!--');
-      const mainContent = parts[0].trim();
-      const comment = parts.length > 1 ? '<!--' + parts.slice(1).join('<!--') : '';
-
-      // Toggle the checkbox and add task ID if not present
-      const newMark = desiredChecked ? 'x' : ' ';
-      const hasTaskId = comment.includes('task-id:');
-
-      console.log(`Toggling checkbox from [${matchToUse.currentCheck}] to [${newMark}] at indent level ${matchToUse.indentLevel}`);
-
-      if (hasTaskId) {
-        updatedLines[index] = `${matchToUse.indent}${matchToUse.listMarker} [${newMark}] ${mainContent} ${comment}`;
-      } else {
-        updatedLines[index] = `${matchToUse.indent}${matchToUse.listMarker} [${newMark}] ${mainContent} <!-- task-id: ${taskId} -->`;
-      }
-
-      console.log(`Updated line ${index}: ${updatedLines[index]}`);
-      return updatedLines.join('\n');
-    }
-
-    console.warn('Could not find any matching task');
-    return lines.join('\n');
-  }
-
   /**
    * Normalize task content for comparison
    */
@@ -799,7 +511,9 @@ class KanbanTaskManager {
           taskTextElement.innerHTML = this.processMarkdown(newText);
 
           // Save changes
-          this.core.getPersistenceManager().saveKanbanChanges();
+          this.core.getPersistenceManager().editTask(taskItem, originalMarkdown, newText).catch(err => {
+            console.error('Error saving task text:', err);
+          });
         }
 
         finishEditing();
@@ -839,6 +553,9 @@ class KanbanTaskManager {
       (confirmed) => {
         if (!confirmed) return;
 
+        // Remember where the task was before removing it
+        const position = this.core.getPersistenceManager().getTaskPosition(taskItem);
+
         // Find all descendants
         const descendants = this.core.getDragHandler().findAllDescendants(taskItem);
 
@@ -851,7 +568,9 @@ class KanbanTaskManager {
         taskItem.remove();
 
         // Save changes
-        this.core.getPersistenceManager().saveKanbanChanges();
+        this.core.getPersistenceManager().deleteTask(position).catch(err => {
+          console.error('Error deleting task:', err);
+        });
       }
     );
   }
@@ -953,8 +672,8 @@ class KanbanTaskManager {
     // Add input to container
     inputContainer.appendChild(input);
 
-    // Insert at the top of the column
-    columnContent.insertBefore(inputContainer, columnContent.firstChild);
+    // Insert at the top of the column, above the task list it adds to
+    taskList.parentNode.insertBefore(inputContainer, taskList);
 
     // Focus the input
     input.focus();
@@ -967,10 +686,17 @@ class KanbanTaskManager {
 
         if (taskText) {
           // Create new task
-          this.createNewTask(taskText, taskList);
+          const taskContainer = this.createNewTask(taskText, taskList);
 
           // Save changes
-          this.core.getPersistenceManager().saveKanbanChanges();
+          this.core.getPersistenceManager().addTask(taskContainer).catch(err => {
+            console.error('Error saving new task:', err);
+
+            // The column is at its WIP limit, the task was not added
+            if (err.status === 422) {
+              taskContainer.remove();
+            }
+          });
 
           // Remove input
           inputContainer.remove();
@@ -0,0 +1,125 @@
+/**
+ * Link Check Manager Module
+ * Shows the dead-link report and starts link checks
+ */
+
+document.addEventListener('DOMContentLoaded', function() {
+    'use strict';
+
+    // Elements
+    const runLinkCheckBtn = document.getElementById('runLinkCheckBtn');
+    const linkCheckStatus = document.getElementById('linkCheckStatus');
+    const deadLinkList = document.getElementById('deadLinkList');
+    const linkCheckTabBtn = document.querySelector('button[data-tab="linkcheck-tab"]');
+
+    let pollTimer = null;
+
+    // Initialize
+    if (linkCheckTabBtn) {
+        linkCheckTabBtn.addEventListener('click', loadReport);
+    }
+
+    if (runLinkCheckBtn) {
+        runLinkCheckBtn.addEventListener('click', startLinkCheck);
+    }
+
+    function t(key, fallback) {
+        return window.i18n ? window.i18n.t(key) : fallback;
+    }
+
+    function escapeHTML(value) {
+        const div = document.createElement('div');
+        div.textContent = value;
+        return div.innerHTML;
+    }
+
+    // Functions
+    async function loadReport() {
+        if (!deadLinkList) return;
+
+        try {
+            const response = await fetch('/api/links/check');
+            if (!response.ok) {
+                throw new Error('Failed to load report');
+            }
+            const data = await response.json();
+            renderReport(data);
+
+            // Keep polling while a check is running
+            clearTimeout(pollTimer);
+            if (data.running) {
+                pollTimer = setTimeout(loadReport, 3000);
+            }
+        } catch (error) {
+            console.error('Error loading link check report:', error);
+            deadLinkList.innerHTML = `<div class="error-message">${t('linkcheck.error_loading', 'Failed to load link check results')}</div>`;
+        }
+    }
+
+    function renderReport(data) {
+        if (runLinkCheckBtn) {
+            runLinkCheckBtn.disabled = data.running;
+        }
+
+        if (linkCheckStatus) {
+            if (data.running) {
+                linkCheckStatus.textContent = t('linkcheck.running', 'Checking links...');
+            } else if (data.last_run) {
+                linkCheckStatus.textContent = `${t('linkcheck.last_run', 'Last checked')}: ${new Date(data.last_run).toLocaleString()}`;
+            } else {
+                linkCheckStatus.textContent = t('linkcheck.never_run', 'Links have not been checked yet');
+            }
+        }
+
+        deadLinkList.innerHTML = '';
+
+        const results = data.results || [];
+        if (results.length === 0) {
+            deadLinkList.innerHTML = `<div class="empty-message">${t('linkcheck.no_dead_links', 'No dead links found')}</div>`;
+            return;
+        }
+
+        results.forEach(result => {
+            const item = document.createElement('div');
+            item.className = 'file-item';
+
+            const reason = result.error ? result.error : `HTTP ${result.status_code}`;
+            const pages = (result.pages || [])
+                .map(page => `<a href="${encodeURI(page)}">${escapeHTML(page)}</a>`)
+                .join(', ');
+
+            item.innerHTML = `
+                <div class="file-info">
+                    <div class="file-icon"><i class="fa fa-chain-broken"></i></div>
+                    <div class="file-details" style="display: flex; flex-direction: column; overflow: hidden;">
+                        <a class="file-name" href="${encodeURI(result.url)}" target="_blank" rel="noopener noreferrer" title="${escapeHTML(result.url)}">${escapeHTML(result.url)}</a>
+                        <span class="file-meta" style="font-size: 0.85em; color: var(--text-muted);">${escapeHTML(reason)} • ${pages}</span>
+                    </div>
+                </div>
+            `;
+
+            deadLinkList.appendChild(item);
+        });
+    }
+
+    async function startLinkCheck() {
+        if (runLinkCheckBtn.disabled) return;
+
+        runLinkCheckBtn.disabled = true;
+
+        try {
+            const response = await fetch('/api/links/check', { method: 'POST' });
+            if (!response.ok && response.status !== 409) {
+                throw new Error('Failed to start link check');
+            }
+            loadReport();
+        } catch (error) {
+            console.error('Link check error:', error);
+            window.DialogSystem.showMessageDialog(
+                t('common.error', 'Error'),
+                t('linkcheck.error_start', 'Failed to start link check')
+            );
+            runLinkCheckBtn.disabled = false;
+        }
+    }
+});
@@ -39,6 +39,58 @@
         
         // Set up floating add link button (admin/editor only)
         setupAddLinkButton();
+
+        // Set up bookmark file import/export
+        setupBookmarkActions();
+    }
+
+    /**
+     * Set up Netscape bookmark file export and import buttons
+     */
+    function setupBookmarkActions() {
+        const exportButton = document.getElementById('exportBookmarks');
+        const importButton = document.getElementById('importBookmarks');
+        const fileInput = document.getElementById('importBookmarksFile');
+
+        if (exportButton) {
+            exportButton.addEventListener('click', function() {
+                window.location.href = `/api/links/export/${getCurrentDocumentPath()}`;
+            });
+        }
+
+        if (importButton && fileInput) {
+            importButton.addEventListener('click', function() {
+                fileInput.click();
+            });
+
+            fileInput.addEventListener('change', async function() {
+                if (!fileInput.files.length) return;
+
+                const formData = new FormData();
+                formData.append('file', fileInput.files[0]);
+
+                try {
+                    const response = await fetch(`/api/links/import/${getCurrentDocumentPath()}`, {
+                        method: 'POST',
+                        body: formData
+                    });
+                    const result = await response.json();
+                    if (!response.ok || !result.success) {
+                        throw new Error(result.error || result.message || 'Import failed');
+                    }
+
+                    showToast(result.message);
+                    if (result.imported > 0) {
+                        window.location.reload();
+                    }
+                } catch (error) {
+                    console.error('Bookmark import error:', error);
+                    showToast(`Failed to import bookmarks: ${error.message}`, 'error');
+                } finally {
+                    fileInput.value = '';
+                }
+            });
+        }
     }
 
     /**
@@ -30,4 +30,37 @@ document.addEventListener('DOMContentLoaded', function() {
             });
         });
     }
-});
\ No newline at end of file
+});
+
+// Sort tables rendered by :::table sortable=true::: when a header is clicked
+document.addEventListener('DOMContentLoaded', function() {
+    document.querySelectorAll('table.wiki-table.sortable').forEach(table => {
+        const headers = table.querySelectorAll('thead th');
+        const tbody = table.tBodies[0];
+        if (!tbody) return;
+
+        headers.forEach((header, column) => {
+            header.addEventListener('click', function() {
+                const ascending = header.getAttribute('aria-sort') !== 'ascending';
+                headers.forEach(h => h.removeAttribute('aria-sort'));
+                header.setAttribute('aria-sort', ascending ? 'ascending' : 'descending');
+
+                const rows = Array.from(tbody.rows);
+                rows.sort((a, b) => {
+                    const x = a.cells[column] ? a.cells[column].textContent.trim() : '';
+                    const y = b.cells[column] ? b.cells[column].textContent.trim() : '';
+                    const nx = parseFloat(x.replace(/[,$€£%]/g, ''));
+                    const ny = parseFloat(y.replace(/[,$€£%]/g, ''));
+                    let result;
+                    if (!isNaN(nx) && !isNaN(ny)) {
+                        result = nx - ny;
+                    } else {
+                        result = x.localeCompare(y, undefined, { numeric: true, sensitivity: 'base' });
+                    }
+                    return ascending ? result : -result;
+                });
+                rows.forEach(row => tbody.appendChild(row));
+            });
+        });
+    });
+});
@@ -60,6 +60,13 @@ const WikiSearch = (function() {
      * @param {string} query - The search query
      */
     async function performSearch(query) {
+        // Static exports have no server; search their index instead
+        const staticIndex = document.querySelector('link[rel="search-index"]');
+        if (staticIndex) {
+            searchStaticIndex(query, staticIndex.href);
+            return;
+        }
+
         try {
             const response = await fetch('/api/search', {
                 method: 'POST',
@@ -89,6 +96,74 @@ const WikiSearch = (function() {
         }
     }
 
+    // Pages of a static export's search index, loaded on the first search
+    let staticPages;
+
+    /**
+     * Escape plain text from the static search index for use as HTML
+     * @param {string} text - The text to escape
+     * @returns {string} The escaped text
+     */
+    function escapeText(text) {
+        const div = document.createElement('div');
+        div.textContent = text;
+        return div.innerHTML;
+    }
+
+    /**
+     * Search the index of a static export, matching quoted phrases and words
+     * and excluding words after NOT like the server does
+     * @param {string} query - The search query
+     * @param {string} indexURL - URL of search-index.json
+     */
+    async function searchStaticIndex(query, indexURL) {
+        try {
+            if (!staticPages) {
+                const response = await fetch(indexURL);
+                if (!response.ok) {
+                    throw new Error('Search index not found');
+                }
+                staticPages = await response.json();
+            }
+
+            const include = [];
+            const exclude = [];
+            const rest = query.replace(/"([^"]+)"/g, function(_, phrase) {
+                include.push(phrase.toLowerCase());
+                return ' ';
+            });
+            const words = rest.split(/\s+/).filter(Boolean);
+            for (let i = 0; i < words.length; i++) {
+                const word = words[i].toLowerCase();
+                if (word === 'not' && i + 1 < words.length) {
+                    exclude.push(words[++i].toLowerCase());
+                } else if (word !== 'and') {
+                    include.push(word);
+                }
+            }
+
+            const results = staticPages.filter(page => {
+                const text = (page.title + ' ' + page.text).toLowerCase();
+                return include.every(term => text.includes(term)) && !exclude.some(term => text.includes(term));
+            }).map(page => {
+                const text = page.text;
+                const at = include.length ? Math.max(0, text.toLowerCase().indexOf(include[0])) : 0;
+                const start = Math.max(0, at - 100);
+                const excerpt = (start > 0 ? '...' : '') + text.substring(start, start + 200) + (start + 200 < text.length ? '...' : '');
+                return {
+                    title: escapeText(page.title),
+                    path: new URL(page.path, indexURL).href,
+                    excerpt: escapeText(excerpt)
+                };
+            });
+
+            displaySearchResults(results, query);
+        } catch (error) {
+            console.error('Search error:', error);
+            searchResultsContent.innerHTML = '<div class="empty-message">An error occurred while searching. Please try again.</div>';
+        }
+    }
+
     /**
      * Display search results in the UI
      * @param {Array} results - Search results from the API
@@ -370,6 +370,34 @@ func SetupRoutes(cfg *config.Config) {
 		handlers.SearchHandler(w, r, cfg)
 	})
 
+	// Backlinks API - documents linking to or including a page
+	mux.HandleFunc("/api/backlinks", func(w http.ResponseWriter, r *http.Request) {
+		handlers.BacklinksHandler(w, r, cfg)
+	})
+
+	// Graph API - pages and the relationships between them
+	mux.HandleFunc("/api/graph", func(w http.ResponseWriter, r *http.Request) {
+		handlers.GraphHandler(w, r, cfg)
+	})
+
+	// EPUB export of a page and the pages below it
+	mux.HandleFunc("/api/export/epub", func(w http.ResponseWriter, r *http.Request) {
+		handlers.EPUBExportHandler(w, r, cfg)
+	})
+
+	// Markdown export of a page and the pages below it, as a vault of notes
+	mux.HandleFunc("/api/export/markdown", func(w http.ResponseWriter, r *http.Request) {
+		handlers.MarkdownExportHandler(w, r, cfg)
+	})
+
+	// Tasks API - open tasks across all documents, and as a calendar feed
+	mux.HandleFunc("/api/tasks", func(w http.ResponseWriter, r *http.Request) {
+		handlers.TasksHandler(w, r, cfg)
+	})
+	mux.HandleFunc("/api/tasks/calendar.ics", func(w http.ResponseWriter, r *http.Request) {
+		handlers.TasksCalendarHandler(w, r, cfg)
+	})
+
 	// Settings API - Admin only
 	mux.HandleFunc("/api/settings/wiki", adminMiddleware(handlers.WikiSettingsHandler))
 	mux.HandleFunc("/api/settings/security", adminMiddleware(handlers.SecuritySettingsHandler))
@@ -425,6 +453,9 @@ func SetupRoutes(cfg *config.Config) {
 		handlers.ImportStatusHandler(w, r, cfg)
 	})
 
+	// Kanban board import - Editor or Admin only
+	mux.HandleFunc("/api/import/kanban", editorMiddleware(handlers.KanbanImportHandler))
+
 	// Backup API - Admin only
 	mux.HandleFunc("/api/backup/start", adminMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		handlers.StartBackupHandler(w, r, cfg)
@@ -440,6 +471,25 @@ func SetupRoutes(cfg *config.Config) {
 		handlers.DeleteBackupHandler(w, r, cfg)
 	}))
 
+	// Render cache statistics and purge - Admin only
+	mux.HandleFunc("/api/render-cache", adminMiddleware(handlers.RenderCacheHandler))
+
+	// User shortcode templates list and validation - Admin only
+	mux.HandleFunc("/api/shortcodes", adminMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		handlers.ShortcodesHandler(w, r, cfg)
+	}))
+	mux.HandleFunc("/api/shortcodes/validate", adminMiddleware(handlers.ShortcodeValidateHandler))
+
+	// Wiki variables and the pages using them - Admin only
+	mux.HandleFunc("/api/variables", adminMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		handlers.VariablesHandler(w, r, cfg)
+	}))
+
+	// Dead-link report - Admin only
+	mux.HandleFunc("/api/links/check", adminMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		handlers.LinkCheckReportHandler(w, r, cfg)
+	}))
+
 	// Sitemap routes
 	mux.HandleFunc("/sitemap/", func(w http.ResponseWriter, r *http.Request) {
 		handlers.SitemapHandler(w, r, cfg)
@@ -449,12 +499,42 @@ func SetupRoutes(cfg *config.Config) {
 		handlers.SitemapHandler(w, r, cfg)
 	})
 
+	// Graph page
+	mux.HandleFunc("/graph", func(w http.ResponseWriter, r *http.Request) {
+		if !auth.RequireAuth(r, cfg) {
+			http.Redirect(w, r, "/login?redirect="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+			return
+		}
+		handlers.GraphPageHandler(w, r, cfg)
+	})
+
+	// Tasks page
+	mux.HandleFunc("/tasks", func(w http.ResponseWriter, r *http.Request) {
+		if !auth.RequireAuth(r, cfg) {
+			http.Redirect(w, r, "/login?redirect="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+			return
+		}
+		handlers.TasksPageHandler(w, r, cfg)
+	})
+
 	// Utility API endpoints
 	mux.HandleFunc("/api/utils/slugify", handlers.SlugifyHandler)
 
 	// Links Metadata API - Editor or Admin only
 	mux.HandleFunc("/api/links/fetch-metadata", editorMiddleware(handlers.FetchMetadataHandler))
 
+	// Links document API - editing and import are Editor or Admin only
+	mux.HandleFunc("/api/links/add/", editorMiddleware(handlers.AddLinkHandler))
+	mux.HandleFunc("/api/links/edit/", editorMiddleware(handlers.EditLinkHandler))
+	mux.HandleFunc("/api/links/delete/", editorMiddleware(handlers.DeleteLinkHandler))
+	mux.HandleFunc("/api/links/import/", editorMiddleware(handlers.ImportLinksHandler))
+	mux.HandleFunc("/api/links/list/", handlers.ListLinksHandler)
+	mux.HandleFunc("/api/links/search/", handlers.SearchLinksHandler)
+	mux.HandleFunc("/api/links/export/", handlers.ExportLinksHandler)
+
+	// Kanban board operations (access and editor role are checked by the handler)
+	mux.HandleFunc("/api/kanban/", handlers.KanbanHandler)
+
 	// Login page
 	mux.HandleFunc("/login", handlers.LoginPageHandler)
 
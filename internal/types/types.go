@@ -55,4 +55,5 @@ type PageData struct {
 	DocumentLayout     string             // Document layout type from frontmatter (e.g., "kanban")
 	IsEditMode         bool               // Whether page is in edit mode (separate edit page architecture)
 	RawContent         string             // Raw markdown content with frontmatter for edit mode
+	IsStaticExport     bool               // Whether the page is written to a static site export, without server features
 }
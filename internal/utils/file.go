@@ -2,9 +2,41 @@ package utils
 
 import (
 	"os"
+	"path/filepath"
 )
 
 // GetFileInfo returns file information for the given path
 func GetFileInfo(path string) (os.FileInfo, error) {
 	return os.Stat(path)
 }
+
+// WriteFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers never see a partially written file
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	// Rename is atomic on POSIX
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
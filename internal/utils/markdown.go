@@ -46,8 +46,26 @@ func RenderMarkdown(md string) []byte {
 
 // RenderMarkdownWithPath converts markdown text to HTML with the current document path
 func RenderMarkdownWithPath(md string, docPath string) []byte {
+	return RenderMarkdownWithContext(md, &goldext.RenderContext{DocPath: docPath})
+}
+
+// RenderMarkdownWithContext converts markdown text to HTML using a render context
+// that knows the viewer, so that :::include::: shortcodes can be access-checked
+func RenderMarkdownWithContext(md string, ctx *goldext.RenderContext) []byte {
+	docPath := ctx.DocPath
+
 	// Check for frontmatter
 	metadata, contentWithoutFrontmatter, hasFrontmatter := frontmatter.Parse(md)
+	ctx.RecordContent(md, metadata.Layout)
+
+	// The page's own settings for the markdown extension
+	pageCtx := *ctx
+	pageCtx.Layout = metadata.Layout
+	pageCtx.LinkGlossary = metadata.Layout != goldext.GlossaryLayout && (metadata.Glossary == nil || *metadata.Glossary)
+	pageCtx.Bibliography = metadata.Bibliography
+	pageCtx.CitationStyle = metadata.CitationStyle
+	pageCtx.Variables = metadata.Variables
+	pageCtx.NumberHeadings = metadata.NumberHeadings
 
 	// If this has kanban layout, render as kanban with full goldext support
 	if hasFrontmatter && metadata.Layout == "kanban" {
@@ -68,14 +86,10 @@ func RenderMarkdownWithPath(md string, docPath string) []byte {
 			}
 		}
 
-		// Add post-processors for mermaid and direction blocks
-		postProcessors = append(postProcessors, func(html string) string {
-			result := goldext.RestoreMermaidBlocks(html)
-			result = goldext.RestoreDirectionBlocks(result)
-			return result
-		})
+		// Add post-processors for mermaid blocks
+		postProcessors = append(postProcessors, goldext.RestoreMermaidBlocks)
 
-		kanbanHTML := frontmatter.RenderKanbanWithProcessors(contentWithoutFrontmatter, preprocessors, postProcessors)
+		kanbanHTML := frontmatter.RenderKanbanWithProcessors(contentWithoutFrontmatter, preprocessors, postProcessors, goldext.NewExtension(&pageCtx))
 		return []byte(kanbanHTML)
 	}
 
@@ -95,6 +109,12 @@ func RenderMarkdownWithPath(md string, docPath string) []byte {
 		md = contentWithoutFrontmatter
 	}
 
+	// Render included pages first, each with its own document path. This must happen
+	// before ProcessMarkdown because the nested renders reuse the Mermaid
+	// placeholder storage.
+	md, includes := goldext.ExtractIncludes(md)
+	renderedIncludes := renderIncludes(includes, ctx)
+
 	// Apply any custom extensions via pre-processing
 	md = goldext.ProcessMarkdown(md, docPath)
 
@@ -110,6 +130,7 @@ func RenderMarkdownWithPath(md string, docPath string) []byte {
 			extension.DefinitionList, // Enable definition lists
 			extension.GFM,            // GitHub Flavored Markdown
 			goldext.OnePasswordIgnore, // Add data-1p-ignore to code blocks
+			goldext.NewExtension(&pageCtx), // Wiki syntax: alerts, details, shortcodes, [toc] and more
 			// MathJax is now handled via client-side JavaScript
 		),
 		// Parser options
@@ -137,10 +158,37 @@ func RenderMarkdownWithPath(md string, docPath string) []byte {
 	// Post-process: Restore Mermaid blocks that were replaced with placeholders
 	htmlResult := goldext.RestoreMermaidBlocks(buf.String())
 
-	// Post-process: Restore Direction blocks that were replaced with placeholders
-	// This ensures RTL/LTR content is properly rendered with Markdown formatting
-	htmlResult = goldext.RestoreDirectionBlocks(htmlResult)
+	// Post-process: Restore included pages
+	htmlResult = goldext.RestoreIncludes(htmlResult, renderedIncludes)
 
 	// Return the post-processed HTML
 	return []byte(htmlResult)
+}
+
+// renderIncludes renders every :::include::: found in a document and returns
+// the HTML keyed by placeholder ID
+func renderIncludes(includes []goldext.Include, ctx *goldext.RenderContext) map[string]string {
+	rendered := make(map[string]string, len(includes))
+
+	for _, inc := range includes {
+		switch {
+		case !ctx.CheckAccess(inc.Path):
+			rendered[inc.ID] = goldext.RenderIncludeError(inc, "You do not have access to the included page "+inc.Path)
+		case ctx.IsIncluding(inc.Path):
+			chain := append(append([]string{}, ctx.IncludeStack...), "/"+strings.Trim(ctx.DocPath, "/"), inc.Path)
+			rendered[inc.ID] = goldext.RenderIncludeError(inc, "Include cycle detected: "+strings.Join(chain, " → "))
+		case len(ctx.IncludeStack) >= goldext.MaxIncludeDepth:
+			rendered[inc.ID] = goldext.RenderIncludeError(inc, "Includes are nested too deeply at "+inc.Path)
+		default:
+			ctx.RecordInclude(inc.Path)
+			source, err := goldext.ReadIncludeSource(inc.Path, inc.Section)
+			if err != nil {
+				rendered[inc.ID] = goldext.RenderIncludeError(inc, "Included page not found: "+inc.Path)
+				continue
+			}
+			rendered[inc.ID] = goldext.WrapInclude(inc, string(RenderMarkdownWithContext(source, ctx.Child(inc.Path))))
+		}
+	}
+
+	return rendered
 }
\ No newline at end of file
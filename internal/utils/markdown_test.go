@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden HTML files in testdata/golden")
+
+// TestRenderGolden renders every page in testdata/golden and compares the
+// output byte for byte with the HTML stored next to it. Run with -update to
+// accept intended rendering changes.
+func TestRenderGolden(t *testing.T) {
+	pages, err := filepath.Glob(filepath.Join("testdata", "golden", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) == 0 {
+		t.Fatal("no golden pages found")
+	}
+
+	for _, page := range pages {
+		name := strings.TrimSuffix(filepath.Base(page), ".md")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := RenderMarkdownWithPath(string(source), "golden/"+name)
+
+			goldenFile := strings.TrimSuffix(page, ".md") + ".html"
+			if *updateGolden {
+				if err := os.WriteFile(goldenFile, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(goldenFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("rendered HTML differs from %s\n--- got ---\n%s\n--- want ---\n%s", goldenFile, got, want)
+			}
+		})
+	}
+}
+
+// BenchmarkRenderMarkdown renders the mixed golden page, which uses most of
+// the wiki's markdown extensions
+func BenchmarkRenderMarkdown(b *testing.B) {
+	source, err := os.ReadFile(filepath.Join("testdata", "golden", "mixed.md"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		RenderMarkdownWithPath(string(source), "golden/mixed")
+	}
+}
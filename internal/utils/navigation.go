@@ -38,7 +38,7 @@ func GetDocumentTitle(dirPath string) string {
 		if strings.HasPrefix(line, "# ") {
 			title := strings.TrimPrefix(line, "# ")
 			// Process emojis in the title
-			title = goldext.EmojiPreprocessor(title, "")
+			title = goldext.ReplaceEmoji(title)
 			return title
 		}
 	}
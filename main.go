@@ -45,6 +45,8 @@ func main() {
 	// :::stats recent=N::: formats edit times in the configured zone
 	// (consistent with formatTime used elsewhere in templates).
 	goldext.SetWikiTimezone(cfg.Wiki.Timezone)
+	goldext.SetCitationDefaults(cfg.Citations.Bibliography, cfg.Citations.Style)
+	goldext.SetConfigVariables(cfg.Variables)
 
 	// Initialize session store for persistent logins
 	sessionPath := filepath.Join(cfg.Wiki.RootDir, "temp", "sessions.json")
@@ -62,6 +64,14 @@ func main() {
 		log.Fatal("Error copying static assets:", err)
 	}
 
+	// Commands such as export-static run once instead of starting the
+	// server, without its background link checks and file watching
+	if flag.NArg() > 0 {
+		handlers.InitRenderer(cfg)
+		runCommand(cfg, flag.Args())
+		return
+	}
+
 	// Update handlers with config
 	handlers.InitHandlers(cfg)
 
@@ -83,6 +93,27 @@ func main() {
 	}
 }
 
+// runCommand runs the command named by the first argument with the flags
+// that follow it
+func runCommand(cfg *config.Config, args []string) {
+	switch args[0] {
+	case "export-static":
+		flags := flag.NewFlagSet("export-static", flag.ExitOnError)
+		out := flags.String("out", "static-site", "directory to write the static site to")
+		root := flags.String("path", "/", "page to export with the pages below it")
+		baseURL := flags.String("base-url", "", "URL the site is published at, used in sitemap.xml")
+		flags.Parse(args[1:])
+
+		pages, err := handlers.ExportStatic(cfg, *out, *root, *baseURL)
+		if err != nil {
+			log.Fatal("Error exporting static site: ", err)
+		}
+		fmt.Printf("Exported %d pages to %s\n", pages, *out)
+	default:
+		log.Fatalf("Unknown command %q", args[0])
+	}
+}
+
 func GetEnvString(name, defaultvalue string) string {
 	value, ok := os.LookupEnv(name)
 	if ! ok {